@@ -37,6 +37,7 @@ type Expression interface {
 // in Statements slice.
 type Program struct {
 	Statements []Statement
+	Comments   []*token.Token // the // comments of the source in order, only used by tools
 }
 
 // implementing the Node interface TokenLiteral func returning
@@ -61,8 +62,9 @@ func (p *Program) String() string {
 
 // struct representing the let statement node in the AST
 // implements the Statement Interface.
+// const statements share this node, they only differ in their token.
 type LetStatement struct {
-	Token *token.Token // This is the token.Let token.
+	Token *token.Token // This is the token.Let or token.CONST token.
 	Name  *Identifier  // This is contains the name of the identifier token
 	Value Expression   // this is rhs of the let statement.
 }
@@ -70,6 +72,11 @@ type LetStatement struct {
 // empty method to satisfy the Statement interface.
 func (ls *LetStatement) statementNode() {}
 
+// reports whether this is a const statement whose binding can't be overwritten.
+func (ls *LetStatement) IsConst() bool {
+	return ls.Token.Type == token.CONST
+}
+
 // implementing the Node Interface.
 func (ls *LetStatement) TokenLiteral() string {
 	return ls.Token.Literal
@@ -98,6 +105,7 @@ func (ls *LetStatement) String() string {
 type Identifier struct {
 	Token *token.Token // This is the token.IDENT token.
 	Value string
+	Type  *TypeAnnotation // optional annotation of let names and parameters e.g. x: int
 }
 
 // empty method to implement Expression interface.
@@ -109,9 +117,50 @@ func (i *Identifier) TokenLiteral() string {
 }
 
 func (i *Identifier) String() string {
+	if i.Type != nil {
+		return i.Value + ": " + i.Type.String()
+	}
 	return i.Value
 }
 
+// ------------------------------Type Annotation--------------------------------
+
+// struct representing an optional type annotation e.g. the int in let x: int = 5;
+// Annotations are ignored by the evaluator and only used by the type checker.
+// Named types are int, string, bool, null and any, [T] is an array of T
+// and fn(T, U): R is a function.
+type TypeAnnotation struct {
+	Token  *token.Token      // the first token of the annotation
+	Name   string            // int, string, bool, null, any, array or fn
+	Elem   *TypeAnnotation   // element type of an array
+	Params []*TypeAnnotation // parameter types of a function
+	Return *TypeAnnotation   // return type of a function, nil if it is not given
+}
+
+// method implementing the Node interface
+func (ta *TypeAnnotation) TokenLiteral() string {
+	return ta.Token.Literal
+}
+
+func (ta *TypeAnnotation) String() string {
+	switch ta.Name {
+	case "array":
+		return "[" + ta.Elem.String() + "]"
+	case "fn":
+		params := []string{}
+		for _, p := range ta.Params {
+			params = append(params, p.String())
+		}
+		out := "fn(" + strings.Join(params, ", ") + ")"
+		if ta.Return != nil {
+			out += ": " + ta.Return.String()
+		}
+		return out
+	default:
+		return ta.Name
+	}
+}
+
 //--------------------------------Return Statement------------------------------
 
 // struct defining the node associated with a return statement
@@ -323,6 +372,7 @@ func (ie *IfExpression) String() string {
 type BlockStatement struct {
 	Token      *token.Token // should be token {
 	Statements []Statement
+	End        *token.Token // the closing }, nil if the block is not closed
 }
 
 // functions implementing the statement interface
@@ -348,9 +398,17 @@ func (bs *BlockStatement) String() string {
 // It implements the expression interface
 // it consists of the following syntax
 // fn(x, y) { return x + y; }
+// Parameters may have default values and the last one may be a rest parameter
+// that collects any remaining arguments into an array e.g. fn(x, y = 2, ...rest) {}
+// A function can also be given a name, fn add(x, y) { x + y }, which is bound
+// inside its own scope so that it can call itself.
 type FunctionLiteral struct {
 	Token      *token.Token // the 'fn' keyword
+	Name       *Identifier  // nil for anonymous functions
 	Parameters []*Identifier
+	Defaults   []Expression    // default value of each parameter, nil if the parameter has none
+	Rest       *Identifier     // the ...rest parameter, nil if there is none
+	ReturnType *TypeAnnotation // optional annotation of the return type e.g. fn(x): int {}
 	Body       *BlockStatement
 }
 
@@ -363,19 +421,39 @@ func (fl *FunctionLiteral) TokenLiteral() string {
 func (fl *FunctionLiteral) String() string {
 	var out bytes.Buffer
 
-	params := []string{}
-	for _, p := range fl.Parameters {
-		params = append(params, p.String())
-	}
 	out.WriteString(fl.TokenLiteral())
+	if fl.Name != nil {
+		out.WriteString(" " + fl.Name.String())
+	}
 	out.WriteString("(")
-	out.WriteString(strings.Join(params, ", "))
-	out.WriteString(") ")
+	out.WriteString(FormatParameters(fl.Parameters, fl.Defaults, fl.Rest, Expression.String))
+	out.WriteString(")")
+	if fl.ReturnType != nil {
+		out.WriteString(": " + fl.ReturnType.String())
+	}
+	out.WriteString(" ")
 	out.WriteString(fl.Body.String())
 
 	return out.String()
 }
 
+// FormatParameters returns the comma separated parameter list of a function,
+// e.g. x, y = 2, ...rest, using expr to print the default values.
+func FormatParameters(params []*Identifier, defaults []Expression, rest *Identifier, expr func(Expression) string) string {
+	list := []string{}
+	for i, p := range params {
+		if i < len(defaults) && defaults[i] != nil {
+			list = append(list, p.String()+" = "+expr(defaults[i]))
+		} else {
+			list = append(list, p.String())
+		}
+	}
+	if rest != nil {
+		list = append(list, "..."+rest.String())
+	}
+	return strings.Join(list, ", ")
+}
+
 // ------------------------------Call Expression Node--------------------------
 
 // struct defining the Call expression node in the ast
@@ -386,6 +464,7 @@ type CallExpression struct {
 	Token     *token.Token // The '(' token
 	Function  Expression   // Identifier or function literal
 	Arguments []Expression // list of arguments passed to a function
+	End       *token.Token // the closing ')', nil if the call is not closed
 }
 
 // functions for implementing the Expression interface
@@ -417,6 +496,7 @@ func (ce *CallExpression) String() string {
 type ArrayLiteral struct {
 	Token    *token.Token // it is the '[' token.
 	Elements []Expression
+	End      *token.Token // the closing ']', nil if the array is not closed
 }
 
 // methods to implement the expression interface
@@ -440,6 +520,27 @@ func (al *ArrayLiteral) String() string {
 	return out.String()
 }
 
+// -------------------------------Spread Expression--------------------------
+
+// struct representing the spread of an array into a list e.g. add(...args)
+// or [0, ...rest]. It is only valid as an argument or an array element.
+// It implements the expression interface
+type SpreadExpression struct {
+	Token *token.Token // the '...' token
+	Value Expression   // should produce an array
+}
+
+// methods to implement the expression interface
+func (se *SpreadExpression) expressionNode() {}
+
+func (se *SpreadExpression) TokenLiteral() string {
+	return se.Token.Literal
+}
+
+func (se *SpreadExpression) String() string {
+	return "..." + se.Value.String()
+}
+
 // -------------------------------Index Expression--------------------------
 
 // struct implementing the index access to an array
@@ -467,3 +568,95 @@ func (ie *IndexExpression) String() string {
 
 	return out.String()
 }
+
+// -------------------------------Hash Literal------------------------------
+
+// struct representing a hash literal e.g. {"name": "monkey", 1: true}
+// The pairs are kept in the order they are written in.
+// It implements the expression interface
+type HashLiteral struct {
+	Token *token.Token // the '{' token
+	Pairs []HashPair
+	End   *token.Token // the closing '}', nil if the hash is not closed
+}
+
+// struct holding a key of a hash literal and the value given for it.
+type HashPair struct {
+	Key   Expression
+	Value Expression
+}
+
+// methods to implement the expression interface
+func (hl *HashLiteral) expressionNode() {}
+
+func (hl *HashLiteral) TokenLiteral() string {
+	return hl.Token.Literal
+}
+
+func (hl *HashLiteral) String() string {
+	var out bytes.Buffer
+
+	pairs := []string{}
+	for _, pair := range hl.Pairs {
+		pairs = append(pairs, pair.Key.String()+": "+pair.Value.String())
+	}
+	out.WriteString("{")
+	out.WriteString(strings.Join(pairs, ", "))
+	out.WriteString("}")
+
+	return out.String()
+}
+
+// ------------------------------------Tokens---------------------------------
+
+// function that returns the tokens kept in node itself, not in its
+// children, in the order they appear in the source: the token of the node
+// followed by its closing token, if it has one. A program keeps none.
+func Tokens(node Node) []*token.Token {
+	var tok, end *token.Token
+	switch n := node.(type) {
+	case *LetStatement:
+		tok = n.Token
+	case *ReturnStatement:
+		tok = n.Token
+	case *ExpressionStatement:
+		tok = n.Token
+	case *BlockStatement:
+		tok, end = n.Token, n.End
+	case *Identifier:
+		tok = n.Token
+	case *TypeAnnotation:
+		tok = n.Token
+	case *IntegerLiteral:
+		tok = n.Token
+	case *StringLiteral:
+		tok = n.Token
+	case *Boolean:
+		tok = n.Token
+	case *PrefixExpression:
+		tok = n.Token
+	case *InfixExpression:
+		tok = n.Token
+	case *IfExpression:
+		tok = n.Token
+	case *FunctionLiteral:
+		tok = n.Token
+	case *CallExpression:
+		tok, end = n.Token, n.End
+	case *ArrayLiteral:
+		tok, end = n.Token, n.End
+	case *SpreadExpression:
+		tok = n.Token
+	case *IndexExpression:
+		tok = n.Token
+	case *HashLiteral:
+		tok, end = n.Token, n.End
+	}
+	var tokens []*token.Token
+	for _, t := range []*token.Token{tok, end} {
+		if t != nil {
+			tokens = append(tokens, t)
+		}
+	}
+	return tokens
+}
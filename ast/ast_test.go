@@ -1,6 +1,7 @@
 package ast
 
 import (
+	"reflect"
 	"testing"
 
 	token "github.com/Artypuppet/monkey/token"
@@ -26,3 +27,22 @@ func TestString(t *testing.T) {
 		t.Errorf("program.String() wrong. got=%q", program.String())
 	}
 }
+
+func TestTokens(t *testing.T) {
+	open := &token.Token{Type: token.LBRACKET, Literal: "[", Line: 1, Column: 1}
+	closing := &token.Token{Type: token.RBRACKET, Literal: "]", Line: 2, Column: 1}
+	tests := []struct {
+		node     Node
+		expected []*token.Token
+	}{
+		{&ArrayLiteral{Token: open, End: closing}, []*token.Token{open, closing}},
+		{&ArrayLiteral{Token: open}, []*token.Token{open}},
+		{&InfixExpression{Token: open, Left: &Identifier{Token: closing}}, []*token.Token{open}},
+		{&Program{}, nil},
+	}
+	for _, tt := range tests {
+		if got := Tokens(tt.node); !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("Tokens(%T) = %v, want %v", tt.node, got, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,486 @@
+package ast
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	token "github.com/Artypuppet/monkey/token"
+)
+
+// The JSON form of a node is an object whose "kind" is the name of its Go
+// type, e.g. "InfixExpression", followed by its token and its fields under
+// their Go names in camel case. A token is an object with "type", "literal",
+// "line" and "column". Missing optional fields, like the alternative of an
+// if expression, are left out, and lists of nodes are arrays. For example
+// the program `x + 1` is
+//
+//	{"kind":"Program","statements":[{"kind":"ExpressionStatement",
+//	"token":{...},"expression":{"kind":"InfixExpression","token":{...},
+//	"operator":"+","left":{...},"right":{...}}}],"comments":[]}
+
+// ---------------------------------Encoding----------------------------------
+
+// function that encodes the tree rooted at node as JSON.
+func ToJSON(node Node) ([]byte, error) {
+	obj, err := encodeNode(node)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(obj)
+}
+
+// type def for a JSON object that keeps its fields in the order they were
+// added, so that the output is stable and reads like the source.
+type jsonObject []jsonField
+
+type jsonField struct {
+	key   string
+	value interface{}
+}
+
+func (o jsonObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, f := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(f.key)
+		buf.Write(key)
+		buf.WriteByte(':')
+		value, err := json.Marshal(f.value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// struct defining the JSON form of a token.
+type jsonToken struct {
+	Type    token.TokenType `json:"type"`
+	Literal string          `json:"literal"`
+	Line    int             `json:"line"`
+	Column  int             `json:"column"`
+}
+
+// helper function that makes the JSON object of a node of the given kind
+// from its token and pairs of field names and values. Nil values are left out.
+func newJSONObject(kind string, tok *token.Token, fields ...interface{}) jsonObject {
+	obj := jsonObject{{"kind", kind}}
+	if tok != nil {
+		obj = append(obj, jsonField{"token", encodeToken(tok)})
+	}
+	for i := 0; i+1 < len(fields); i += 2 {
+		if !isNilValue(fields[i+1]) {
+			obj = append(obj, jsonField{fields[i].(string), fields[i+1]})
+		}
+	}
+	return obj
+}
+
+// helper function that reports whether v is nil, including the nil tokens
+// and lists that are stored in an interface without being nil themselves.
+func isNilValue(v interface{}) bool {
+	switch v := v.(type) {
+	case nil:
+		return true
+	case *jsonToken:
+		return v == nil
+	case []interface{}:
+		return v == nil
+	}
+	return false
+}
+
+func encodeToken(tok *token.Token) *jsonToken {
+	if tok == nil {
+		return nil
+	}
+	return &jsonToken{Type: tok.Type, Literal: tok.Literal, Line: tok.Line, Column: tok.Column}
+}
+
+// helper function that encodes a node, returning nil for a missing one.
+// It is written out for every type of node rather than using reflection so
+// that renaming a field in the ast doesn't silently change the format.
+func encodeNode(node Node) (interface{}, error) {
+	var err error
+	// helper function that encodes a child and remembers the first error.
+	enc := func(child Node) interface{} {
+		obj, e := encodeNode(child)
+		if e != nil && err == nil {
+			err = e
+		}
+		return obj
+	}
+	list := func(n int, child func(i int) Node) []interface{} {
+		out := make([]interface{}, n)
+		for i := range out {
+			out[i] = enc(child(i))
+		}
+		return out
+	}
+	statements := func(stmts []Statement) []interface{} {
+		return list(len(stmts), func(i int) Node { return stmts[i] })
+	}
+	expressions := func(exps []Expression) []interface{} {
+		return list(len(exps), func(i int) Node { return exps[i] })
+	}
+
+	var obj jsonObject
+	switch n := node.(type) {
+	case nil:
+		return nil, nil
+	case *Program:
+		comments := []*jsonToken{}
+		for _, c := range n.Comments {
+			comments = append(comments, encodeToken(c))
+		}
+		obj = newJSONObject("Program", nil, "statements", statements(n.Statements), "comments", comments)
+	case *LetStatement:
+		obj = newJSONObject("LetStatement", n.Token, "name", enc(nilIdentifier(n.Name)), "value", enc(n.Value))
+	case *Identifier:
+		obj = newJSONObject("Identifier", n.Token, "value", n.Value, "type", enc(nilAnnotation(n.Type)))
+	case *TypeAnnotation:
+		params := list(len(n.Params), func(i int) Node { return n.Params[i] })
+		if n.Name != "fn" {
+			params = nil
+		}
+		obj = newJSONObject("TypeAnnotation", n.Token, "name", n.Name, "elem", enc(nilAnnotation(n.Elem)),
+			"params", params, "return", enc(nilAnnotation(n.Return)))
+	case *ReturnStatement:
+		obj = newJSONObject("ReturnStatement", n.Token, "returnValue", enc(n.ReturnValue))
+	case *ExpressionStatement:
+		obj = newJSONObject("ExpressionStatement", n.Token, "expression", enc(n.Expression))
+	case *IntegerLiteral:
+		obj = newJSONObject("IntegerLiteral", n.Token, "value", n.Value)
+	case *StringLiteral:
+		obj = newJSONObject("StringLiteral", n.Token, "value", n.Value)
+	case *Boolean:
+		obj = newJSONObject("Boolean", n.Token, "value", n.Value)
+	case *PrefixExpression:
+		obj = newJSONObject("PrefixExpression", n.Token, "operator", n.Operator, "right", enc(n.Right))
+	case *InfixExpression:
+		obj = newJSONObject("InfixExpression", n.Token, "operator", n.Operator, "left", enc(n.Left), "right", enc(n.Right))
+	case *IfExpression:
+		obj = newJSONObject("IfExpression", n.Token, "condition", enc(n.Condition),
+			"consequence", enc(nilBlock(n.Consequence)), "alternative", enc(nilBlock(n.Alternative)))
+	case *BlockStatement:
+		obj = newJSONObject("BlockStatement", n.Token, "statements", statements(n.Statements), "end", encodeToken(n.End))
+	case *FunctionLiteral:
+		params := list(len(n.Parameters), func(i int) Node { return n.Parameters[i] })
+		var defaults []interface{}
+		if n.Defaults != nil {
+			defaults = expressions(n.Defaults)
+		}
+		obj = newJSONObject("FunctionLiteral", n.Token, "name", enc(nilIdentifier(n.Name)), "parameters", params,
+			"defaults", defaults, "rest", enc(nilIdentifier(n.Rest)), "returnType", enc(nilAnnotation(n.ReturnType)),
+			"body", enc(nilBlock(n.Body)))
+	case *CallExpression:
+		obj = newJSONObject("CallExpression", n.Token, "function", enc(n.Function),
+			"arguments", expressions(n.Arguments), "end", encodeToken(n.End))
+	case *ArrayLiteral:
+		obj = newJSONObject("ArrayLiteral", n.Token, "elements", expressions(n.Elements), "end", encodeToken(n.End))
+	case *SpreadExpression:
+		obj = newJSONObject("SpreadExpression", n.Token, "value", enc(n.Value))
+	case *IndexExpression:
+		obj = newJSONObject("IndexExpression", n.Token, "left", enc(n.Left), "index", enc(n.Index))
+	case *HashLiteral:
+		pairs := []interface{}{}
+		for _, pair := range n.Pairs {
+			pairs = append(pairs, newJSONObject("HashPair", nil, "key", enc(pair.Key), "value", enc(pair.Value)))
+		}
+		obj = newJSONObject("HashLiteral", n.Token, "pairs", pairs, "end", encodeToken(n.End))
+	default:
+		return nil, fmt.Errorf("ast: cannot encode node of type %T", n)
+	}
+	return obj, err
+}
+
+// helper functions that turn a nil pointer into a nil Node, which a nil
+// pointer stored in the Node interface is not.
+
+func nilIdentifier(ident *Identifier) Node {
+	if ident == nil {
+		return nil
+	}
+	return ident
+}
+
+func nilAnnotation(ta *TypeAnnotation) Node {
+	if ta == nil {
+		return nil
+	}
+	return ta
+}
+
+func nilBlock(block *BlockStatement) Node {
+	if block == nil {
+		return nil
+	}
+	return block
+}
+
+// ---------------------------------Decoding----------------------------------
+
+// function that decodes a tree encoded by ToJSON. The kind of the root
+// decides the type of the node returned, e.g. *Program for a program.
+func FromJSON(data []byte) (Node, error) {
+	d := &jsonDecoder{}
+	node := d.node(json.RawMessage(data))
+	if d.err != nil {
+		return nil, d.err
+	}
+	return node, nil
+}
+
+// struct holding the state of FromJSON. Decoding goes on after an error
+// but only the first one is kept.
+type jsonDecoder struct {
+	err error
+}
+
+func (d *jsonDecoder) errorf(format string, a ...interface{}) {
+	if d.err == nil {
+		d.err = fmt.Errorf("ast: "+format, a...)
+	}
+}
+
+// helper method that decodes a JSON value into v.
+func (d *jsonDecoder) unmarshal(data json.RawMessage, v interface{}) {
+	if err := json.Unmarshal(data, v); err != nil {
+		d.errorf("%s", err)
+	}
+}
+
+// helper method that decodes the field key of obj into v, if it is there.
+func (d *jsonDecoder) field(obj map[string]json.RawMessage, key string, v interface{}) {
+	if data, ok := obj[key]; ok {
+		d.unmarshal(data, v)
+	}
+}
+
+func (d *jsonDecoder) token(obj map[string]json.RawMessage, key string) *token.Token {
+	var t *jsonToken
+	d.field(obj, key, &t)
+	if t == nil {
+		return nil
+	}
+	return &token.Token{Type: t.Type, Literal: t.Literal, Line: t.Line, Column: t.Column}
+}
+
+// method that decodes the node held in data, or returns nil for null.
+func (d *jsonDecoder) node(data json.RawMessage) Node {
+	var obj map[string]json.RawMessage
+	d.unmarshal(data, &obj)
+	if obj == nil {
+		return nil
+	}
+	var kind string
+	d.field(obj, "kind", &kind)
+	tok := d.token(obj, "token")
+	// every node but a program has a token, nodes without one can't even
+	// be printed.
+	if tok == nil && kind != "Program" {
+		d.errorf("node of kind %q has no token", kind)
+	}
+
+	switch kind {
+	case "Program":
+		program := &Program{Statements: d.statements(obj["statements"])}
+		var comments []*jsonToken
+		d.field(obj, "comments", &comments)
+		for _, c := range comments {
+			program.Comments = append(program.Comments, &token.Token{Type: c.Type, Literal: c.Literal, Line: c.Line, Column: c.Column})
+		}
+		return program
+	case "LetStatement":
+		return &LetStatement{Token: tok, Name: d.identifier(obj["name"]), Value: d.expression(obj["value"])}
+	case "Identifier":
+		ident := &Identifier{Token: tok, Type: d.typeAnnotation(obj["type"])}
+		d.field(obj, "value", &ident.Value)
+		return ident
+	case "TypeAnnotation":
+		ta := &TypeAnnotation{Token: tok, Elem: d.typeAnnotation(obj["elem"]), Return: d.typeAnnotation(obj["return"])}
+		d.field(obj, "name", &ta.Name)
+		var params []json.RawMessage
+		d.field(obj, "params", &params)
+		for _, p := range params {
+			ta.Params = append(ta.Params, d.typeAnnotation(p))
+		}
+		if ta.Name == "fn" && ta.Params == nil {
+			ta.Params = []*TypeAnnotation{}
+		}
+		return ta
+	case "ReturnStatement":
+		return &ReturnStatement{Token: tok, ReturnValue: d.expression(obj["returnValue"])}
+	case "ExpressionStatement":
+		return &ExpressionStatement{Token: tok, Expression: d.expression(obj["expression"])}
+	case "IntegerLiteral":
+		lit := &IntegerLiteral{Token: tok}
+		d.field(obj, "value", &lit.Value)
+		return lit
+	case "StringLiteral":
+		lit := &StringLiteral{Token: tok}
+		d.field(obj, "value", &lit.Value)
+		return lit
+	case "Boolean":
+		lit := &Boolean{Token: tok}
+		d.field(obj, "value", &lit.Value)
+		return lit
+	case "PrefixExpression":
+		pe := &PrefixExpression{Token: tok, Right: d.expression(obj["right"])}
+		d.field(obj, "operator", &pe.Operator)
+		return pe
+	case "InfixExpression":
+		ie := &InfixExpression{Token: tok, Left: d.expression(obj["left"]), Right: d.expression(obj["right"])}
+		d.field(obj, "operator", &ie.Operator)
+		return ie
+	case "IfExpression":
+		return &IfExpression{
+			Token:       tok,
+			Condition:   d.expression(obj["condition"]),
+			Consequence: d.block(obj["consequence"]),
+			Alternative: d.block(obj["alternative"]),
+		}
+	case "BlockStatement":
+		return &BlockStatement{Token: tok, Statements: d.statements(obj["statements"]), End: d.token(obj, "end")}
+	case "FunctionLiteral":
+		fl := &FunctionLiteral{
+			Token:      tok,
+			Name:       d.identifier(obj["name"]),
+			Parameters: []*Identifier{},
+			Rest:       d.identifier(obj["rest"]),
+			ReturnType: d.typeAnnotation(obj["returnType"]),
+			Body:       d.block(obj["body"]),
+		}
+		var params []json.RawMessage
+		d.field(obj, "parameters", &params)
+		for _, p := range params {
+			fl.Parameters = append(fl.Parameters, d.identifier(p))
+		}
+		if _, ok := obj["defaults"]; ok {
+			fl.Defaults = d.expressions(obj["defaults"])
+		}
+		return fl
+	case "CallExpression":
+		return &CallExpression{
+			Token:     tok,
+			Function:  d.expression(obj["function"]),
+			Arguments: d.expressions(obj["arguments"]),
+			End:       d.token(obj, "end"),
+		}
+	case "ArrayLiteral":
+		return &ArrayLiteral{Token: tok, Elements: d.expressions(obj["elements"]), End: d.token(obj, "end")}
+	case "SpreadExpression":
+		return &SpreadExpression{Token: tok, Value: d.expression(obj["value"])}
+	case "IndexExpression":
+		return &IndexExpression{Token: tok, Left: d.expression(obj["left"]), Index: d.expression(obj["index"])}
+	case "HashLiteral":
+		hl := &HashLiteral{Token: tok, Pairs: []HashPair{}, End: d.token(obj, "end")}
+		var pairs []map[string]json.RawMessage
+		d.field(obj, "pairs", &pairs)
+		for _, pair := range pairs {
+			hl.Pairs = append(hl.Pairs, HashPair{Key: d.expression(pair["key"]), Value: d.expression(pair["value"])})
+		}
+		return hl
+	}
+	d.errorf("unknown node kind %q", kind)
+	return nil
+}
+
+// helper methods that decode a node of a particular type. A missing field or
+// null gives nil and a node of the wrong type is an error.
+
+func (d *jsonDecoder) statements(data json.RawMessage) []Statement {
+	var list []json.RawMessage
+	if data != nil {
+		d.unmarshal(data, &list)
+	}
+	stmts := []Statement{}
+	for _, item := range list {
+		node := d.node(item)
+		if stmt, ok := node.(Statement); ok {
+			stmts = append(stmts, stmt)
+		} else if node != nil {
+			d.errorf("%T is not a Statement", node)
+		}
+	}
+	return stmts
+}
+
+func (d *jsonDecoder) expressions(data json.RawMessage) []Expression {
+	var list []json.RawMessage
+	if data != nil {
+		d.unmarshal(data, &list)
+	}
+	exps := []Expression{}
+	for _, item := range list {
+		// defaults keep a nil for the parameters without one.
+		exps = append(exps, d.expression(item))
+	}
+	return exps
+}
+
+func (d *jsonDecoder) expression(data json.RawMessage) Expression {
+	if data == nil {
+		return nil
+	}
+	node := d.node(data)
+	if node == nil {
+		return nil
+	}
+	exp, ok := node.(Expression)
+	if !ok {
+		d.errorf("%T is not an Expression", node)
+	}
+	return exp
+}
+
+func (d *jsonDecoder) identifier(data json.RawMessage) *Identifier {
+	if data == nil {
+		return nil
+	}
+	node := d.node(data)
+	if node == nil {
+		return nil
+	}
+	ident, ok := node.(*Identifier)
+	if !ok {
+		d.errorf("%T is not an *Identifier", node)
+	}
+	return ident
+}
+
+func (d *jsonDecoder) block(data json.RawMessage) *BlockStatement {
+	if data == nil {
+		return nil
+	}
+	node := d.node(data)
+	if node == nil {
+		return nil
+	}
+	block, ok := node.(*BlockStatement)
+	if !ok {
+		d.errorf("%T is not a *BlockStatement", node)
+	}
+	return block
+}
+
+func (d *jsonDecoder) typeAnnotation(data json.RawMessage) *TypeAnnotation {
+	if data == nil {
+		return nil
+	}
+	node := d.node(data)
+	if node == nil {
+		return nil
+	}
+	ta, ok := node.(*TypeAnnotation)
+	if !ok {
+		d.errorf("%T is not a *TypeAnnotation", node)
+	}
+	return ta
+}
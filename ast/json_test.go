@@ -0,0 +1,86 @@
+package ast
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	token "github.com/Artypuppet/monkey/token"
+)
+
+func TestToJSON(t *testing.T) {
+	program := &Program{Statements: []Statement{
+		&ExpressionStatement{
+			Token: &token.Token{Type: token.IDENT, Literal: "x", Line: 1, Column: 1},
+			Expression: &InfixExpression{
+				Token:    &token.Token{Type: token.PLUS, Literal: "+", Line: 1, Column: 3},
+				Operator: "+",
+				Left:     &Identifier{Token: &token.Token{Type: token.IDENT, Literal: "x", Line: 1, Column: 1}, Value: "x"},
+				Right:    &IntegerLiteral{Token: &token.Token{Type: token.INT, Literal: "1", Line: 1, Column: 5}, Value: 1},
+			},
+		},
+	}}
+	expected := `{"kind":"Program","statements":[{"kind":"ExpressionStatement",` +
+		`"token":{"type":"IDENT","literal":"x","line":1,"column":1},` +
+		`"expression":{"kind":"InfixExpression","token":{"type":"+","literal":"+","line":1,"column":3},"operator":"+",` +
+		`"left":{"kind":"Identifier","token":{"type":"IDENT","literal":"x","line":1,"column":1},"value":"x"},` +
+		`"right":{"kind":"IntegerLiteral","token":{"type":"INT","literal":"1","line":1,"column":5},"value":1}}}],` +
+		`"comments":[]}`
+
+	data, err := ToJSON(program)
+	if err != nil {
+		t.Fatalf("ToJSON failed: %s", err)
+	}
+	if string(data) != expected {
+		t.Errorf("wrong JSON.\nexpected=%s\ngot=     %s", expected, data)
+	}
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	program := allNodesProgram()
+	data, err := ToJSON(program)
+	if err != nil {
+		t.Fatalf("ToJSON failed: %s", err)
+	}
+	node, err := FromJSON(data)
+	if err != nil {
+		t.Fatalf("FromJSON failed: %s", err)
+	}
+
+	if !reflect.DeepEqual(node, program) {
+		t.Errorf("decoded program differs.\nexpected=%s\ngot=     %s", program, node)
+	}
+	again, err := ToJSON(node)
+	if err != nil {
+		t.Fatalf("ToJSON failed: %s", err)
+	}
+	if string(again) != string(data) {
+		t.Errorf("JSON changed in round trip.\nexpected=%s\ngot=     %s", data, again)
+	}
+}
+
+func TestFromJSONErrors(t *testing.T) {
+	tok := `{"type":"INT","literal":"1","line":1,"column":1}`
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`{"kind":"Nope","token":` + tok + `}`, `ast: unknown node kind "Nope"`},
+		{`{"kind":"Program","statements":[{"kind":"Boolean","token":` + tok + `,"value":true}]}`, "ast: *ast.Boolean is not a Statement"},
+		{`{"kind":"ReturnStatement","token":` + tok + `,"returnValue":{"kind":"ReturnStatement","token":` + tok + `}}`, "ast: *ast.ReturnStatement is not an Expression"},
+		{`{"kind":"IntegerLiteral","token":` + tok + `,"value":"1"}`, "ast: json: cannot unmarshal string into Go value of type int64"},
+		{`{"kind":"IntegerLiteral","value":1}`, `ast: node of kind "IntegerLiteral" has no token`},
+		{`{"kind":"Program","statements":[{"kind":"ExpressionStatement","token":null}]}`, `ast: node of kind "ExpressionStatement" has no token`},
+		{`[`, "ast: unexpected end of JSON input"},
+	}
+	for _, tt := range tests {
+		_, err := FromJSON([]byte(tt.input))
+		if err == nil {
+			t.Errorf("expected an error for %s", tt.input)
+			continue
+		}
+		if !strings.HasPrefix(err.Error(), tt.expected) {
+			t.Errorf("wrong error for %s. expected=%q, got=%q", tt.input, tt.expected, err)
+		}
+	}
+}
@@ -0,0 +1,330 @@
+package ast
+
+import "fmt"
+
+// ---------------------------------Visitor----------------------------------
+
+// interface implemented by the callers of Walk.
+// Visit is called for every node. If it returns a visitor w, the children of
+// the node are walked with w and w.Visit(nil) is called once they are done.
+type Visitor interface {
+	Visit(node Node) (w Visitor)
+}
+
+// function that traverses the tree rooted at node in depth first order,
+// in the order the nodes appear in the source. It starts by calling
+// v.Visit(node) and nil children are skipped.
+func Walk(v Visitor, node Node) {
+	if v = v.Visit(node); v == nil {
+		return
+	}
+
+	switch n := node.(type) {
+	case *Program:
+		for _, s := range n.Statements {
+			walkIf(v, s)
+		}
+	case *LetStatement:
+		if n.Name != nil {
+			Walk(v, n.Name)
+		}
+		walkIf(v, n.Value)
+	case *Identifier:
+		if n.Type != nil {
+			Walk(v, n.Type)
+		}
+	case *TypeAnnotation:
+		if n.Elem != nil {
+			Walk(v, n.Elem)
+		}
+		for _, p := range n.Params {
+			Walk(v, p)
+		}
+		if n.Return != nil {
+			Walk(v, n.Return)
+		}
+	case *ReturnStatement:
+		walkIf(v, n.ReturnValue)
+	case *ExpressionStatement:
+		walkIf(v, n.Expression)
+	case *IntegerLiteral, *StringLiteral, *Boolean:
+		// no children
+	case *PrefixExpression:
+		walkIf(v, n.Right)
+	case *InfixExpression:
+		walkIf(v, n.Left)
+		walkIf(v, n.Right)
+	case *IfExpression:
+		walkIf(v, n.Condition)
+		if n.Consequence != nil {
+			Walk(v, n.Consequence)
+		}
+		if n.Alternative != nil {
+			Walk(v, n.Alternative)
+		}
+	case *BlockStatement:
+		for _, s := range n.Statements {
+			walkIf(v, s)
+		}
+	case *FunctionLiteral:
+		if n.Name != nil {
+			Walk(v, n.Name)
+		}
+		for i, p := range n.Parameters {
+			Walk(v, p)
+			if i < len(n.Defaults) {
+				walkIf(v, n.Defaults[i])
+			}
+		}
+		if n.Rest != nil {
+			Walk(v, n.Rest)
+		}
+		if n.ReturnType != nil {
+			Walk(v, n.ReturnType)
+		}
+		if n.Body != nil {
+			Walk(v, n.Body)
+		}
+	case *CallExpression:
+		walkIf(v, n.Function)
+		for _, a := range n.Arguments {
+			walkIf(v, a)
+		}
+	case *ArrayLiteral:
+		for _, e := range n.Elements {
+			walkIf(v, e)
+		}
+	case *SpreadExpression:
+		walkIf(v, n.Value)
+	case *IndexExpression:
+		walkIf(v, n.Left)
+		walkIf(v, n.Index)
+	case *HashLiteral:
+		for _, pair := range n.Pairs {
+			walkIf(v, pair.Key)
+			walkIf(v, pair.Value)
+		}
+	default:
+		panic(fmt.Sprintf("ast.Walk: unexpected node type %T", n))
+	}
+
+	v.Visit(nil)
+}
+
+// helper function that walks node unless it is nil.
+// Statements and expressions are interfaces so a missing one, e.g. after a
+// parse error, is a nil interface.
+func walkIf(v Visitor, node Node) {
+	if node != nil {
+		Walk(v, node)
+	}
+}
+
+// type def to turn a function into a Visitor for Inspect.
+type inspector func(Node) bool
+
+func (f inspector) Visit(node Node) Visitor {
+	if f(node) {
+		return f
+	}
+	return nil
+}
+
+// function that traverses the tree rooted at node in depth first order.
+// It calls f(node) for every node, and if f returns true it calls f for the
+// children of the node followed by f(nil).
+func Inspect(node Node, f func(Node) bool) {
+	Walk(inspector(f), node)
+}
+
+// ---------------------------------Rewrite----------------------------------
+
+// function that rewrites the tree rooted at node from the bottom up.
+// The children of a node are rewritten before f is called on the node itself,
+// and the node f returns takes the place of the node in its parent, so f
+// should return the node unchanged when there is nothing to replace. Nodes are
+// updated in place and the new root is returned.
+// Returning nil from f removes a statement, argument, element or hash pair
+// from the list it is in and leaves any other field empty. Returning a node that
+// doesn't fit where the old one was, e.g. a statement in place of an
+// expression, panics.
+func Rewrite(node Node, f func(Node) Node) Node {
+	switch n := node.(type) {
+	case *Program:
+		n.Statements = rewriteStatements(n.Statements, f)
+	case *LetStatement:
+		n.Name = rewriteIdentifier(n.Name, f)
+		n.Value = rewriteExpression(n.Value, f)
+	case *Identifier:
+		n.Type = rewriteTypeAnnotation(n.Type, f)
+	case *TypeAnnotation:
+		n.Elem = rewriteTypeAnnotation(n.Elem, f)
+		params := n.Params[:0]
+		for _, p := range n.Params {
+			if p = rewriteTypeAnnotation(p, f); p != nil {
+				params = append(params, p)
+			}
+		}
+		n.Params = params
+		n.Return = rewriteTypeAnnotation(n.Return, f)
+	case *ReturnStatement:
+		n.ReturnValue = rewriteExpression(n.ReturnValue, f)
+	case *ExpressionStatement:
+		n.Expression = rewriteExpression(n.Expression, f)
+	case *IntegerLiteral, *StringLiteral, *Boolean:
+		// no children
+	case *PrefixExpression:
+		n.Right = rewriteExpression(n.Right, f)
+	case *InfixExpression:
+		n.Left = rewriteExpression(n.Left, f)
+		n.Right = rewriteExpression(n.Right, f)
+	case *IfExpression:
+		n.Condition = rewriteExpression(n.Condition, f)
+		n.Consequence = rewriteBlock(n.Consequence, f)
+		n.Alternative = rewriteBlock(n.Alternative, f)
+	case *BlockStatement:
+		n.Statements = rewriteStatements(n.Statements, f)
+	case *FunctionLiteral:
+		n.Name = rewriteIdentifier(n.Name, f)
+		// a parameter and its default are removed together.
+		params := n.Parameters[:0]
+		var defaults []Expression
+		for i, p := range n.Parameters {
+			var def Expression
+			if i < len(n.Defaults) {
+				def = n.Defaults[i]
+			}
+			if p = rewriteIdentifier(p, f); p == nil {
+				continue
+			}
+			params = append(params, p)
+			if len(n.Defaults) > 0 {
+				defaults = append(defaults, rewriteExpression(def, f))
+			}
+		}
+		n.Parameters = params
+		n.Defaults = defaults
+		n.Rest = rewriteIdentifier(n.Rest, f)
+		n.ReturnType = rewriteTypeAnnotation(n.ReturnType, f)
+		n.Body = rewriteBlock(n.Body, f)
+	case *CallExpression:
+		n.Function = rewriteExpression(n.Function, f)
+		n.Arguments = rewriteExpressions(n.Arguments, f)
+	case *ArrayLiteral:
+		n.Elements = rewriteExpressions(n.Elements, f)
+	case *SpreadExpression:
+		n.Value = rewriteExpression(n.Value, f)
+	case *IndexExpression:
+		n.Left = rewriteExpression(n.Left, f)
+		n.Index = rewriteExpression(n.Index, f)
+	case *HashLiteral:
+		// a pair goes when its key or its value is removed.
+		pairs := n.Pairs[:0]
+		for _, pair := range n.Pairs {
+			key := rewriteExpression(pair.Key, f)
+			value := rewriteExpression(pair.Value, f)
+			if key != nil && value != nil {
+				pairs = append(pairs, HashPair{Key: key, Value: value})
+			}
+		}
+		n.Pairs = pairs
+	default:
+		panic(fmt.Sprintf("ast.Rewrite: unexpected node type %T", n))
+	}
+	return f(node)
+}
+
+// helper functions that rewrite a child of a particular type. They skip
+// missing children and check that the replacement is of the same kind.
+
+func rewriteStatements(stmts []Statement, f func(Node) Node) []Statement {
+	out := stmts[:0]
+	for _, s := range stmts {
+		if s == nil {
+			continue
+		}
+		n := Rewrite(s, f)
+		if n == nil {
+			continue
+		}
+		stmt, ok := n.(Statement)
+		if !ok {
+			panic(fmt.Sprintf("ast.Rewrite: %T is not a Statement", n))
+		}
+		out = append(out, stmt)
+	}
+	return out
+}
+
+func rewriteExpressions(exps []Expression, f func(Node) Node) []Expression {
+	if exps == nil {
+		return nil
+	}
+	out := exps[:0]
+	for _, e := range exps {
+		if e = rewriteExpression(e, f); e != nil {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+func rewriteExpression(exp Expression, f func(Node) Node) Expression {
+	if exp == nil {
+		return nil
+	}
+	n := Rewrite(exp, f)
+	if n == nil {
+		return nil
+	}
+	e, ok := n.(Expression)
+	if !ok {
+		panic(fmt.Sprintf("ast.Rewrite: %T is not an Expression", n))
+	}
+	return e
+}
+
+func rewriteIdentifier(ident *Identifier, f func(Node) Node) *Identifier {
+	if ident == nil {
+		return nil
+	}
+	n := Rewrite(ident, f)
+	if n == nil {
+		return nil
+	}
+	i, ok := n.(*Identifier)
+	if !ok {
+		panic(fmt.Sprintf("ast.Rewrite: %T is not an *Identifier", n))
+	}
+	return i
+}
+
+func rewriteBlock(block *BlockStatement, f func(Node) Node) *BlockStatement {
+	if block == nil {
+		return nil
+	}
+	n := Rewrite(block, f)
+	if n == nil {
+		return nil
+	}
+	b, ok := n.(*BlockStatement)
+	if !ok {
+		panic(fmt.Sprintf("ast.Rewrite: %T is not a *BlockStatement", n))
+	}
+	return b
+}
+
+func rewriteTypeAnnotation(ta *TypeAnnotation, f func(Node) Node) *TypeAnnotation {
+	if ta == nil {
+		return nil
+	}
+	n := Rewrite(ta, f)
+	if n == nil {
+		return nil
+	}
+	t, ok := n.(*TypeAnnotation)
+	if !ok {
+		panic(fmt.Sprintf("ast.Rewrite: %T is not a *TypeAnnotation", n))
+	}
+	return t
+}
@@ -0,0 +1,247 @@
+package ast
+
+import (
+	"fmt"
+	"testing"
+
+	token "github.com/Artypuppet/monkey/token"
+)
+
+// helper function that builds a program containing every node type, the
+// ast of
+// let x: [int] = [1, ...rest, {"k": v}];
+// return -a + b;
+// fn f(p: int, q = "s", ...r): fn(int): bool { if (true) { x[0] } else { g(p) } }
+func allNodesProgram() *Program {
+	tok := func(t token.TokenType, lit string) *token.Token {
+		return &token.Token{Type: t, Literal: lit}
+	}
+	ident := func(name string) *Identifier {
+		return &Identifier{Token: tok(token.IDENT, name), Value: name}
+	}
+	typeName := func(name string) *TypeAnnotation {
+		return &TypeAnnotation{Token: tok(token.IDENT, name), Name: name}
+	}
+
+	x := ident("x")
+	x.Type = &TypeAnnotation{Token: tok(token.LBRACKET, "["), Name: "array", Elem: typeName("int")}
+	p := ident("p")
+	p.Type = typeName("int")
+
+	return &Program{Statements: []Statement{
+		&LetStatement{
+			Token: tok(token.LET, "let"),
+			Name:  x,
+			Value: &ArrayLiteral{Token: tok(token.LBRACKET, "["), Elements: []Expression{
+				&IntegerLiteral{Token: tok(token.INT, "1"), Value: 1},
+				&SpreadExpression{Token: tok(token.ELLIPSIS, "..."), Value: ident("rest")},
+				&HashLiteral{Token: tok(token.LBRACE, "{"), Pairs: []HashPair{
+					{Key: &StringLiteral{Token: tok(token.STRING, "k"), Value: "k"}, Value: ident("v")},
+				}},
+			}},
+		},
+		&ReturnStatement{
+			Token: tok(token.RETURN, "return"),
+			ReturnValue: &InfixExpression{
+				Token:    tok(token.PLUS, "+"),
+				Operator: "+",
+				Left:     &PrefixExpression{Token: tok(token.MINUS, "-"), Operator: "-", Right: ident("a")},
+				Right:    ident("b"),
+			},
+		},
+		&ExpressionStatement{
+			Token: tok(token.FUNCTION, "fn"),
+			Expression: &FunctionLiteral{
+				Token:      tok(token.FUNCTION, "fn"),
+				Name:       ident("f"),
+				Parameters: []*Identifier{p, ident("q")},
+				Defaults:   []Expression{nil, &StringLiteral{Token: tok(token.STRING, "s"), Value: "s"}},
+				Rest:       ident("r"),
+				ReturnType: &TypeAnnotation{
+					Token:  tok(token.FUNCTION, "fn"),
+					Name:   "fn",
+					Params: []*TypeAnnotation{typeName("int")},
+					Return: typeName("bool"),
+				},
+				Body: &BlockStatement{Token: tok(token.LBRACE, "{"), Statements: []Statement{
+					&ExpressionStatement{
+						Token: tok(token.IF, "if"),
+						Expression: &IfExpression{
+							Token:     tok(token.IF, "if"),
+							Condition: &Boolean{Token: tok(token.TRUE, "true"), Value: true},
+							Consequence: &BlockStatement{Token: tok(token.LBRACE, "{"), Statements: []Statement{
+								&ExpressionStatement{Token: tok(token.IDENT, "x"), Expression: &IndexExpression{
+									Token: tok(token.LBRACKET, "["),
+									Left:  ident("x"),
+									Index: &IntegerLiteral{Token: tok(token.INT, "0"), Value: 0},
+								}},
+							}},
+							Alternative: &BlockStatement{Token: tok(token.LBRACE, "{"), Statements: []Statement{
+								&ExpressionStatement{Token: tok(token.IDENT, "g"), Expression: &CallExpression{
+									Token:     tok(token.LPAREN, "("),
+									Function:  ident("g"),
+									Arguments: []Expression{ident("p")},
+								}},
+							}},
+						},
+					},
+				}},
+			},
+		},
+	}}
+}
+
+// the type of every node in allNodesProgram in the order they are walked.
+var allNodesOrder = []string{
+	"*ast.Program",
+	"*ast.LetStatement", "*ast.Identifier", "*ast.TypeAnnotation", "*ast.TypeAnnotation",
+	"*ast.ArrayLiteral", "*ast.IntegerLiteral", "*ast.SpreadExpression", "*ast.Identifier",
+	"*ast.HashLiteral", "*ast.StringLiteral", "*ast.Identifier",
+	"*ast.ReturnStatement", "*ast.InfixExpression", "*ast.PrefixExpression", "*ast.Identifier", "*ast.Identifier",
+	"*ast.ExpressionStatement", "*ast.FunctionLiteral", "*ast.Identifier",
+	"*ast.Identifier", "*ast.TypeAnnotation", "*ast.Identifier", "*ast.StringLiteral", "*ast.Identifier",
+	"*ast.TypeAnnotation", "*ast.TypeAnnotation", "*ast.TypeAnnotation",
+	"*ast.BlockStatement", "*ast.ExpressionStatement", "*ast.IfExpression", "*ast.Boolean",
+	"*ast.BlockStatement", "*ast.ExpressionStatement", "*ast.IndexExpression", "*ast.Identifier", "*ast.IntegerLiteral",
+	"*ast.BlockStatement", "*ast.ExpressionStatement", "*ast.CallExpression", "*ast.Identifier", "*ast.Identifier",
+}
+
+func TestInspect(t *testing.T) {
+	program := allNodesProgram()
+
+	visited := []string{}
+	depth := 0
+	Inspect(program, func(n Node) bool {
+		if n == nil {
+			depth--
+			return false
+		}
+		depth++
+		visited = append(visited, fmt.Sprintf("%T", n))
+		return true
+	})
+
+	if depth != 0 {
+		t.Errorf("f(nil) not called once per node. depth=%d", depth)
+	}
+	if len(visited) != len(allNodesOrder) {
+		t.Fatalf("wrong number of nodes visited. want=%d, got=%d: %v", len(allNodesOrder), len(visited), visited)
+	}
+	for i, typ := range allNodesOrder {
+		if visited[i] != typ {
+			t.Errorf("node %d wrong. want=%s, got=%s", i, typ, visited[i])
+		}
+	}
+}
+
+func TestInspectSkipsChildren(t *testing.T) {
+	count := 0
+	Inspect(allNodesProgram(), func(n Node) bool {
+		if n == nil {
+			return false
+		}
+		count++
+		_, isFunction := n.(*FunctionLiteral)
+		return !isFunction
+	})
+	// everything up to and including the function literal.
+	if count != 19 {
+		t.Errorf("wrong number of nodes visited. want=19, got=%d", count)
+	}
+}
+
+func TestRewriteRoundTrip(t *testing.T) {
+	program := allNodesProgram()
+	expected := program.String()
+
+	visited := []string{}
+	result := Rewrite(program, func(n Node) Node {
+		visited = append(visited, fmt.Sprintf("%T", n))
+		return n
+	})
+
+	if result != program {
+		t.Fatalf("root replaced by %T", result)
+	}
+	if program.String() != expected {
+		t.Errorf("program changed. want=%q, got=%q", expected, program.String())
+	}
+	// every node is passed to f once, children before their parent.
+	if len(visited) != len(allNodesOrder) {
+		t.Fatalf("wrong number of nodes rewritten. want=%d, got=%d", len(allNodesOrder), len(visited))
+	}
+	if visited[len(visited)-1] != "*ast.Program" {
+		t.Errorf("program not rewritten last. got=%s", visited[len(visited)-1])
+	}
+	seen := map[string]int{}
+	for _, typ := range allNodesOrder {
+		seen[typ]++
+	}
+	for _, typ := range visited {
+		seen[typ]--
+	}
+	for typ, n := range seen {
+		if n != 0 {
+			t.Errorf("%s rewritten the wrong number of times. off by %d", typ, n)
+		}
+	}
+}
+
+func TestRewriteReplaces(t *testing.T) {
+	program := allNodesProgram()
+	Rewrite(program, func(n Node) Node {
+		switch n := n.(type) {
+		case *IntegerLiteral:
+			value := n.Value + 10
+			return &IntegerLiteral{Token: &token.Token{Type: token.INT, Literal: fmt.Sprint(value)}, Value: value}
+		case *Identifier:
+			if n.Value == "b" {
+				return &StringLiteral{Token: n.Token, Value: "b"}
+			}
+		case *ReturnStatement:
+			// children are rewritten first.
+			if n.ReturnValue.String() != "((-a) + b)" {
+				t.Errorf("children of return not rewritten first. got=%q", n.ReturnValue.String())
+			}
+		case *SpreadExpression:
+			return nil
+		case *ExpressionStatement:
+			if _, ok := n.Expression.(*CallExpression); ok {
+				return nil
+			}
+		}
+		return n
+	})
+
+	let := program.Statements[0].(*LetStatement)
+	if let.Value.String() != "[11, {k: v}]" {
+		t.Errorf("array not rewritten. got=%q", let.Value.String())
+	}
+	ret := program.Statements[1].(*ReturnStatement)
+	if ret.ReturnValue.String() != "((-a) + b)" {
+		t.Errorf("return value not rewritten. got=%q", ret.ReturnValue.String())
+	}
+	fl := program.Statements[2].(*ExpressionStatement).Expression.(*FunctionLiteral)
+	ie := fl.Body.Statements[0].(*ExpressionStatement).Expression.(*IfExpression)
+	if len(ie.Alternative.Statements) != 0 {
+		t.Errorf("call statement not removed. got=%q", ie.Alternative.String())
+	}
+	if ie.Consequence.String() != "(x[10])" {
+		t.Errorf("index not rewritten. got=%q", ie.Consequence.String())
+	}
+}
+
+func TestRewritePanicsOnWrongKind(t *testing.T) {
+	defer func() {
+		r := recover()
+		if r != "ast.Rewrite: *ast.LetStatement is not an Expression" {
+			t.Errorf("wrong panic. got=%v", r)
+		}
+	}()
+	Rewrite(allNodesProgram(), func(n Node) Node {
+		if _, ok := n.(*IntegerLiteral); ok {
+			return &LetStatement{}
+		}
+		return n
+	})
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	resolver "github.com/Artypuppet/monkey/resolver"
+	testrunner "github.com/Artypuppet/monkey/testrunner"
+	types "github.com/Artypuppet/monkey/types"
+)
+
+// function implementing `monkey check files...`.
+// Every file is parsed, resolved and type checked without being run.
+// The exit code is 1 if any file has an error, warnings don't count.
+func checkCommand(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintf(os.Stderr, "usage: monkey check files...\n")
+		return 2
+	}
+
+	code := 0
+	for _, path := range args {
+		program := parseFile(path)
+		if program == nil {
+			code = 1
+			continue
+		}
+
+		diagnostics := resolver.Resolve(program, testrunner.Globals(path))
+		for _, d := range diagnostics {
+			fmt.Fprintf(os.Stderr, "%s:%s\n", path, d)
+		}
+		if resolver.HasErrors(diagnostics) {
+			code = 1
+		}
+
+		for _, e := range types.Check(program) {
+			fmt.Fprintf(os.Stderr, "%s:%s\n", path, e)
+			code = 1
+		}
+	}
+	return code
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckTestFile(t *testing.T) {
+	dir := t.TempDir()
+	source := "fn test_add() { assert_eq(1 + 1, 2); assert(true); assert_error(fn() { 1 / 0 }) }"
+	testFile := filepath.Join(dir, "add_test.mk")
+	otherFile := filepath.Join(dir, "add.mk")
+	for _, path := range []string{testFile, otherFile} {
+		if err := os.WriteFile(path, []byte(source), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the assertions are only known in test files.
+	if code := checkCommand([]string{testFile}); code != 0 {
+		t.Errorf("checking %s failed with code %d", testFile, code)
+	}
+	if code := checkCommand([]string{otherFile}); code != 1 {
+		t.Errorf("checking %s returned %d, want 1", otherFile, code)
+	}
+}
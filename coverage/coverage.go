@@ -0,0 +1,172 @@
+package coverage
+
+import (
+	"sort"
+
+	ast "github.com/Artypuppet/monkey/ast"
+	object "github.com/Artypuppet/monkey/object"
+)
+
+// struct defining a coverage recorder. It is the evaluator.Hook of the
+// programs it follows and counts how often their statements and the arms
+// of their if expressions ran.
+type Coverage struct {
+	Files []*File
+
+	statements map[ast.Statement]*Statement
+	branches   map[*ast.IfExpression]*Branch
+	arms       map[*ast.BlockStatement]*int // the counter of each arm
+}
+
+// struct holding the coverage of one file.
+type File struct {
+	Path       string
+	Source     string
+	Statements []*Statement // in the order of the source
+	Branches   []*Branch
+}
+
+// struct holding how often a statement ran.
+type Statement struct {
+	Line, Column int
+	Count        int
+}
+
+// struct holding how often an if expression ran and which arms it took.
+// An if without else still has an else arm, taken when the condition is false.
+type Branch struct {
+	Line, Column int
+	Count        int
+	Then         int
+	Else         int
+	hasElse      bool
+}
+
+// constructor for an empty coverage recorder.
+func New() *Coverage {
+	return &Coverage{
+		statements: make(map[ast.Statement]*Statement),
+		branches:   make(map[*ast.IfExpression]*Branch),
+		arms:       make(map[*ast.BlockStatement]*int),
+	}
+}
+
+// method that adds a program read from the file at path, whose text is
+// source, to the files covered. It has to be added before it runs.
+func (c *Coverage) Add(path, source string, program *ast.Program) *File {
+	f := &File{Path: path, Source: source}
+	ast.Inspect(program, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.LetStatement:
+			c.addStatement(f, node, node.Token.Line, node.Token.Column)
+		case *ast.ReturnStatement:
+			c.addStatement(f, node, node.Token.Line, node.Token.Column)
+		case *ast.ExpressionStatement:
+			c.addStatement(f, node, node.Token.Line, node.Token.Column)
+		case *ast.IfExpression:
+			b := &Branch{Line: node.Token.Line, Column: node.Token.Column, hasElse: node.Alternative != nil}
+			f.Branches = append(f.Branches, b)
+			c.branches[node] = b
+			c.arms[node.Consequence] = &b.Then
+			if node.Alternative != nil {
+				c.arms[node.Alternative] = &b.Else
+			}
+		}
+		return true
+	})
+	sort.SliceStable(f.Statements, func(i, j int) bool {
+		a, b := f.Statements[i], f.Statements[j]
+		return a.Line < b.Line || a.Line == b.Line && a.Column < b.Column
+	})
+	c.Files = append(c.Files, f)
+	return f
+}
+
+func (c *Coverage) addStatement(f *File, node ast.Statement, line, column int) {
+	s := &Statement{Line: line, Column: column}
+	f.Statements = append(f.Statements, s)
+	c.statements[node] = s
+}
+
+// -----------------------------------Hook------------------------------------
+
+// method implementing evaluator.Hook that counts the statements, ifs and
+// arms about to run.
+func (c *Coverage) Before(node ast.Node, env *object.Environment) *object.Error {
+	switch node := node.(type) {
+	case *ast.IfExpression:
+		if b, ok := c.branches[node]; ok {
+			b.Count++
+		}
+	case *ast.BlockStatement:
+		if count, ok := c.arms[node]; ok {
+			*count++
+		}
+	case ast.Statement:
+		if s, ok := c.statements[node]; ok {
+			s.Count++
+		}
+	}
+	return nil
+}
+
+// methods implementing evaluator.Hook, calls are not counted.
+
+func (c *Coverage) EnterCall(fn *object.Function, args []object.Object, env *object.Environment) {
+}
+
+func (c *Coverage) ExitCall(fn *object.Function, result object.Object) {
+}
+
+// ---------------------------------Results-----------------------------------
+
+// method that returns how often the else arm ran.
+func (b *Branch) ElseCount() int {
+	if b.hasElse {
+		return b.Else
+	}
+	return b.Count - b.Then
+}
+
+// struct holding how often a line ran, which is how often its most run
+// statement ran.
+type Line struct {
+	Number int
+	Count  int
+}
+
+// method that returns the lines a statement starts on, in order.
+func (f *File) Lines() []Line {
+	lines := []Line{}
+	for _, s := range f.Statements {
+		if n := len(lines); n > 0 && lines[n-1].Number == s.Line {
+			lines[n-1].Count = max(lines[n-1].Count, s.Count)
+			continue
+		}
+		lines = append(lines, Line{Number: s.Line, Count: s.Count})
+	}
+	return lines
+}
+
+// method that returns how many statements there are and how many ran.
+func (f *File) StatementsCovered() (covered, total int) {
+	for _, s := range f.Statements {
+		if s.Count > 0 {
+			covered++
+		}
+	}
+	return covered, len(f.Statements)
+}
+
+// method that returns how many arms there are, two per if, and how many ran.
+func (f *File) BranchesCovered() (covered, total int) {
+	for _, b := range f.Branches {
+		if b.Then > 0 {
+			covered++
+		}
+		if b.ElseCount() > 0 {
+			covered++
+		}
+	}
+	return covered, 2 * len(f.Branches)
+}
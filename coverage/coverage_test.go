@@ -0,0 +1,122 @@
+package coverage
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	evaluator "github.com/Artypuppet/monkey/evaluator"
+	lexer "github.com/Artypuppet/monkey/lexer"
+	object "github.com/Artypuppet/monkey/object"
+	parser "github.com/Artypuppet/monkey/parser"
+)
+
+const testProgram = `let sign = fn(n) {
+  if (n < 0) {
+    return -1;
+  }
+  if (n == 0) { 0 } else { 1 }
+};
+let a = sign(5);
+let b = sign(0);
+let unused = fn() {
+  99
+};`
+
+func cover(t *testing.T) *Coverage {
+	t.Helper()
+	p := parser.New(lexer.New(testProgram))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+	c := New()
+	c.Add("sign.mk", testProgram, program)
+	ip := &evaluator.Interpreter{Hook: c}
+	if result, ok := ip.Eval(program, object.NewEnvironment()).(*object.Error); ok {
+		t.Fatalf("evaluation failed: %s", result.Message)
+	}
+	return c
+}
+
+func TestCoverage(t *testing.T) {
+	f := cover(t).Files[0]
+
+	expectedLines := []Line{{1, 1}, {2, 2}, {3, 0}, {5, 2}, {7, 1}, {8, 1}, {9, 1}, {10, 0}}
+	if lines := f.Lines(); !reflect.DeepEqual(lines, expectedLines) {
+		t.Errorf("wrong lines.\nexpected=%v\ngot=%v", expectedLines, lines)
+	}
+	if covered, total := f.StatementsCovered(); covered != 8 || total != 10 {
+		t.Errorf("wrong statements. expected=8/10, got=%d/%d", covered, total)
+	}
+
+	expectedBranches := []struct{ line, count, then, els int }{
+		{2, 2, 0, 2},
+		{5, 2, 1, 1},
+	}
+	if len(f.Branches) != len(expectedBranches) {
+		t.Fatalf("wrong number of branches. expected=%d, got=%d", len(expectedBranches), len(f.Branches))
+	}
+	for i, tt := range expectedBranches {
+		b := f.Branches[i]
+		if b.Line != tt.line || b.Count != tt.count || b.Then != tt.then || b.ElseCount() != tt.els {
+			t.Errorf("wrong branch %d. expected=%v, got=%+v else=%d", i, tt, b, b.ElseCount())
+		}
+	}
+	if covered, total := f.BranchesCovered(); covered != 3 || total != 4 {
+		t.Errorf("wrong branches. expected=3/4, got=%d/%d", covered, total)
+	}
+}
+
+func TestReports(t *testing.T) {
+	c := cover(t)
+
+	var text bytes.Buffer
+	c.WriteText(&text)
+	expectedText := "sign.mk: statements 8/10 (80.0%), branches 3/4 (75.0%)\n    not run: lines 3, 10\n"
+	if text.String() != expectedText {
+		t.Errorf("wrong text.\nexpected=%q\ngot=%q", expectedText, text.String())
+	}
+
+	var lcov bytes.Buffer
+	c.WriteLCOV(&lcov)
+	expectedLCOV := `TN:
+SF:sign.mk
+BRDA:2,0,0,0
+BRDA:2,0,1,2
+BRDA:5,1,0,1
+BRDA:5,1,1,1
+BRF:4
+BRH:3
+DA:1,1
+DA:2,2
+DA:3,0
+DA:5,2
+DA:7,1
+DA:8,1
+DA:9,1
+DA:10,0
+LF:8
+LH:6
+end_of_record
+`
+	if lcov.String() != expectedLCOV {
+		t.Errorf("wrong LCOV.\nexpected=%q\ngot=%q", expectedLCOV, lcov.String())
+	}
+
+	var html bytes.Buffer
+	if err := c.WriteHTML(&html); err != nil {
+		t.Fatalf("WriteHTML failed: %s", err)
+	}
+	for _, s := range []string{
+		`<h2>sign.mk</h2>`,
+		`<span class="covered"><span class="number">2</span> <span class="count">2x</span>  if (n &lt; 0) {</span><span class="note">if: then 0, else 2</span>`,
+		`<span class="uncovered"><span class="number">3</span> <span class="count">0x</span>    return -1;</span>`,
+		`<span class=""><span class="number">4</span> <span class="count"></span>  }</span>`,
+	} {
+		if !strings.Contains(html.String(), s) {
+			t.Errorf("the HTML report does not contain %q:\n%s", s, html.String())
+		}
+	}
+}
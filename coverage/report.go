@@ -0,0 +1,185 @@
+package coverage
+
+import (
+	"bufio"
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+)
+
+// method that writes a summary of the coverage of every file and of all of
+// them, with the lines that never ran.
+func (c *Coverage) WriteText(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	var statements, statementsTotal, branches, branchesTotal int
+	for _, f := range c.Files {
+		s, st := f.StatementsCovered()
+		b, bt := f.BranchesCovered()
+		statements, statementsTotal = statements+s, statementsTotal+st
+		branches, branchesTotal = branches+b, branchesTotal+bt
+		fmt.Fprintf(bw, "%s: %s\n", f.Path, summary(s, st, b, bt))
+		if ranges := uncoveredRanges(f); ranges != "" {
+			fmt.Fprintf(bw, "    not run: lines %s\n", ranges)
+		}
+	}
+	if len(c.Files) > 1 {
+		fmt.Fprintf(bw, "total: %s\n", summary(statements, statementsTotal, branches, branchesTotal))
+	}
+	return bw.Flush()
+}
+
+// helper function that formats the counts of a summary.
+func summary(statements, statementsTotal, branches, branchesTotal int) string {
+	return fmt.Sprintf("statements %s, branches %s",
+		ratio(statements, statementsTotal), ratio(branches, branchesTotal))
+}
+
+func ratio(covered, total int) string {
+	if total == 0 {
+		return "0/0"
+	}
+	return fmt.Sprintf("%d/%d (%.1f%%)", covered, total, 100*float64(covered)/float64(total))
+}
+
+// helper function that lists the lines of f that never ran, merging
+// consecutive lines into ranges like 7-9.
+func uncoveredRanges(f *File) string {
+	ranges := []string{}
+	start, end := 0, 0
+	flush := func() {
+		if start == 0 {
+			return
+		}
+		if start == end {
+			ranges = append(ranges, fmt.Sprint(start))
+		} else {
+			ranges = append(ranges, fmt.Sprintf("%d-%d", start, end))
+		}
+		start = 0
+	}
+	for _, line := range f.Lines() {
+		if line.Count > 0 {
+			flush()
+			continue
+		}
+		if start != 0 && line.Number == end+1 {
+			end = line.Number
+			continue
+		}
+		flush()
+		start, end = line.Number, line.Number
+	}
+	flush()
+	return strings.Join(ranges, ", ")
+}
+
+// method that writes the coverage in the LCOV tracefile format read by
+// genhtml and most editors.
+func (c *Coverage) WriteLCOV(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "TN:")
+	for _, f := range c.Files {
+		fmt.Fprintf(bw, "SF:%s\n", f.Path)
+
+		for i, b := range f.Branches {
+			for arm, count := range []int{b.Then, b.ElseCount()} {
+				taken := "-"
+				if b.Count > 0 {
+					taken = fmt.Sprint(count)
+				}
+				fmt.Fprintf(bw, "BRDA:%d,%d,%d,%s\n", b.Line, i, arm, taken)
+			}
+		}
+		covered, total := f.BranchesCovered()
+		fmt.Fprintf(bw, "BRF:%d\nBRH:%d\n", total, covered)
+
+		lines, hit := f.Lines(), 0
+		for _, line := range lines {
+			fmt.Fprintf(bw, "DA:%d,%d\n", line.Number, line.Count)
+			if line.Count > 0 {
+				hit++
+			}
+		}
+		fmt.Fprintf(bw, "LF:%d\nLH:%d\n", len(lines), hit)
+		fmt.Fprintln(bw, "end_of_record")
+	}
+	return bw.Flush()
+}
+
+// ----------------------------------HTML-------------------------------------
+
+// struct defining a line of source in the HTML report.
+type htmlLine struct {
+	Number int
+	Text   string
+	Class  string // covered, uncovered or empty for lines without statements
+	Count  string
+	Note   string // the arms taken by the ifs on the line
+}
+
+type htmlFile struct {
+	Path    string
+	Summary string
+	Lines   []htmlLine
+}
+
+var htmlTemplate = template.Must(template.New("coverage").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Monkey coverage</title>
+<style>
+body { font-family: sans-serif; }
+pre { line-height: 1.3; }
+.number, .count { color: #888; display: inline-block; text-align: right; }
+.number { width: 4em; }
+.count { width: 5em; margin-right: 1em; }
+.covered { background: #dfd; }
+.uncovered { background: #fdd; }
+.note { color: #a60; margin-left: 2em; }
+</style>
+</head>
+<body>
+{{range .}}<h2>{{.Path}}</h2>
+<p>{{.Summary}}</p>
+<pre>{{range .Lines}}<span class="{{.Class}}"><span class="number">{{.Number}}</span> <span class="count">{{.Count}}</span>{{.Text}}</span>{{if .Note}}<span class="note">{{.Note}}</span>{{end}}
+{{end}}</pre>
+{{end}}</body>
+</html>
+`))
+
+// method that writes an HTML page showing the source of every file with
+// the lines that ran in green, those that did not in red and how often
+// each arm of an if ran.
+func (c *Coverage) WriteHTML(w io.Writer) error {
+	files := []htmlFile{}
+	for _, f := range c.Files {
+		s, st := f.StatementsCovered()
+		b, bt := f.BranchesCovered()
+		hf := htmlFile{Path: f.Path, Summary: summary(s, st, b, bt)}
+
+		counts := map[int]int{}
+		for _, line := range f.Lines() {
+			counts[line.Number] = line.Count
+		}
+		notes := map[int][]string{}
+		for _, b := range f.Branches {
+			notes[b.Line] = append(notes[b.Line], fmt.Sprintf("if: then %d, else %d", b.Then, b.ElseCount()))
+		}
+
+		for i, text := range strings.Split(f.Source, "\n") {
+			line := htmlLine{Number: i + 1, Text: text, Note: strings.Join(notes[i+1], "; ")}
+			if count, ok := counts[i+1]; ok {
+				line.Count = fmt.Sprintf("%dx", count)
+				line.Class = "uncovered"
+				if count > 0 {
+					line.Class = "covered"
+				}
+			}
+			hf.Lines = append(hf.Lines, line)
+		}
+		files = append(files, hf)
+	}
+	return htmlTemplate.Execute(w, files)
+}
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	dap "github.com/Artypuppet/monkey/dap"
+)
+
+// function implementing `monkey dap`, which runs a debug adapter for
+// editors over stdin and stdout.
+func dapCommand(args []string) int {
+	if len(args) != 0 {
+		fmt.Fprintf(os.Stderr, "usage: monkey dap\n")
+		return 2
+	}
+	if err := dap.NewServer(os.Stdin, os.Stdout).Run(); err != nil {
+		fmt.Fprintf(os.Stderr, "monkey: %s\n", err)
+		return 1
+	}
+	return 0
+}
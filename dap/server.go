@@ -0,0 +1,530 @@
+package dap
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	ast "github.com/Artypuppet/monkey/ast"
+	debugger "github.com/Artypuppet/monkey/debugger"
+	lexer "github.com/Artypuppet/monkey/lexer"
+	object "github.com/Artypuppet/monkey/object"
+	parser "github.com/Artypuppet/monkey/parser"
+	resolver "github.com/Artypuppet/monkey/resolver"
+)
+
+// the only thread of a program, since Monkey has no concurrency.
+const threadID = 1
+
+// struct defining a debug adapter talking the Debug Adapter Protocol over a
+// pair of streams, usually stdin and stdout. It debugs one program, which
+// runs on its own goroutine while the server keeps answering requests.
+type Server struct {
+	in  *bufio.Reader
+	out io.Writer
+
+	debugger *debugger.Debugger
+	path     string
+	program  *ast.Program
+	lines    map[int]bool // the lines a statement starts on
+	// the offsets added to lines and columns sent to the client, -1 if it
+	// counts them from 0.
+	lineOffset, columnOffset int
+	launched, configured     bool
+	refs                     []interface{} // the values of variable references minus 1
+	after                    func()        // run once the current response is sent
+
+	mu          sync.Mutex // guards the fields below and the writes to out
+	seq         int
+	started     bool
+	stopped     bool // whether the program waits on resume
+	terminating bool
+	resume      chan debugger.Action
+	done        chan struct{} // closed when the program ended
+}
+
+// constructor for a server reading requests from in and writing to out.
+func NewServer(in io.Reader, out io.Writer) *Server {
+	s := &Server{
+		in:     bufio.NewReader(in),
+		out:    out,
+		resume: make(chan debugger.Action),
+		done:   make(chan struct{}),
+	}
+	s.debugger = debugger.New(s.stop)
+	// the streams of the server carry the protocol, so what the program
+	// prints is sent as output events and it has nothing to read.
+	s.debugger.Stdout = outputWriter{s}
+	s.debugger.Stdin = strings.NewReader("")
+	return s
+}
+
+// type def for the writer sending what the program prints to the client.
+type outputWriter struct {
+	s *Server
+}
+
+func (w outputWriter) Write(p []byte) (int, error) {
+	w.s.send("output", &OutputEventBody{Category: "stdout", Output: string(p)})
+	return len(p), nil
+}
+
+// map from the command of a request to the method handling it.
+var handlers = map[string]func(s *Server, args json.RawMessage) (interface{}, error){
+	"initialize":        (*Server).initialize,
+	"launch":            (*Server).launch,
+	"setBreakpoints":    (*Server).setBreakpoints,
+	"configurationDone": (*Server).configurationDone,
+	"threads":           (*Server).threads,
+	"stackTrace":        (*Server).stackTrace,
+	"scopes":            (*Server).scopes,
+	"variables":         (*Server).variables,
+	"evaluate":          (*Server).evaluate,
+	"continue":          (*Server).continueRequest,
+	"next":              (*Server).next,
+	"stepIn":            (*Server).stepIn,
+	"stepOut":           (*Server).stepOut,
+	"pause":             (*Server).pause,
+}
+
+// error returned by requests that need the program to be stopped.
+var errNotStopped = errors.New("the program is not stopped")
+
+// method that serves requests until the client sends disconnect or closes
+// the input. The program is stopped before Run returns. It only fails if
+// the streams do.
+func (s *Server) Run() error {
+	for {
+		data, err := readMessage(s.in)
+		if err == io.EOF {
+			s.terminate()
+			return nil
+		}
+		if err != nil {
+			s.terminate()
+			return err
+		}
+
+		var req request
+		if err := json.Unmarshal(data, &req); err != nil {
+			continue
+		}
+		if req.Command == "disconnect" {
+			s.terminate()
+			return s.respond(&req, nil, nil)
+		}
+
+		var body interface{}
+		handler, ok := handlers[req.Command]
+		if ok {
+			body, err = handler(s, req.Arguments)
+		} else {
+			err = fmt.Errorf("unknown command %q", req.Command)
+		}
+		if err := s.respond(&req, body, err); err != nil {
+			return err
+		}
+		if s.after != nil {
+			s.after()
+			s.after = nil
+		}
+	}
+}
+
+// method that sends the response to req, a failure if err is not nil.
+func (s *Server) respond(req *request, body interface{}, err error) error {
+	resp := &response{Type: "response", RequestSeq: req.Seq, Success: err == nil, Command: req.Command, Body: body}
+	if err != nil {
+		resp.Message = err.Error()
+		resp.Body = nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.seq++
+	resp.Seq = s.seq
+	return writeMessage(s.out, resp)
+}
+
+// method that sends an event. It is called by the goroutine running the
+// program too, so errors are dropped; Run notices a broken stream anyway.
+func (s *Server) send(name string, body interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.seq++
+	writeMessage(s.out, &event{Seq: s.seq, Type: "event", Event: name, Body: body})
+}
+
+// helper function that decodes the arguments of a request into v.
+func decodeArguments(args json.RawMessage, v interface{}) error {
+	if args == nil {
+		return nil
+	}
+	return json.Unmarshal(args, v)
+}
+
+// --------------------------------Lifecycle----------------------------------
+
+func (s *Server) initialize(args json.RawMessage) (interface{}, error) {
+	var a InitializeRequestArguments
+	if err := decodeArguments(args, &a); err != nil {
+		return nil, err
+	}
+	if a.LinesStartAt1 != nil && !*a.LinesStartAt1 {
+		s.lineOffset = -1
+	}
+	if a.ColumnsStartAt1 != nil && !*a.ColumnsStartAt1 {
+		s.columnOffset = -1
+	}
+	s.after = func() { s.send("initialized", nil) }
+	return &Capabilities{SupportsConfigurationDoneRequest: true, SupportsEvaluateForHovers: true}, nil
+}
+
+// method that loads the program to debug. Like `monkey run` it is resolved
+// first and refused if that finds errors. It starts once the client is done
+// setting breakpoints.
+func (s *Server) launch(args json.RawMessage) (interface{}, error) {
+	var a LaunchRequestArguments
+	if err := decodeArguments(args, &a); err != nil {
+		return nil, err
+	}
+	if s.launched {
+		return nil, errors.New("a program was launched already")
+	}
+	src, err := os.ReadFile(a.Program)
+	if err != nil {
+		return nil, err
+	}
+	p := parser.New(lexer.New(string(src)))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return nil, fmt.Errorf("%s: %s", a.Program, strings.Join(p.Errors(), "; "))
+	}
+	for _, d := range resolver.Resolve(program, nil) {
+		if d.Severity == resolver.Error {
+			return nil, fmt.Errorf("%s:%s", a.Program, d)
+		}
+	}
+
+	s.path, s.program, s.launched = a.Program, program, true
+	s.lines = make(map[int]bool)
+	ast.Inspect(program, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.LetStatement:
+			s.lines[node.Token.Line] = true
+		case *ast.ReturnStatement:
+			s.lines[node.Token.Line] = true
+		case *ast.ExpressionStatement:
+			s.lines[node.Token.Line] = true
+		}
+		return true
+	})
+	s.debugger.StopOnEntry = a.StopOnEntry
+	s.after = s.start
+	return nil, nil
+}
+
+func (s *Server) configurationDone(args json.RawMessage) (interface{}, error) {
+	s.configured = true
+	s.after = s.start
+	return nil, nil
+}
+
+// method that starts the program once it is launched and configured.
+func (s *Server) start() {
+	if !s.launched || !s.configured {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.started {
+		return
+	}
+	s.started = true
+	go s.runProgram()
+}
+
+// method that runs the program and reports how it ended.
+func (s *Server) runProgram() {
+	defer close(s.done)
+	exitCode := 0
+	result := s.debugger.Run(s.program, object.NewEnvironment())
+	if err, ok := result.(*object.Error); ok {
+		exitCode = 1
+		s.mu.Lock()
+		terminating := s.terminating
+		s.mu.Unlock()
+		if !terminating {
+			s.send("output", &OutputEventBody{Category: "stderr", Output: fmt.Sprintf("%s: %s\n", s.path, err.Inspect())})
+		}
+	}
+	s.send("exited", &ExitedEventBody{ExitCode: exitCode})
+	s.send("terminated", nil)
+}
+
+// method that is the OnStop of the debugger. It runs on the goroutine of
+// the program, which waits until a request resumes it.
+func (s *Server) stop(d *debugger.Debugger, reason debugger.Reason) debugger.Action {
+	s.mu.Lock()
+	if s.terminating {
+		s.mu.Unlock()
+		return debugger.Quit
+	}
+	s.stopped = true
+	s.mu.Unlock()
+
+	s.send("stopped", &StoppedEventBody{Reason: string(reason), ThreadID: threadID, AllThreadsStopped: true})
+	return <-s.resume
+}
+
+// method that stops the program, if it runs, and waits until it did.
+func (s *Server) terminate() {
+	s.mu.Lock()
+	s.terminating = true
+	started, stopped := s.started, s.stopped
+	s.stopped = false
+	s.mu.Unlock()
+	if !started {
+		return
+	}
+	if stopped {
+		s.resume <- debugger.Quit
+	} else {
+		s.debugger.Pause()
+	}
+	<-s.done
+}
+
+// --------------------------------Breakpoints--------------------------------
+
+// method that replaces the breakpoints. Only lines a statement starts on
+// can be stopped at, the others are reported as not verified.
+func (s *Server) setBreakpoints(args json.RawMessage) (interface{}, error) {
+	var a SetBreakpointsArguments
+	if err := decodeArguments(args, &a); err != nil {
+		return nil, err
+	}
+	lines := a.Lines
+	if a.Breakpoints != nil {
+		lines = []int{}
+		for _, bp := range a.Breakpoints {
+			lines = append(lines, bp.Line)
+		}
+	}
+
+	breakpoints := []Breakpoint{}
+	set := []int{}
+	for _, line := range lines {
+		line -= s.lineOffset
+		bp := Breakpoint{Verified: true, Source: &a.Source, Line: line + s.lineOffset}
+		switch {
+		case s.launched && a.Source.Path != s.path:
+			bp.Verified, bp.Message = false, "not the program being debugged"
+		case s.launched && !s.lines[line]:
+			bp.Verified, bp.Message = false, "no statement on this line"
+		default:
+			set = append(set, line)
+		}
+		breakpoints = append(breakpoints, bp)
+	}
+	s.debugger.SetBreakpoints(set)
+	return &SetBreakpointsResponseBody{Breakpoints: breakpoints}, nil
+}
+
+// ---------------------------------Execution---------------------------------
+
+// helper method that resumes the stopped program with action once the
+// response is sent.
+func (s *Server) resumeWith(action debugger.Action) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.stopped {
+		return errNotStopped
+	}
+	s.stopped = false
+	s.refs = nil
+	s.after = func() { s.resume <- action }
+	return nil
+}
+
+func (s *Server) continueRequest(args json.RawMessage) (interface{}, error) {
+	if err := s.resumeWith(debugger.Continue); err != nil {
+		return nil, err
+	}
+	return &ContinueResponseBody{AllThreadsContinued: true}, nil
+}
+
+func (s *Server) next(args json.RawMessage) (interface{}, error) {
+	return nil, s.resumeWith(debugger.StepOver)
+}
+
+func (s *Server) stepIn(args json.RawMessage) (interface{}, error) {
+	return nil, s.resumeWith(debugger.StepIn)
+}
+
+func (s *Server) stepOut(args json.RawMessage) (interface{}, error) {
+	return nil, s.resumeWith(debugger.StepOut)
+}
+
+func (s *Server) pause(args json.RawMessage) (interface{}, error) {
+	s.mu.Lock()
+	running := s.started && !s.stopped
+	s.mu.Unlock()
+	if running {
+		s.debugger.Pause()
+	}
+	return nil, nil
+}
+
+// --------------------------------Inspection---------------------------------
+
+// helper method that returns the call stack if the program is stopped.
+func (s *Server) stack() ([]debugger.Frame, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.stopped {
+		return nil, errNotStopped
+	}
+	return s.debugger.Stack(), nil
+}
+
+func (s *Server) threads(args json.RawMessage) (interface{}, error) {
+	return &ThreadsResponseBody{Threads: []Thread{{ID: threadID, Name: "main"}}}, nil
+}
+
+func (s *Server) stackTrace(args json.RawMessage) (interface{}, error) {
+	var a StackTraceArguments
+	if err := decodeArguments(args, &a); err != nil {
+		return nil, err
+	}
+	stack, err := s.stack()
+	if err != nil {
+		return nil, err
+	}
+	source := &Source{Name: filepath.Base(s.path), Path: s.path}
+	frames := []StackFrame{}
+	for i, frame := range stack {
+		if i < a.StartFrame || a.Levels > 0 && i >= a.StartFrame+a.Levels {
+			continue
+		}
+		frames = append(frames, StackFrame{
+			ID:     i,
+			Name:   frame.Name,
+			Source: source,
+			Line:   frame.Line + s.lineOffset,
+			Column: frame.Column + s.columnOffset,
+		})
+	}
+	return &StackTraceResponseBody{StackFrames: frames, TotalFrames: len(stack)}, nil
+}
+
+// method that returns the scopes of a frame by walking its environment out
+// to the globals.
+func (s *Server) scopes(args json.RawMessage) (interface{}, error) {
+	var a ScopesArguments
+	if err := decodeArguments(args, &a); err != nil {
+		return nil, err
+	}
+	stack, err := s.stack()
+	if err != nil {
+		return nil, err
+	}
+	if a.FrameID < 0 || a.FrameID >= len(stack) {
+		return nil, fmt.Errorf("no frame %d", a.FrameID)
+	}
+
+	scopes := []Scope{}
+	for env := stack[a.FrameID].Env; env != nil; env = env.Outer() {
+		name := "Closure"
+		switch {
+		case env.Outer() == nil:
+			name = "Globals"
+		case len(scopes) == 0:
+			name = "Locals"
+		}
+		scopes = append(scopes, Scope{Name: name, VariablesReference: s.reference(env)})
+	}
+	return &ScopesResponseBody{Scopes: scopes}, nil
+}
+
+// method that returns the variables of a scope, or the elements of an array
+// or a hash.
+func (s *Server) variables(args json.RawMessage) (interface{}, error) {
+	var a VariablesArguments
+	if err := decodeArguments(args, &a); err != nil {
+		return nil, err
+	}
+	if _, err := s.stack(); err != nil {
+		return nil, err
+	}
+	if a.VariablesReference < 1 || a.VariablesReference > len(s.refs) {
+		return nil, fmt.Errorf("unknown variables reference %d", a.VariablesReference)
+	}
+
+	variables := []Variable{}
+	switch value := s.refs[a.VariablesReference-1].(type) {
+	case *object.Environment:
+		for _, name := range value.Names() {
+			obj, _ := value.Get(name)
+			variables = append(variables, s.variable(name, obj))
+		}
+	case *object.Array:
+		for i, element := range value.Elements {
+			variables = append(variables, s.variable(fmt.Sprintf("[%d]", i), element))
+		}
+	case *object.Hash:
+		for _, pair := range value.SortedPairs() {
+			variables = append(variables, s.variable(pair.Key.Inspect(), pair.Value))
+		}
+	}
+	return &VariablesResponseBody{Variables: variables}, nil
+}
+
+func (s *Server) evaluate(args json.RawMessage) (interface{}, error) {
+	var a EvaluateArguments
+	if err := decodeArguments(args, &a); err != nil {
+		return nil, err
+	}
+	if _, err := s.stack(); err != nil {
+		return nil, err
+	}
+	result := s.debugger.Evaluate(a.Expression, a.FrameID)
+	if err, ok := result.(*object.Error); ok {
+		return nil, errors.New(err.Message)
+	}
+	v := s.variable("", result)
+	return &EvaluateResponseBody{Result: v.Value, Type: v.Type, VariablesReference: v.VariablesReference}, nil
+}
+
+// helper method that describes a value. Non empty arrays and hashes get a
+// reference to their elements. Statements and bindings to them have no
+// value, which is shown as null.
+func (s *Server) variable(name string, value object.Object) Variable {
+	if value == nil {
+		return Variable{Name: name, Value: "null", Type: object.NULL_OBJ}
+	}
+	v := Variable{Name: name, Value: value.Inspect(), Type: string(value.Type())}
+	switch value := value.(type) {
+	case *object.Array:
+		if len(value.Elements) != 0 {
+			v.VariablesReference = s.reference(value)
+		}
+	case *object.Hash:
+		if len(value.Pairs) != 0 {
+			v.VariablesReference = s.reference(value)
+		}
+	}
+	return v
+}
+
+// helper method that returns a reference to value, valid until the program
+// is resumed.
+func (s *Server) reference(value interface{}) int {
+	s.refs = append(s.refs, value)
+	return len(s.refs)
+}
@@ -0,0 +1,396 @@
+package dap
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testProgram = `let add = fn(a, b) {
+  let sum = a + b;
+  sum
+};
+let list = [1, {"k": true}];
+let x = add(1, 2);
+let y = add(x, 3);
+y`
+
+// struct defining a client talking to a server running on another goroutine.
+// The events read while waiting for a response are kept for event.
+type testClient struct {
+	t      *testing.T
+	w      *io.PipeWriter
+	r      *bufio.Reader
+	seq    int
+	events []testMessage
+	done   chan error
+}
+
+// struct defining any message sent by the server.
+type testMessage struct {
+	Type       string          `json:"type"`
+	RequestSeq int             `json:"request_seq"`
+	Success    bool            `json:"success"`
+	Message    string          `json:"message"`
+	Event      string          `json:"event"`
+	Body       json.RawMessage `json:"body"`
+}
+
+func newTestClient(t *testing.T) *testClient {
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	c := &testClient{t: t, w: inW, r: bufio.NewReader(outR), done: make(chan error, 1)}
+	go func() {
+		err := NewServer(inR, outW).Run()
+		outW.Close()
+		c.done <- err
+	}()
+	return c
+}
+
+func (c *testClient) read() testMessage {
+	c.t.Helper()
+	data, err := readMessage(c.r)
+	if err != nil {
+		c.t.Fatalf("reading a message failed: %s", err)
+	}
+	var msg testMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		c.t.Fatalf("invalid message %s: %s", data, err)
+	}
+	return msg
+}
+
+// method that sends a request and returns its response. The request is
+// written on another goroutine since the server may be writing an event
+// that has to be read first.
+func (c *testClient) request(command string, args interface{}) testMessage {
+	c.t.Helper()
+	c.seq++
+	msg := map[string]interface{}{"seq": c.seq, "type": "request", "command": command, "arguments": args}
+	go writeMessage(c.w, msg)
+	for {
+		msg := c.read()
+		if msg.Type == "response" && msg.RequestSeq == c.seq {
+			return msg
+		}
+		c.events = append(c.events, msg)
+	}
+}
+
+// method that sends a request that has to succeed and decodes its body into v.
+func (c *testClient) call(command string, args interface{}, v interface{}) {
+	c.t.Helper()
+	msg := c.request(command, args)
+	if !msg.Success {
+		c.t.Fatalf("%s failed: %s", command, msg.Message)
+	}
+	if v != nil {
+		if err := json.Unmarshal(msg.Body, v); err != nil {
+			c.t.Fatalf("invalid body %s: %s", msg.Body, err)
+		}
+	}
+}
+
+// method that waits for the next event with the given name and decodes its
+// body into v. Other events before it are dropped.
+func (c *testClient) event(name string, v interface{}) {
+	c.t.Helper()
+	for {
+		var msg testMessage
+		if len(c.events) > 0 {
+			msg, c.events = c.events[0], c.events[1:]
+		} else {
+			msg = c.read()
+		}
+		if msg.Type != "event" || msg.Event != name {
+			continue
+		}
+		if v != nil {
+			if err := json.Unmarshal(msg.Body, v); err != nil {
+				c.t.Fatalf("invalid body %s: %s", msg.Body, err)
+			}
+		}
+		return
+	}
+}
+
+// method that disconnects and checks that the server ends cleanly.
+func (c *testClient) disconnect() {
+	c.t.Helper()
+	c.call("disconnect", nil, nil)
+	c.w.Close()
+	if err := <-c.done; err != nil {
+		c.t.Fatalf("server failed: %s", err)
+	}
+}
+
+// helper function that writes the source of a program to a temporary file.
+func writeProgram(t *testing.T, src string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test.mk")
+	if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// helper function that starts a session on the program at path.
+func launch(t *testing.T, path string, stopOnEntry bool, lines ...int) *testClient {
+	t.Helper()
+	c := newTestClient(t)
+	var capabilities Capabilities
+	c.call("initialize", map[string]interface{}{"clientID": "test"}, &capabilities)
+	if !capabilities.SupportsConfigurationDoneRequest {
+		t.Errorf("configurationDone is not supported")
+	}
+	c.event("initialized", nil)
+	c.call("launch", map[string]interface{}{"program": path, "stopOnEntry": stopOnEntry}, nil)
+
+	breakpoints := []map[string]int{}
+	for _, line := range lines {
+		breakpoints = append(breakpoints, map[string]int{"line": line})
+	}
+	var body SetBreakpointsResponseBody
+	c.call("setBreakpoints", map[string]interface{}{"source": map[string]string{"path": path}, "breakpoints": breakpoints}, &body)
+	for i, bp := range body.Breakpoints {
+		if bp.Line != lines[i] || !bp.Verified {
+			t.Errorf("breakpoint %d not verified: %+v", lines[i], bp)
+		}
+	}
+	c.call("configurationDone", nil, nil)
+	return c
+}
+
+// helper method that waits for the program to stop and returns why, with
+// the names and lines of the stack frames.
+func (c *testClient) stopped() (string, []string, []int) {
+	c.t.Helper()
+	var stopped StoppedEventBody
+	c.event("stopped", &stopped)
+	var trace StackTraceResponseBody
+	c.call("stackTrace", map[string]int{"threadId": threadID}, &trace)
+	names, lines := []string{}, []int{}
+	for _, frame := range trace.StackFrames {
+		names = append(names, frame.Name)
+		lines = append(lines, frame.Line)
+	}
+	return stopped.Reason, names, lines
+}
+
+func TestSession(t *testing.T) {
+	path := writeProgram(t, testProgram)
+	c := launch(t, path, false, 3)
+
+	reason, names, lines := c.stopped()
+	if reason != "breakpoint" || !reflect.DeepEqual(names, []string{"add", "<program>"}) || !reflect.DeepEqual(lines, []int{3, 6}) {
+		t.Fatalf("wrong stop: %s %v %v", reason, names, lines)
+	}
+
+	var threads ThreadsResponseBody
+	c.call("threads", nil, &threads)
+	if !reflect.DeepEqual(threads.Threads, []Thread{{ID: threadID, Name: "main"}}) {
+		t.Errorf("wrong threads: %+v", threads.Threads)
+	}
+
+	var scopes ScopesResponseBody
+	c.call("scopes", map[string]int{"frameId": 0}, &scopes)
+	if len(scopes.Scopes) != 2 || scopes.Scopes[0].Name != "Locals" || scopes.Scopes[1].Name != "Globals" {
+		t.Fatalf("wrong scopes: %+v", scopes.Scopes)
+	}
+
+	variables := func(ref int) []Variable {
+		t.Helper()
+		var body VariablesResponseBody
+		c.call("variables", map[string]int{"variablesReference": ref}, &body)
+		for i := range body.Variables {
+			if body.Variables[i].Type == "FUNCTION" {
+				body.Variables[i].Value = "..."
+			}
+		}
+		return body.Variables
+	}
+
+	locals := variables(scopes.Scopes[0].VariablesReference)
+	expected := []Variable{
+		{Name: "a", Value: "1", Type: "INTEGER"},
+		{Name: "b", Value: "2", Type: "INTEGER"},
+		{Name: "sum", Value: "3", Type: "INTEGER"},
+	}
+	if !reflect.DeepEqual(locals, expected) {
+		t.Errorf("wrong locals.\nexpected=%+v\ngot=%+v", expected, locals)
+	}
+
+	globals := variables(scopes.Scopes[1].VariablesReference)
+	if len(globals) != 2 || globals[0].Name != "add" || globals[1].Name != "list" || globals[1].VariablesReference == 0 {
+		t.Fatalf("wrong globals: %+v", globals)
+	}
+	elements := variables(globals[1].VariablesReference)
+	if len(elements) != 2 || elements[0] != (Variable{Name: "[0]", Value: "1", Type: "INTEGER"}) ||
+		elements[1].Name != "[1]" || elements[1].Type != "HASH" || elements[1].VariablesReference == 0 {
+		t.Fatalf("wrong elements: %+v", elements)
+	}
+	pairs := variables(elements[1].VariablesReference)
+	if !reflect.DeepEqual(pairs, []Variable{{Name: "k", Value: "true", Type: "BOOLEAN"}}) {
+		t.Errorf("wrong pairs: %+v", pairs)
+	}
+
+	var evaluated EvaluateResponseBody
+	c.call("evaluate", map[string]interface{}{"expression": "sum * 2", "frameId": 0}, &evaluated)
+	if evaluated.Result != "6" || evaluated.Type != "INTEGER" {
+		t.Errorf("wrong evaluation: %+v", evaluated)
+	}
+	if msg := c.request("evaluate", map[string]interface{}{"expression": "nope"}); msg.Success || msg.Message != "identifier not found: nope" {
+		t.Errorf("evaluating an undefined name did not fail: %+v", msg)
+	}
+
+	c.call("setBreakpoints", map[string]interface{}{"source": map[string]string{"path": path}, "breakpoints": []int{}}, nil)
+	steps := []struct {
+		command string
+		names   []string
+		lines   []int
+	}{
+		{"next", []string{"<program>"}, []int{7}},
+		{"stepIn", []string{"add", "<program>"}, []int{2, 7}},
+		{"stepOut", []string{"<program>"}, []int{8}},
+	}
+	for _, step := range steps {
+		c.call(step.command, map[string]int{"threadId": threadID}, nil)
+		reason, names, lines := c.stopped()
+		if reason != "step" || !reflect.DeepEqual(names, step.names) || !reflect.DeepEqual(lines, step.lines) {
+			t.Errorf("%s: wrong stop: %s %v %v", step.command, reason, names, lines)
+		}
+	}
+
+	var continued ContinueResponseBody
+	c.call("continue", map[string]int{"threadId": threadID}, &continued)
+	var exited ExitedEventBody
+	c.event("exited", &exited)
+	if exited.ExitCode != 0 {
+		t.Errorf("wrong exit code. expected=0, got=%d", exited.ExitCode)
+	}
+	c.event("terminated", nil)
+	if msg := c.request("stackTrace", map[string]int{"threadId": threadID}); msg.Success {
+		t.Errorf("stackTrace succeeded after the program ended")
+	}
+	c.disconnect()
+}
+
+func TestNilValues(t *testing.T) {
+	c := launch(t, writeProgram(t, "let r = fn() {}();\nlet x = 1;\nx"), false, 3)
+	c.stopped()
+
+	var scopes ScopesResponseBody
+	c.call("scopes", map[string]int{"frameId": 0}, &scopes)
+	var body VariablesResponseBody
+	c.call("variables", map[string]int{"variablesReference": scopes.Scopes[0].VariablesReference}, &body)
+	expected := []Variable{
+		{Name: "r", Value: "null", Type: "NULL"},
+		{Name: "x", Value: "1", Type: "INTEGER"},
+	}
+	if !reflect.DeepEqual(body.Variables, expected) {
+		t.Errorf("wrong variables.\nexpected=%+v\ngot=%+v", expected, body.Variables)
+	}
+
+	var evaluated EvaluateResponseBody
+	c.call("evaluate", map[string]interface{}{"expression": "let z = 3", "frameId": 0}, &evaluated)
+	if evaluated.Result != "null" || evaluated.Type != "NULL" {
+		t.Errorf("wrong evaluation: %+v", evaluated)
+	}
+
+	c.call("continue", map[string]int{"threadId": threadID}, nil)
+	c.event("terminated", nil)
+	c.disconnect()
+}
+
+func TestSetBreakpointsVerification(t *testing.T) {
+	path := writeProgram(t, testProgram)
+	c := newTestClient(t)
+	c.call("initialize", nil, nil)
+	c.call("launch", map[string]interface{}{"program": path}, nil)
+
+	var body SetBreakpointsResponseBody
+	c.call("setBreakpoints", map[string]interface{}{"source": map[string]string{"path": path}, "lines": []int{2, 4}}, &body)
+	if len(body.Breakpoints) != 2 || !body.Breakpoints[0].Verified || body.Breakpoints[1].Verified {
+		t.Errorf("wrong verification: %+v", body.Breakpoints)
+	}
+	c.call("setBreakpoints", map[string]interface{}{"source": map[string]string{"path": "other.mk"}, "lines": []int{2}}, &body)
+	if body.Breakpoints[0].Verified || body.Breakpoints[0].Message != "not the program being debugged" {
+		t.Errorf("breakpoint in another file verified: %+v", body.Breakpoints)
+	}
+	c.disconnect()
+}
+
+func TestLaunchErrors(t *testing.T) {
+	tests := []struct {
+		src      string
+		expected string
+	}{
+		{"let x = ;", "no prefix parse function for ; found"},
+		{"y;", "undefined: y"},
+	}
+
+	for _, tt := range tests {
+		path := writeProgram(t, tt.src)
+		c := newTestClient(t)
+		msg := c.request("launch", map[string]interface{}{"program": path})
+		if msg.Success {
+			t.Errorf("launching %q succeeded", tt.src)
+		} else if !strings.Contains(msg.Message, tt.expected) {
+			t.Errorf("wrong message for %q: %s", tt.src, msg.Message)
+		}
+		c.disconnect()
+	}
+
+	c := newTestClient(t)
+	if msg := c.request("launch", map[string]interface{}{"program": "does-not-exist.mk"}); msg.Success {
+		t.Errorf("launching a missing file succeeded")
+	}
+	if msg := c.request("frobnicate", nil); msg.Success || msg.Message != `unknown command "frobnicate"` {
+		t.Errorf("unknown command did not fail: %+v", msg)
+	}
+	c.disconnect()
+}
+
+func TestDisconnect(t *testing.T) {
+	path := writeProgram(t, testProgram)
+
+	// disconnecting while stopped quits the program.
+	c := launch(t, path, true)
+	if reason, _, lines := c.stopped(); reason != "entry" || !reflect.DeepEqual(lines, []int{1}) {
+		t.Fatalf("wrong stop on entry: %s %v", reason, lines)
+	}
+	c.disconnect()
+
+	// and so does disconnecting while it runs.
+	path = writeProgram(t, "let loop = fn(n) { loop(n + 1) };\nloop(0);")
+	c = launch(t, path, false)
+	c.call("pause", map[string]int{"threadId": threadID}, nil)
+	if reason, _, _ := c.stopped(); reason != "pause" {
+		t.Errorf("wrong stop on pause: %s", reason)
+	}
+	c.call("continue", map[string]int{"threadId": threadID}, nil)
+	c.disconnect()
+}
+
+func TestProgramOutput(t *testing.T) {
+	path := writeProgram(t, "println(\"sum\", 1 + 2);\nlet line = readline();\nputs(line);")
+	c := launch(t, path, false)
+	var output OutputEventBody
+	c.event("output", &output)
+	if output.Category != "stdout" || output.Output != "sum 3\n" {
+		t.Errorf("wrong output event: %+v", output)
+	}
+	// the program has nothing to read.
+	c.event("output", &output)
+	if output.Category != "stdout" || output.Output != "null\n" {
+		t.Errorf("wrong output event: %+v", output)
+	}
+	c.event("terminated", nil)
+	c.disconnect()
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	debugger "github.com/Artypuppet/monkey/debugger"
+	object "github.com/Artypuppet/monkey/object"
+	resolver "github.com/Artypuppet/monkey/resolver"
+)
+
+// function implementing `monkey debug file`, which runs a program under a
+// debugger reading its commands from stdin. The program is not optimized
+// so that every statement can still be stopped at.
+func debugCommand(args []string) int {
+	if len(args) != 1 {
+		fmt.Fprintf(os.Stderr, "usage: monkey debug file\n")
+		return 2
+	}
+	path := args[0]
+
+	program := parseFile(path)
+	if program == nil {
+		return 1
+	}
+	src, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "monkey: %s\n", err)
+		return 1
+	}
+
+	diagnostics := resolver.Resolve(program, nil)
+	for _, d := range diagnostics {
+		fmt.Fprintf(os.Stderr, "%s:%s\n", path, d)
+	}
+	if resolver.HasErrors(diagnostics) {
+		return 1
+	}
+
+	console := debugger.NewConsole(path, string(src), os.Stdin, os.Stdout)
+	if result, ok := console.Run(program, object.NewEnvironment()).(*object.Error); ok {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", path, result.Inspect())
+		return 1
+	}
+	return 0
+}
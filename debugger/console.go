@@ -0,0 +1,190 @@
+package debugger
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	ast "github.com/Artypuppet/monkey/ast"
+	object "github.com/Artypuppet/monkey/object"
+)
+
+const PROMPT = "(mdb) "
+
+const consoleHelp = `commands:
+  break LINE, b LINE     stop at LINE
+  delete LINE, d LINE    remove the breakpoint at LINE
+  breakpoints            list the breakpoints
+  continue, c            run until the next breakpoint
+  step, s                stop at the next statement, inside calls too
+  next, n                stop at the next statement, stepping over calls
+  finish, out            stop after the current call returned
+  stack, bt              print the call stack
+  locals [FRAME]         print the variables seen from a frame
+  print EXPR, p EXPR     evaluate EXPR in the current call
+  list, l                print the source around the current line
+  quit, q                stop the program
+  help, h                print this help
+`
+
+// struct defining a terminal frontend of a debugger. It reads commands
+// from in every time the program stops and writes to out.
+type Console struct {
+	Debugger *Debugger
+	path     string
+	lines    []string
+	in       *bufio.Reader
+	out      io.Writer
+}
+
+// constructor for a console debugging the file at path, whose text is source.
+// The program stops before its first statement so breakpoints can be set.
+func NewConsole(path, source string, in io.Reader, out io.Writer) *Console {
+	c := &Console{
+		path:  path,
+		lines: strings.Split(source, "\n"),
+		in:    bufio.NewReader(in),
+		out:   out,
+	}
+	c.Debugger = New(c.stop)
+	c.Debugger.StopOnEntry = true
+	// the program reads the lines after the command that resumed it.
+	c.Debugger.Stdout, c.Debugger.Stdin = out, c.in
+	return c
+}
+
+// method that runs program in env under the debugger and returns its value.
+func (c *Console) Run(program *ast.Program, env *object.Environment) object.Object {
+	return c.Debugger.Run(program, env)
+}
+
+// method called when the program stops. It prints where and reads commands
+// until one of them resumes the program. The end of the input quits it.
+func (c *Console) stop(d *Debugger, reason Reason) Action {
+	frame := d.Stack()[0]
+	fmt.Fprintf(c.out, "stopped at %s:%d (%s)\n", c.path, frame.Line, reason)
+	c.printLine(frame.Line, true)
+
+	for {
+		fmt.Fprint(c.out, PROMPT)
+		line, err := c.in.ReadString('\n')
+		if err != nil && line == "" {
+			fmt.Fprintln(c.out)
+			return Quit
+		}
+		command, arg := splitCommand(strings.TrimRight(line, "\r\n"))
+		switch command {
+		case "":
+		case "break", "b":
+			if line, ok := c.lineArgument(arg); ok {
+				d.SetBreakpoint(line)
+				fmt.Fprintf(c.out, "breakpoint at %s:%d\n", c.path, line)
+			}
+		case "delete", "d":
+			if line, ok := c.lineArgument(arg); ok && !d.ClearBreakpoint(line) {
+				fmt.Fprintf(c.out, "no breakpoint at line %d\n", line)
+			}
+		case "breakpoints":
+			for _, line := range d.Breakpoints() {
+				fmt.Fprintf(c.out, "%s:%d\n", c.path, line)
+			}
+		case "continue", "c":
+			return Continue
+		case "step", "s":
+			return StepIn
+		case "next", "n":
+			return StepOver
+		case "finish", "out":
+			return StepOut
+		case "quit", "q":
+			return Quit
+		case "stack", "bt":
+			for i, frame := range d.Stack() {
+				fmt.Fprintf(c.out, "#%d %s at %s:%d\n", i, frame.Name, c.path, frame.Line)
+			}
+		case "locals":
+			c.printLocals(d, arg)
+		case "print", "p":
+			if arg == "" {
+				fmt.Fprintln(c.out, "print needs an expression")
+				continue
+			}
+			fmt.Fprintln(c.out, inspect(d.Evaluate(arg, 0)))
+		case "list", "l":
+			for line := frame.Line - 2; line <= frame.Line+2; line++ {
+				c.printLine(line, line == frame.Line)
+			}
+		case "help", "h":
+			fmt.Fprint(c.out, consoleHelp)
+		default:
+			fmt.Fprintf(c.out, "unknown command %q, try help\n", command)
+		}
+	}
+}
+
+// method that prints line of the source, marked if it is the current one.
+func (c *Console) printLine(line int, current bool) {
+	if line < 1 || line > len(c.lines) {
+		return
+	}
+	marker := " "
+	if current {
+		marker = ">"
+	}
+	fmt.Fprintf(c.out, "%s %4d  %s\n", marker, line, c.lines[line-1])
+}
+
+// method that prints the names visible from a frame, one scope at a time
+// from the innermost one out to the globals.
+func (c *Console) printLocals(d *Debugger, arg string) {
+	index := 0
+	if arg != "" {
+		n, err := strconv.Atoi(arg)
+		if err != nil || n < 0 || n >= len(d.Stack()) {
+			fmt.Fprintf(c.out, "no frame %s\n", arg)
+			return
+		}
+		index = n
+	}
+	depth := 0
+	for env := d.Stack()[index].Env; env != nil; env = env.Outer() {
+		if env.Outer() == nil {
+			fmt.Fprintln(c.out, "globals:")
+		} else {
+			fmt.Fprintf(c.out, "scope %d:\n", depth)
+		}
+		for _, name := range env.Names() {
+			value, _ := env.Get(name)
+			fmt.Fprintf(c.out, "  %s = %s\n", name, inspect(value))
+		}
+		depth++
+	}
+}
+
+// function that returns how value is printed. Statements, like a let, and
+// bindings to them have no value, which is printed as null.
+func inspect(value object.Object) string {
+	if value == nil {
+		return "null"
+	}
+	return value.Inspect()
+}
+
+// method that parses the line number argument of a command.
+func (c *Console) lineArgument(arg string) (int, bool) {
+	line, err := strconv.Atoi(arg)
+	if err != nil || line < 1 {
+		fmt.Fprintf(c.out, "invalid line %q\n", arg)
+		return 0, false
+	}
+	return line, true
+}
+
+// helper function that splits a command line into the command and the rest.
+func splitCommand(text string) (string, string) {
+	text = strings.TrimSpace(text)
+	command, arg, _ := strings.Cut(text, " ")
+	return command, strings.TrimSpace(arg)
+}
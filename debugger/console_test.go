@@ -0,0 +1,84 @@
+package debugger
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	object "github.com/Artypuppet/monkey/object"
+)
+
+func TestConsole(t *testing.T) {
+	commands := []string{
+		"break 3",
+		"breakpoints",
+		"continue",
+		"bt",
+		"locals",
+		"p sum * 10",
+		"next",
+		"delete 3",
+		"delete 3",
+		"frobnicate",
+		"c",
+	}
+	var out bytes.Buffer
+	c := NewConsole("add.mk", testProgram, strings.NewReader(strings.Join(commands, "\n")), &out)
+	result := c.Run(parse(t, testProgram), object.NewEnvironment())
+	if result.Inspect() != "6" {
+		t.Errorf("wrong result. expected=6, got=%s", result.Inspect())
+	}
+
+	expected := []string{
+		"stopped at add.mk:1 (entry)",
+		">    1  let add = fn(a, b) {",
+		"(mdb) breakpoint at add.mk:3",
+		"(mdb) add.mk:3",
+		"(mdb) stopped at add.mk:3 (breakpoint)",
+		">    3    sum",
+		"(mdb) #0 add at add.mk:3",
+		"#1 <program> at add.mk:5",
+		"(mdb) scope 0:",
+		"  a = 1",
+		"  b = 2",
+		"  sum = 3",
+		"globals:",
+		"  add = fn(a, b) {",
+		"let sum = (a + b);sum",
+		"}",
+		"(mdb) 30",
+		"(mdb) stopped at add.mk:6 (step)",
+		">    6  let y = add(x, 3);",
+		"(mdb) (mdb) no breakpoint at line 3",
+		`(mdb) unknown command "frobnicate", try help`,
+		"(mdb) ",
+	}
+	if got := strings.Split(out.String(), "\n"); strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("wrong output.\nexpected:\n%s\ngot:\n%s", strings.Join(expected, "\n"), out.String())
+	}
+}
+
+func TestConsoleNilValues(t *testing.T) {
+	source := "let r = fn() {}();\nlet x = 1;"
+	var out bytes.Buffer
+	c := NewConsole("nil.mk", source, strings.NewReader("next\nlocals\np let z = 3\nc\n"), &out)
+	c.Run(parse(t, source), object.NewEnvironment())
+
+	for _, expected := range []string{"  r = null\n", "(mdb) null\n"} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("expected %q in the output:\n%s", expected, out.String())
+		}
+	}
+}
+
+func TestConsoleQuitAtEndOfInput(t *testing.T) {
+	var out bytes.Buffer
+	c := NewConsole("add.mk", testProgram, strings.NewReader("step\n"), &out)
+	result := c.Run(parse(t, testProgram), object.NewEnvironment())
+	if result.Inspect() != "ERROR: debugger: program stopped" {
+		t.Errorf("wrong result. got=%s", result.Inspect())
+	}
+	if !strings.Contains(out.String(), "stopped at add.mk:5 (step)") {
+		t.Errorf("step did not stop at line 5:\n%s", out.String())
+	}
+}
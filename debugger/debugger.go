@@ -0,0 +1,232 @@
+package debugger
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	ast "github.com/Artypuppet/monkey/ast"
+	evaluator "github.com/Artypuppet/monkey/evaluator"
+	lexer "github.com/Artypuppet/monkey/lexer"
+	object "github.com/Artypuppet/monkey/object"
+	parser "github.com/Artypuppet/monkey/parser"
+)
+
+// type def for why the debugger stopped the program.
+type Reason string
+
+const (
+	Entry      Reason = "entry"      // before the first statement, if StopOnEntry is set
+	Breakpoint Reason = "breakpoint" // at a statement on a line with a breakpoint
+	Step       Reason = "step"       // at the end of a step
+	Pause      Reason = "pause"      // at the next statement after Pause was called
+)
+
+// type def for what the program should do after a stop.
+type Action int
+
+const (
+	Continue Action = iota // run until the next breakpoint
+	StepIn                 // stop at the next statement, inside calls too
+	StepOver               // stop at the next statement of this call or its callers
+	StepOut                // stop at the next statement after this call returned
+	Quit                   // stop running the program
+)
+
+// struct describing a call on the call stack. Line and Column are the
+// position of the statement the call is at, 0 before it ran any.
+type Frame struct {
+	Name     string
+	Function *object.Function // nil for the program itself
+	Env      *object.Environment
+	Line     int
+	Column   int
+}
+
+// struct defining a debugger. It is the evaluator.Hook of the programs it
+// runs and stops them at statements, where it asks OnStop what to do next.
+// The stops are made on the goroutine running the program, so OnStop can
+// look at the stack and the environments while the program waits.
+// Breakpoints can be changed and Pause called from any goroutine.
+type Debugger struct {
+	OnStop      func(d *Debugger, reason Reason) Action
+	StopOnEntry bool
+	// the streams of the programs run, see evaluator.Interpreter.
+	Stdout io.Writer
+	Stdin  io.Reader
+
+	mu          sync.Mutex // guards breakpoints
+	breakpoints map[int]bool
+	pause       atomic.Bool
+	frames      []*Frame // innermost last
+	action      Action
+	depth       int  // the number of frames when the last step started
+	started     bool // whether the first statement was reached
+	quit        bool
+}
+
+// constructor for a debugger calling onStop whenever the program stops.
+func New(onStop func(d *Debugger, reason Reason) Action) *Debugger {
+	return &Debugger{OnStop: onStop, breakpoints: make(map[int]bool)}
+}
+
+// error returned by Run when the program was stopped with Quit.
+var errQuit = &object.Error{Message: "debugger: program stopped"}
+
+// method that runs program in env until it ends or is stopped with Quit.
+// It returns the value of the program like evaluator.Eval.
+func (d *Debugger) Run(program *ast.Program, env *object.Environment) object.Object {
+	d.frames = []*Frame{{Name: "<program>", Env: env}}
+	d.action, d.started, d.quit = Continue, false, false
+	ip := &evaluator.Interpreter{Hook: d, Stdout: d.Stdout, Stdin: d.Stdin}
+	return ip.Eval(program, env)
+}
+
+// ---------------------------------Breakpoints---------------------------------
+
+// method that adds a breakpoint on line.
+func (d *Debugger) SetBreakpoint(line int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.breakpoints[line] = true
+}
+
+// method that removes the breakpoint on line and reports whether there was one.
+func (d *Debugger) ClearBreakpoint(line int) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	ok := d.breakpoints[line]
+	delete(d.breakpoints, line)
+	return ok
+}
+
+// method that replaces all breakpoints by breakpoints on lines.
+func (d *Debugger) SetBreakpoints(lines []int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.breakpoints = make(map[int]bool)
+	for _, line := range lines {
+		d.breakpoints[line] = true
+	}
+}
+
+// method that returns the lines with a breakpoint in order.
+func (d *Debugger) Breakpoints() []int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	lines := []int{}
+	for line := range d.breakpoints {
+		lines = append(lines, line)
+	}
+	sort.Ints(lines)
+	return lines
+}
+
+// method that reports whether there is a breakpoint on line.
+func (d *Debugger) hasBreakpoint(line int) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.breakpoints[line]
+}
+
+// method that makes a running program stop at its next statement.
+func (d *Debugger) Pause() {
+	d.pause.Store(true)
+}
+
+// -------------------------------Inspection----------------------------------
+
+// method that returns the call stack, the innermost call first.
+// The frames are copies and don't change as the program goes on.
+func (d *Debugger) Stack() []Frame {
+	stack := []Frame{}
+	for i := len(d.frames) - 1; i >= 0; i-- {
+		stack = append(stack, *d.frames[i])
+	}
+	return stack
+}
+
+// method that evaluates an expression in the environment of frame i of
+// Stack, 0 being the innermost. Breakpoints are not hit while it runs.
+func (d *Debugger) Evaluate(expression string, frame int) object.Object {
+	if frame < 0 || frame >= len(d.frames) {
+		return &object.Error{Message: fmt.Sprintf("no frame %d", frame)}
+	}
+	p := parser.New(lexer.New(expression))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return &object.Error{Message: p.Errors()[0]}
+	}
+	ip := &evaluator.Interpreter{Stdout: d.Stdout, Stdin: d.Stdin}
+	return ip.Eval(program, d.frames[len(d.frames)-1-frame].Env)
+}
+
+// ---------------------------------Hook--------------------------------------
+
+// method implementing evaluator.Hook. It decides at every statement whether
+// the program has to stop.
+func (d *Debugger) Before(node ast.Node, env *object.Environment) *object.Error {
+	if d.quit {
+		return errQuit
+	}
+	frame := d.frames[len(d.frames)-1]
+	frame.Env = env
+
+	line, column, ok := statementPosition(node)
+	if !ok {
+		return nil
+	}
+	newLine := line != frame.Line
+	frame.Line, frame.Column = line, column
+
+	var reason Reason
+	switch {
+	case !d.started && d.StopOnEntry:
+		reason = Entry
+	case d.pause.Swap(false):
+		reason = Pause
+	case d.hasBreakpoint(line) && newLine:
+		reason = Breakpoint
+	case d.action == StepIn,
+		d.action == StepOver && len(d.frames) <= d.depth,
+		d.action == StepOut && len(d.frames) < d.depth:
+		reason = Step
+	}
+	d.started = true
+	if reason == "" {
+		return nil
+	}
+
+	d.action = d.OnStop(d, reason)
+	d.depth = len(d.frames)
+	if d.action == Quit {
+		d.quit = true
+		return errQuit
+	}
+	return nil
+}
+
+// method implementing evaluator.Hook that pushes a frame for the call.
+func (d *Debugger) EnterCall(fn *object.Function, args []object.Object, env *object.Environment) {
+	d.frames = append(d.frames, &Frame{Name: evaluator.FunctionName(fn), Function: fn, Env: env})
+}
+
+// method implementing evaluator.Hook that pops the frame of the call.
+func (d *Debugger) ExitCall(fn *object.Function, result object.Object) {
+	d.frames = d.frames[:len(d.frames)-1]
+}
+
+// helper function that returns the position of a statement.
+func statementPosition(node ast.Node) (int, int, bool) {
+	switch node := node.(type) {
+	case *ast.LetStatement:
+		return node.Token.Line, node.Token.Column, true
+	case *ast.ReturnStatement:
+		return node.Token.Line, node.Token.Column, true
+	case *ast.ExpressionStatement:
+		return node.Token.Line, node.Token.Column, true
+	}
+	return 0, 0, false
+}
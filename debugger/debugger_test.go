@@ -0,0 +1,170 @@
+package debugger
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	ast "github.com/Artypuppet/monkey/ast"
+	lexer "github.com/Artypuppet/monkey/lexer"
+	object "github.com/Artypuppet/monkey/object"
+	parser "github.com/Artypuppet/monkey/parser"
+)
+
+const testProgram = `let add = fn(a, b) {
+  let sum = a + b;
+  sum
+};
+let x = add(1, 2);
+let y = add(x, 3);
+y`
+
+func parse(t *testing.T, input string) *ast.Program {
+	t.Helper()
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+	return program
+}
+
+func TestDebugger(t *testing.T) {
+	tests := []struct {
+		name        string
+		stopOnEntry bool
+		breakpoints []int
+		actions     []Action
+		expected    []string
+		result      string
+	}{
+		{
+			"step in",
+			true,
+			nil,
+			[]Action{StepIn, StepIn, StepIn, StepIn, StepIn, StepIn, StepIn, StepIn},
+			[]string{
+				"entry 1 <program>",
+				"step 5 <program>",
+				"step 2 add <program>",
+				"step 3 add <program>",
+				"step 6 <program>",
+				"step 2 add <program>",
+				"step 3 add <program>",
+				"step 7 <program>",
+			},
+			"6",
+		},
+		{
+			"step over",
+			true,
+			nil,
+			[]Action{StepOver, StepOver, StepOver, StepOver},
+			[]string{
+				"entry 1 <program>",
+				"step 5 <program>",
+				"step 6 <program>",
+				"step 7 <program>",
+			},
+			"6",
+		},
+		{
+			"breakpoints",
+			false,
+			[]int{3, 7},
+			[]Action{Continue, Continue, Continue},
+			[]string{
+				"breakpoint 3 add <program>",
+				"breakpoint 3 add <program>",
+				"breakpoint 7 <program>",
+			},
+			"6",
+		},
+		{
+			"step out and quit",
+			false,
+			[]int{2},
+			[]Action{StepOut, Continue, Quit},
+			[]string{
+				"breakpoint 2 add <program>",
+				"step 6 <program>",
+				"breakpoint 2 add <program>",
+			},
+			"ERROR: debugger: program stopped",
+		},
+	}
+
+	for _, tt := range tests {
+		events := []string{}
+		d := New(func(d *Debugger, reason Reason) Action {
+			names := []string{}
+			for _, frame := range d.Stack() {
+				names = append(names, frame.Name)
+			}
+			events = append(events, fmt.Sprintf("%s %d %s", reason, d.Stack()[0].Line, strings.Join(names, " ")))
+			if len(events) > len(tt.actions) {
+				return Quit
+			}
+			return tt.actions[len(events)-1]
+		})
+		d.StopOnEntry = tt.stopOnEntry
+		d.SetBreakpoints(tt.breakpoints)
+
+		result := d.Run(parse(t, testProgram), object.NewEnvironment())
+		if !reflect.DeepEqual(events, tt.expected) {
+			t.Errorf("%s: wrong stops.\nexpected=%q\ngot=%q", tt.name, tt.expected, events)
+		}
+		if result.Inspect() != tt.result {
+			t.Errorf("%s: wrong result. expected=%q, got=%q", tt.name, tt.result, result.Inspect())
+		}
+	}
+}
+
+func TestDebuggerInspection(t *testing.T) {
+	input := `let double = fn(n) {
+  n * 2
+};
+let twice = fn(n) {
+  double(double(n))
+};
+twice(5);`
+
+	evaluated := map[string]string{}
+	d := New(func(d *Debugger, reason Reason) Action {
+		for _, expr := range []string{"n", "n + 1", "twice(0)", "m"} {
+			evaluated[expr] = d.Evaluate(expr, 0).Inspect()
+		}
+		evaluated["frame 1"] = d.Evaluate("n", 1).Inspect()
+		evaluated["frame 3"] = d.Evaluate("n", 3).Inspect()
+
+		stack := d.Stack()
+		if len(stack) != 3 {
+			t.Fatalf("wrong stack depth. expected=3, got=%d", len(stack))
+		}
+		if stack[0].Name != "double" || stack[0].Function == nil {
+			t.Errorf("wrong innermost frame: %+v", stack[0])
+		}
+		if stack[2].Name != "<program>" || stack[2].Function != nil || stack[2].Line != 7 {
+			t.Errorf("wrong outermost frame: %+v", stack[2])
+		}
+		if names := stack[0].Env.Names(); !reflect.DeepEqual(names, []string{"n"}) {
+			t.Errorf("wrong names in frame 0: %v", names)
+		}
+		return Quit
+	})
+	d.SetBreakpoint(2)
+	d.Run(parse(t, input), object.NewEnvironment())
+
+	expected := map[string]string{
+		"n":        "5",
+		"n + 1":    "6",
+		"twice(0)": "0",
+		"m":        "ERROR: identifier not found: m",
+		"frame 1":  "5",
+		"frame 3":  "ERROR: no frame 3",
+	}
+	if !reflect.DeepEqual(evaluated, expected) {
+		t.Errorf("wrong evaluations.\nexpected=%v\ngot=%v", expected, evaluated)
+	}
+}
@@ -1,10 +1,14 @@
 package evaluator
 
 import (
+	"bufio"
 	"fmt"
+	"io"
+	"sort"
 
 	ast "github.com/Artypuppet/monkey/ast"
 	object "github.com/Artypuppet/monkey/object"
+	sandbox "github.com/Artypuppet/monkey/sandbox"
 )
 
 var (
@@ -13,6 +17,27 @@ var (
 	FALSE = &object.Boolean{Value: false}
 )
 
+// object type of a pending tail call. It never escapes the evaluator.
+const TAIL_CALL_OBJ = "TAIL_CALL"
+
+// struct representing a call in tail position that has not been made yet.
+// It is handed back to applyFunction, which makes the call in its loop
+// rather than nesting another Eval on the Go stack.
+// It implements the Object interface.
+type tailCall struct {
+	fn   *object.Function
+	args []object.Object
+}
+
+// methods implementing the Object interface.
+func (tc *tailCall) Type() object.ObjectType {
+	return TAIL_CALL_OBJ
+}
+
+func (tc *tailCall) Inspect() string {
+	return "tail call"
+}
+
 // map that contains ptrs to builtin functions
 var builtins = map[string]*object.Builtin{
 	"len": &object.Builtin{
@@ -106,6 +131,22 @@ var builtins = map[string]*object.Builtin{
 			return &object.Array{Elements: newElements}
 		},
 	},
+	"json_parse":     &object.Builtin{Fn: jsonParse},
+	"json_stringify": &object.Builtin{Fn: jsonStringify},
+	"format":         &object.Builtin{Fn: builtinFormat},
+}
+
+// function that returns the names of all builtin functions in sorted order.
+func BuiltinNames() []string {
+	names := make([]string, 0, len(builtins)+len(interpreterBuiltins))
+	for name := range builtins {
+		names = append(names, name)
+	}
+	for name := range interpreterBuiltins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
 }
 
 // function that creates new error structs
@@ -122,25 +163,66 @@ func isError(obj object.Object) bool {
 	return false
 }
 
+// --------------------------------Interpreter---------------------------------
+
+// struct holding the settings of an evaluation. The zero value evaluates
+// programs the same way as Eval.
+type Interpreter struct {
+	Hook Hook // told about every step of the evaluation, nil if no one listens
+	// builtins of this interpreter only, looked up before the global ones.
+	// Builtins needing the interpreter, e.g. to call a function, are made as
+	// closures over it.
+	Builtins map[string]*object.Builtin
+	// where print, println and puts write and where input and readline
+	// read, os.Stdout and os.Stdin if nil.
+	Stdout io.Writer
+	Stdin  io.Reader
+	// the files read_file, write_file and friends work on. They fail if it
+	// is nil, so a program only gets to the files it is given.
+	FS sandbox.FS
+	// decides which of the builtins needing capabilities may be called. A
+	// nil policy grants none.
+	Policy *Policy
+
+	callSite    *ast.CallExpression        // the call of the builtin running, if any
+	bound       map[string]*object.Builtin // the interpreterBuiltins made for this interpreter
+	stdinReader *bufio.Reader              // Stdin, buffered on the first read
+}
+
 // The top level function to evaluate nodes in the ast
+// It evaluates node with an interpreter with the default settings.
+func Eval(node ast.Node, env *object.Environment) object.Object {
+	return (&Interpreter{}).Eval(node, env)
+}
+
+// The method evaluating nodes in the ast
 // It calls itself recursively whenever it encounters an expression
 // Otherwise it delegates it to other functions
-func Eval(node ast.Node, env *object.Environment) object.Object {
+func (ip *Interpreter) Eval(node ast.Node, env *object.Environment) object.Object {
+	if _, ok := node.(*ast.Program); !ok {
+		if err := ip.before(node, env); err != nil {
+			return err
+		}
+	}
 	switch node := node.(type) {
 	case *ast.IndexExpression:
-		left := Eval(node.Left, env)
+		left := ip.Eval(node.Left, env)
 		if isError(left) {
 			return left
 		}
-		index := Eval(node.Index, env)
+		index := ip.Eval(node.Index, env)
 		if isError(index) {
 			return index
 		}
 		return evalIndexExpression(left, index)
 	case *ast.Program:
-		return evalProgram(node.Statements, env)
+		return ip.evalProgram(node.Statements, env)
 	case *ast.ExpressionStatement:
-		return Eval(node.Expression, env)
+		// a named function on its own is a declaration of that name.
+		if fl, ok := node.Expression.(*ast.FunctionLiteral); ok && fl.Name != nil {
+			return env.Set(fl.Name.Value, ip.Eval(fl, env))
+		}
+		return ip.Eval(node.Expression, env)
 	case *ast.IntegerLiteral:
 		return &object.Integer{Value: node.Value}
 	case *ast.StringLiteral:
@@ -148,19 +230,19 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 	case *ast.Boolean:
 		return nativeBoolToBooleanObject(node.Value)
 	case *ast.PrefixExpression:
-		right := Eval(node.Right, env)
+		right := ip.Eval(node.Right, env)
 		if isError(right) {
 			return right
 		}
 		return evalPrefixExpression(node.Operator, right)
 	case *ast.InfixExpression:
-		left := Eval(node.Left, env)
+		left := ip.Eval(node.Left, env)
 
 		if isError(left) {
 			return left
 		}
 
-		right := Eval(node.Right, env)
+		right := ip.Eval(node.Right, env)
 
 		if isError(right) {
 			return right
@@ -168,43 +250,46 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 
 		return evalInfixExpression(node.Operator, left, right)
 	case *ast.IfExpression:
-		return evalIfExpression(node, env)
+		return ip.evalIfExpression(node, env, false)
 	case *ast.BlockStatement:
-		return evalBlockStatement(node, env)
+		return ip.evalBlockStatement(node, object.NewEnclosedEnvironment(env), false)
 	case *ast.ReturnStatement:
-		val := Eval(node.ReturnValue, env)
+		// the returned expression is always in tail position.
+		val := ip.evalTailExpression(node.ReturnValue, env)
 		if isError(val) {
 			return val
 		}
 		return &object.ReturnValue{Value: val}
 	case *ast.LetStatement:
-		val := Eval(node.Value, env)
+		val := ip.Eval(node.Value, env)
+		if isError(val) {
+			return val
+		}
+		if node.IsConst() {
+			val = env.SetConst(node.Name.Value, val)
+		} else {
+			val = env.Set(node.Name.Value, val)
+		}
 		if isError(val) {
 			return val
 		}
-		env.Set(node.Name.Value, val)
 	case *ast.Identifier:
-		return evalIdentifier(node, env)
+		return ip.evalIdentifier(node, env)
 	case *ast.FunctionLiteral:
-		params := node.Parameters
-		body := node.Body
-		return &object.Function{Parameters: params, Body: body, Env: env}
+		return evalFunctionLiteral(node, env)
 	case *ast.CallExpression:
-		function := Eval(node.Function, env)
-		if isError(function) {
-			return function
-		}
-		args := evalExpressions(node.Arguments, env)
-		if len(args) == 1 && isError(args[0]) {
-			return args[0]
-		}
-		return applyFunction(function, args)
+		return ip.evalCallExpression(node, env, false)
 	case *ast.ArrayLiteral:
-		elements := evalExpressions(node.Elements, env)
+		elements := ip.evalExpressions(node.Elements, env)
 		if len(elements) == 1 && isError(elements[0]) {
 			return elements[0]
 		}
 		return &object.Array{Elements: elements}
+	case *ast.HashLiteral:
+		return ip.evalHashLiteral(node, env)
+	case *ast.SpreadExpression:
+		// spreads are expanded by evalExpressions, anywhere else they are an error.
+		return newError("spread is only allowed in call arguments and array literals")
 	}
 	return nil
 }
@@ -217,13 +302,45 @@ func nativeBoolToBooleanObject(val bool) object.Object {
 	return FALSE
 }
 
+// This function creates the function object for a function literal.
+// A named function gets its own environment holding just its name, so that
+// it can always call itself no matter what the name refers to outside.
+func evalFunctionLiteral(node *ast.FunctionLiteral, env *object.Environment) object.Object {
+	fn := &object.Function{
+		Parameters: node.Parameters,
+		Defaults:   node.Defaults,
+		Rest:       node.Rest,
+		Body:       node.Body,
+		Env:        env,
+	}
+	if node.Name != nil {
+		fn.Name = node.Name.Value
+		fn.Env = object.NewEnclosedEnvironment(env)
+		fn.Env.Set(fn.Name, fn)
+	}
+	return fn
+}
+
 // this function is used to evaluate a list of expressions
 // e.g. list of arguments to a function.
-func evalExpressions(expressions []ast.Expression, env *object.Environment) []object.Object {
+// A spread expression in the list is replaced by the elements of its array.
+func (ip *Interpreter) evalExpressions(expressions []ast.Expression, env *object.Environment) []object.Object {
 	var result []object.Object
 
 	for _, e := range expressions {
-		evaluated := Eval(e, env)
+		if spread, ok := e.(*ast.SpreadExpression); ok {
+			evaluated := ip.Eval(spread.Value, env)
+			if isError(evaluated) {
+				return []object.Object{evaluated}
+			}
+			arr, ok := evaluated.(*object.Array)
+			if !ok {
+				return []object.Object{newError("cannot spread %s, want ARRAY", evaluated.Type())}
+			}
+			result = append(result, arr.Elements...)
+			continue
+		}
+		evaluated := ip.Eval(e, env)
 		if isError(evaluated) {
 			return []object.Object{evaluated}
 		}
@@ -234,13 +351,17 @@ func evalExpressions(expressions []ast.Expression, env *object.Environment) []ob
 
 // This function evaulates all the statements in a Statement slice
 // by calling Eval on each statement.
-func evalProgram(stmts []ast.Statement, env *object.Environment) object.Object {
+func (ip *Interpreter) evalProgram(stmts []ast.Statement, env *object.Environment) object.Object {
 	var result object.Object
 	for _, stmt := range stmts {
-		result = Eval(stmt, env)
+		result = ip.Eval(stmt, env)
 
 		switch result := result.(type) {
 		case *object.ReturnValue:
+			// a top level return can still hand us a pending tail call.
+			if call, ok := result.Value.(*tailCall); ok {
+				return ip.applyFunction(call.fn, call.args)
+			}
 			return result.Value
 		case *object.Error:
 			return result
@@ -295,15 +416,14 @@ func evalInfixExpression(operator string, left, right object.Object) object.Obje
 		return evalIntegerInfixExpression(operator, left, right)
 	case left.Type() == object.STRING_OBJ && right.Type() == object.STRING_OBJ:
 		return evalStringInfixExpression(operator, left, right)
-	case left.Type() != right.Type():
-		return newError("type mismatch: %s %s %s", left.Type(), operator, right.Type())
 	case operator == "==":
-		// we only need to check the ptr value since they will always be the same for the objects defined at the top.
-		// pitfall is that the !(585 > 9) == 71 return false since we comparing ptrs the address will ofcourse
-		// be different for lhs which will be the FALSE struct and whatever address 71 has.
-		return nativeBoolToBooleanObject(left == right)
+		// equality is checked before the type mismatch so that comparing
+		// values of different types is simply false instead of an error.
+		return nativeBoolToBooleanObject(ObjectsEqual(left, right))
 	case operator == "!=":
-		return nativeBoolToBooleanObject(left != right)
+		return nativeBoolToBooleanObject(!ObjectsEqual(left, right))
+	case left.Type() != right.Type():
+		return newError("type mismatch: %s %s %s", left.Type(), operator, right.Type())
 	default:
 		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
 	}
@@ -321,6 +441,9 @@ func evalIntegerInfixExpression(operator string, left, right object.Object) obje
 	case "*":
 		return &object.Integer{Value: leftVal * rightVal}
 	case "/":
+		if rightVal == 0 {
+			return newError("division by zero: %d / 0", leftVal)
+		}
 		return &object.Integer{Value: leftVal / rightVal}
 	case "<":
 		return nativeBoolToBooleanObject(leftVal < rightVal)
@@ -343,30 +466,88 @@ func evalStringInfixExpression(operator string, left, right object.Object) objec
 	case "+":
 		return &object.String{Value: leftVal + rightVal}
 	case "==":
-		return &object.Boolean{Value: leftVal == rightVal}
+		return nativeBoolToBooleanObject(leftVal == rightVal)
 	case "!=":
-		return &object.Boolean{Value: leftVal != rightVal}
+		return nativeBoolToBooleanObject(leftVal != rightVal)
 	default:
 		return newError("unknown operator: %s %s %s",
 			left.Type(), operator, right.Type())
 	}
 }
 
+// This function checks whether two objects are structurally equal.
+// Values of different types are never equal. Integers, strings and booleans
+// are compared by value, NULL is only equal to NULL, arrays are equal
+// when they have the same length and their elements are equal pairwise and
+// hashes are equal when they have the same keys with equal values.
+// Functions and builtins have no structure worth comparing, so they are
+// only equal to themselves.
+func ObjectsEqual(left, right object.Object) bool {
+	if left.Type() != right.Type() {
+		return false
+	}
+	switch left := left.(type) {
+	case *object.Integer:
+		return left.Value == right.(*object.Integer).Value
+	case *object.String:
+		return left.Value == right.(*object.String).Value
+	case *object.Boolean:
+		return left.Value == right.(*object.Boolean).Value
+	case *object.Null:
+		return true
+	case *object.Array:
+		rightArr := right.(*object.Array)
+		if len(left.Elements) != len(rightArr.Elements) {
+			return false
+		}
+		for i, el := range left.Elements {
+			if !ObjectsEqual(el, rightArr.Elements[i]) {
+				return false
+			}
+		}
+		return true
+	case *object.Hash:
+		rightHash := right.(*object.Hash)
+		if len(left.Pairs) != len(rightHash.Pairs) {
+			return false
+		}
+		for key, pair := range left.Pairs {
+			other, ok := rightHash.Pairs[key]
+			if !ok || !ObjectsEqual(pair.Value, other.Value) {
+				return false
+			}
+		}
+		return true
+	default:
+		return left == right
+	}
+}
+
 // This function evalutes an if expression
-func evalIfExpression(ie *ast.IfExpression, env *object.Environment) object.Object {
-	condition := Eval(ie.Condition, env)
+// Each branch is a block with its own scope, so bindings made in it don't leak.
+// When tail is true the if expression is in tail position of a function body
+// so the same holds for the last statement of whichever branch is taken.
+func (ip *Interpreter) evalIfExpression(ie *ast.IfExpression, env *object.Environment, tail bool) object.Object {
+	condition := ip.Eval(ie.Condition, env)
 
 	if isError(condition) {
 		return condition
 	}
 
+	var arm *ast.BlockStatement
 	if isTruthy(condition) {
-		return Eval(ie.Consequence, env)
+		arm = ie.Consequence
 	} else if ie.Alternative != nil {
-		return Eval(ie.Alternative, env)
+		arm = ie.Alternative
 	} else {
 		return NULL
 	}
+	// the hook is told which arm runs, even if it has no statements.
+	armEnv := object.NewEnclosedEnvironment(env)
+	if err := ip.before(arm, armEnv); err != nil {
+		return err
+	}
+	return ip.evalBlockStatement(arm, armEnv, tail)
 }
 
 // This function simply checks if the boolean expression should considered truthy
@@ -389,11 +570,21 @@ func isTruthy(obj object.Object) bool {
 // function that evaluates block statements.
 // It's different as it makes sure to terminate the execution of the block
 // when it encounters a return statement.
-func evalBlockStatement(node *ast.BlockStatement, env *object.Environment) object.Object {
+// The block is evaluated in env as is, callers give it its own scope.
+// When tail is true the value of the last statement is the value of the enclosing
+// function, so a call in that position is evaluated as a tail call.
+func (ip *Interpreter) evalBlockStatement(node *ast.BlockStatement, env *object.Environment, tail bool) object.Object {
 	var result object.Object
 
-	for _, stmt := range node.Statements {
-		result = Eval(stmt, env)
+	for i, stmt := range node.Statements {
+		if es, ok := stmt.(*ast.ExpressionStatement); ok && tail && i == len(node.Statements)-1 {
+			if err := ip.before(es, env); err != nil {
+				return err
+			}
+			result = ip.evalTailExpression(es.Expression, env)
+		} else {
+			result = ip.Eval(stmt, env)
+		}
 
 		if result != nil {
 			rt := result.Type()
@@ -407,41 +598,181 @@ func evalBlockStatement(node *ast.BlockStatement, env *object.Environment) objec
 }
 
 // evaluates identifiers by return their values
-func evalIdentifier(node *ast.Identifier, env *object.Environment) object.Object {
+func (ip *Interpreter) evalIdentifier(node *ast.Identifier, env *object.Environment) object.Object {
 	if val, ok := env.Get(node.Value); ok {
 		return val
 	}
+	if builtin, ok := ip.Builtins[node.Value]; ok {
+		return builtin
+	}
+	if builtin := ip.interpreterBuiltin(node.Value); builtin != nil {
+		return builtin
+	}
 	if builtin, ok := builtins[node.Value]; ok {
 		return builtin
 	}
 	return newError("identifier not found: " + node.Value)
 }
 
-// evaluates a function call with the specified arguments.
-func applyFunction(fn object.Object, args []object.Object) object.Object {
-	switch fn := fn.(type) {
-	case *object.Function:
-		extendedEnv := extendFunctionEnv(fn, args)
-		evaluated := Eval(fn.Body, extendedEnv)
-		return unwrapReturnValue(evaluated)
-	case *object.Builtin:
-		return fn.Fn(args...)
+// This function evaluates a call expression. The function and the arguments
+// are evaluated first. When tail is true and the callee is a monkey function
+// the call is not made here, instead a tailCall is returned so that the
+// applyFunction loop of the enclosing function can make it without growing
+// the Go stack.
+func (ip *Interpreter) evalCallExpression(node *ast.CallExpression, env *object.Environment, tail bool) object.Object {
+	function := ip.Eval(node.Function, env)
+	if isError(function) {
+		return function
+	}
+	args := ip.evalExpressions(node.Arguments, env)
+	if len(args) == 1 && isError(args[0]) {
+		return args[0]
+	}
+	if fn, ok := function.(*object.Function); ok && tail {
+		return &tailCall{fn: fn, args: args}
+	}
+	if _, ok := function.(*object.Builtin); ok {
+		saved := ip.callSite
+		ip.callSite = node
+		defer func() { ip.callSite = saved }()
+	}
+	return ip.applyFunction(function, args)
+}
+
+// method that calls a function or a builtin with args, for builtins that
+// take a function as argument.
+func (ip *Interpreter) Call(fn object.Object, args ...object.Object) object.Object {
+	return ip.applyFunction(fn, args)
+}
+
+// method that returns the call expression of the builtin that is running,
+// or nil when called outside of a builtin.
+func (ip *Interpreter) CallSite() *ast.CallExpression {
+	return ip.callSite
+}
+
+// This function evaluates an expression that is in tail position i.e. its value
+// becomes the return value of the enclosing function. Only calls and if
+// expressions need special handling, everything else is evaluated as usual.
+func (ip *Interpreter) evalTailExpression(node ast.Expression, env *object.Environment) object.Object {
+	switch node := node.(type) {
+	case *ast.CallExpression:
+		if err := ip.before(node, env); err != nil {
+			return err
+		}
+		return ip.evalCallExpression(node, env, true)
+	case *ast.IfExpression:
+		if err := ip.before(node, env); err != nil {
+			return err
+		}
+		return ip.evalIfExpression(node, env, true)
 	default:
-		return newError("not a function: %s", fn.Type())
+		return ip.Eval(node, env)
+	}
+}
+
+// evaluates a function call with the specified arguments.
+// Calls to monkey functions run as a trampoline: whenever the body hands back
+// a tailCall we loop and apply it in place instead of recursing, so tail
+// recursive functions run in constant Go stack space.
+func (ip *Interpreter) applyFunction(fn object.Object, args []object.Object) object.Object {
+	for {
+		switch function := fn.(type) {
+		case *object.Function:
+			extendedEnv, err := ip.extendFunctionEnv(function, args)
+			if err != nil {
+				return err
+			}
+			ip.enterCall(function, args, extendedEnv)
+			evaluated := unwrapReturnValue(ip.evalBlockStatement(function.Body, extendedEnv, true))
+			ip.exitCall(function, evaluated)
+			if call, ok := evaluated.(*tailCall); ok {
+				fn, args = call.fn, call.args
+				continue
+			}
+			return evaluated
+		case *object.Builtin:
+			if len(function.Capabilities) != 0 {
+				if err := ip.authorize(function, args); err != nil {
+					return err
+				}
+			}
+			return function.Fn(args...)
+		default:
+			return newError("not a function: %s", fn.Type())
+		}
 	}
 }
 
 // this function creates a new environment and attaches it to the environment
 // the function has. The environment that the function has already is the env
 // that it was defined in.
-func extendFunctionEnv(function *object.Function, args []object.Object) *object.Environment {
+// It checks that the number of arguments fits the parameters, evaluates the
+// defaults of missing arguments inside the new environment, so that they can
+// refer to the parameters before them, and collects any extra arguments into
+// the rest parameter. If anything goes wrong an error is returned instead.
+func (ip *Interpreter) extendFunctionEnv(function *object.Function, args []object.Object) (*object.Environment, object.Object) {
+	if err := checkArity(function, len(args)); err != nil {
+		return nil, err
+	}
+
 	newEnv := object.NewEnclosedEnvironment(function.Env)
 
 	for paramIdx, param := range function.Parameters {
-		newEnv.Set(param.Value, args[paramIdx])
+		var val object.Object
+		if paramIdx < len(args) {
+			val = args[paramIdx]
+		} else {
+			val = ip.Eval(function.Defaults[paramIdx], newEnv)
+		}
+		// also catches parameters declared twice in strict mode.
+		if val = newEnv.Set(param.Value, val); isError(val) {
+			return nil, val
+		}
 	}
 
-	return newEnv
+	if function.Rest != nil {
+		rest := []object.Object{}
+		if len(args) > len(function.Parameters) {
+			rest = append(rest, args[len(function.Parameters):]...)
+		}
+		if val := newEnv.Set(function.Rest.Value, &object.Array{Elements: rest}); isError(val) {
+			return nil, val
+		}
+	}
+
+	return newEnv, nil
+}
+
+// this function checks that a function can be called with the given number
+// of arguments. Parameters with a default value may be left out and a rest
+// parameter accepts any number of extra arguments.
+func checkArity(function *object.Function, got int) *object.Error {
+	required := 0
+	for i := range function.Parameters {
+		if i < len(function.Defaults) && function.Defaults[i] != nil {
+			break
+		}
+		required++
+	}
+	max := len(function.Parameters)
+
+	var want string
+	switch {
+	case function.Rest != nil && got < required:
+		want = fmt.Sprintf(" at least %d", required)
+	case function.Rest == nil && required == max && got != max:
+		want = fmt.Sprintf("=%d", max)
+	case function.Rest == nil && (got < required || got > max):
+		want = fmt.Sprintf("=%d to %d", required, max)
+	default:
+		return nil
+	}
+
+	if function.Name != "" {
+		return newError("wrong number of arguments to `%s`. got=%d, want%s", function.Name, got, want)
+	}
+	return newError("wrong number of arguments. got=%d, want%s", got, want)
 }
 
 // this function checks if the object type is ReturnValue.
@@ -459,6 +790,8 @@ func evalIndexExpression(left object.Object, index object.Object) object.Object
 	switch {
 	case left.Type() == object.ARRAY_OBJ && index.Type() == object.INTEGER_OBJ:
 		return evalArrayIndexExpression(left, index)
+	case left.Type() == object.HASH_OBJ:
+		return evalHashIndexExpression(left, index)
 	default:
 		return newError("index operator not supported: %s", left.Type())
 	}
@@ -476,3 +809,45 @@ func evalArrayIndexExpression(array object.Object, index object.Object) object.O
 
 	return arrayObject.Elements[idx]
 }
+
+// This function evaluates a hash literal. The keys are evaluated before their
+// values, in the order they are written, and must be hashable.
+func (ip *Interpreter) evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Object {
+	pairs := make(map[object.HashKey]object.HashPair)
+
+	for _, pair := range node.Pairs {
+		key := ip.Eval(pair.Key, env)
+		if isError(key) {
+			return key
+		}
+		hashKey, ok := key.(object.Hashable)
+		if !ok {
+			return newError("unusable as hash key: %s", key.Type())
+		}
+
+		value := ip.Eval(pair.Value, env)
+		if isError(value) {
+			return value
+		}
+		pairs[hashKey.HashKey()] = object.HashPair{Key: key, Value: value}
+	}
+
+	return &object.Hash{Pairs: pairs}
+}
+
+// This function evaluates a hash index expression.
+// A key that is not in the hash gives NULL like an index outside an array.
+func evalHashIndexExpression(hash, index object.Object) object.Object {
+	hashObject := hash.(*object.Hash)
+
+	key, ok := index.(object.Hashable)
+	if !ok {
+		return newError("unusable as hash key: %s", index.Type())
+	}
+
+	pair, ok := hashObject.Pairs[key.HashKey()]
+	if !ok {
+		return NULL
+	}
+	return pair.Value
+}
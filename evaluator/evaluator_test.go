@@ -5,6 +5,7 @@ import (
 
 	lexer "github.com/Artypuppet/monkey/lexer"
 	object "github.com/Artypuppet/monkey/object"
+	optimizer "github.com/Artypuppet/monkey/optimizer"
 	parser "github.com/Artypuppet/monkey/parser"
 )
 
@@ -40,7 +41,46 @@ func testEval(t *testing.T, input string) object.Object {
 	program := p.ParseProgram()
 	checkParserErrors(t, p)
 	env := object.NewEnvironment()
-	return Eval(program, env)
+	result := Eval(program, env)
+
+	// every test doubles as a check that the optimizer doesn't change what
+	// a program evaluates to.
+	optimized := optimizer.Optimize(parser.New(lexer.New(input)).ParseProgram())
+	if got := Eval(optimized, object.NewEnvironment()); !sameResult(result, got) {
+		t.Errorf("optimized program %q has a different result. want=%s, got=%s",
+			input, inspect(result), inspect(got))
+	}
+	return result
+}
+
+// helper function that evaluates input with ip, for the tests of the
+// settings of an interpreter, e.g. its streams or its policy.
+func evalWith(t *testing.T, ip *Interpreter, input string) object.Object {
+	t.Helper()
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+	return ip.Eval(program, object.NewEnvironment())
+}
+
+// helper function that reports whether two results of Eval are the same.
+// Functions are only compared by type since the optimizer changes their body.
+func sameResult(a, b object.Object) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	if a.Type() == object.FUNCTION_OBJ {
+		return b.Type() == object.FUNCTION_OBJ
+	}
+	return a.Type() == b.Type() && a.Inspect() == b.Inspect()
+}
+
+// helper function that inspects a result of Eval, which may be nil.
+func inspect(obj object.Object) string {
+	if obj == nil {
+		return "nil"
+	}
+	return obj.Inspect()
 }
 func testIntegerObject(t *testing.T, obj object.Object, expected int64) bool {
 	result, ok := obj.(*object.Integer)
@@ -226,6 +266,10 @@ func TestErrorHandling(t *testing.T) {
 			`"Hello" - "World"`,
 			"unknown operator: STRING - STRING",
 		},
+		{
+			"let zero = 0; 10 / zero",
+			"division by zero: 10 / 0",
+		},
 	}
 	for _, tt := range tests {
 		evaluated := testEval(t, tt.input)
@@ -485,3 +529,339 @@ func TestArrayIndexExpressions(t *testing.T) {
 		}
 	}
 }
+
+func TestEqualityExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"[1, 2] == [1, 2]", true},
+		{"[1, 2] != [1, 2]", false},
+		{"[1, [2, 3]] == [1, [2, 3]]", true},
+		{"[1, 2] == [1, 3]", false},
+		{"[1, 2] == [1, 2, 3]", false},
+		{"[] == []", true},
+		{`["a", true] == ["a", true]`, true},
+		{"1 == true", false},
+		{"1 != true", true},
+		{`1 == "1"`, false},
+		{`"1" != 1`, true},
+		{"[1] == 1", false},
+		{"if (false) { 1 } == if (false) { 2 }", true},
+		{"if (false) { 1 } == false", false},
+		{"let f = fn(x) { x }; f == f", true},
+		{"fn(x) { x } == fn(x) { x }", false},
+		{"len == len", true},
+		{"len == first", false},
+		{"let f = fn(x) { x }; [f] == [f]", true},
+		{`{"a": 1, "b": [2]} == {"b": [2], "a": 1}`, true},
+		{`{"a": 1} == {"a": 2}`, false},
+		{`{"a": 1} == {"a": 1, "b": 2}`, false},
+		{`{1: true} == {"1": true}`, false},
+		{"{} == {}", true},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(t, tt.input)
+		testBooleanObject(t, evaluated, tt.expected)
+	}
+}
+
+func TestTailCalls(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{
+			`let sum = fn(n, acc) { if (n == 0) { acc } else { sum(n - 1, acc + n) } };
+			sum(100000, 0);`,
+			5000050000,
+		},
+		{
+			`let sum = fn(n, acc) { if (n == 0) { return acc; } return sum(n - 1, acc + n); };
+			sum(100000, 0);`,
+			5000050000,
+		},
+		{
+			`let isEven = fn(n) { if (n == 0) { true } else { isOdd(n - 1) } };
+			let isOdd = fn(n) { if (n == 0) { false } else { isEven(n - 1) } };
+			if (isEven(100001)) { 1 } else { 0 }`,
+			0,
+		},
+		{
+			`let count = fn(n) { if (n == 0) { return 0; } 1 + count(n - 1) };
+			count(100);`,
+			100,
+		},
+		{
+			`let apply = fn(f, x) { f(x) };
+			apply(len, "four");`,
+			4,
+		},
+		{
+			`let loop = fn(n) { if (n > 0) { return loop(n - 1); } 42 };
+			return loop(100000);`,
+			42,
+		},
+	}
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestFunctionArguments(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let add = fn(a, b) { a + b }; add(1);", "wrong number of arguments. got=1, want=2"},
+		{"let add = fn(a, b) { a + b }; add(1, 2, 3);", "wrong number of arguments. got=3, want=2"},
+		{"fn() { 1 }(1)", "wrong number of arguments. got=1, want=0"},
+		{"let add = fn(a, b = 2) { a + b }; add(1);", 3},
+		{"let add = fn(a, b = 2) { a + b }; add(1, 5);", 6},
+		{"let add = fn(a, b = 2) { a + b }; add();", "wrong number of arguments. got=0, want=1 to 2"},
+		{"let add = fn(a, b = 2) { a + b }; add(1, 2, 3);", "wrong number of arguments. got=3, want=1 to 2"},
+		{"let f = fn(a, b = a * 2) { a + b }; f(3);", 9},
+		{"let f = fn(a = 1, b = a + 1) { a * b }; f();", 2},
+		{"let f = fn(a = foo) { a }; f();", "identifier not found: foo"},
+		{"let f = fn(a = foo) { a }; f(1);", 1},
+		{"let count = fn(...xs) { len(xs) }; count();", 0},
+		{"let count = fn(...xs) { len(xs) }; count(1, 2, 3);", 3},
+		{"let f = fn(a, ...xs) { a + len(xs) }; f(10, 2, 3);", 12},
+		{"let f = fn(a, ...xs) { a + len(xs) }; f();", "wrong number of arguments. got=0, want at least 1"},
+		{"let f = fn(a, b = 5, ...xs) { a + b + len(xs) }; f(1);", 6},
+		{"let f = fn(a, b = 5, ...xs) { a + b + len(xs) }; f(1, 1, 1, 1);", 4},
+		{"let add = fn(a, b) { a + b }; add(...[1, 2]);", 3},
+		{"let add = fn(a, b, c) { a + b + c }; add(1, ...[2, 3]);", 6},
+		{"let add = fn(a, b) { a + b }; add(...[1]);", "wrong number of arguments. got=1, want=2"},
+		{"let add = fn(a, b) { a + b }; add(...1);", "cannot spread INTEGER, want ARRAY"},
+		{"let sum = fn(...xs) { if (len(xs) == 0) { 0 } else { first(xs) + sum(...rest(xs)) } }; sum(1, 2, 3, 4);", 10},
+		{"len([0, ...[1, 2], ...[], 3])", 4},
+		{"[0, ...[1, 2], 3][2]", 2},
+		{"len(...[\"four\"])", 4},
+		{"...[1]", "spread is only allowed in call arguments and array literals"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(t, tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v), input: %s",
+					evaluated, evaluated, tt.input)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q",
+					expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestNamedFunctions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"fn fact(n) { if (n == 0) { 1 } else { n * fact(n - 1) } }; fact(5);", 120},
+		{"let f = fn fact(n) { if (n == 0) { 1 } else { n * fact(n - 1) } }; f(5);", 120},
+		{"let f = fn fact(n) { if (n == 0) { 1 } else { n * fact(n - 1) } }; fact(5);", "identifier not found: fact"},
+		{
+			`let f = fn fact(n) { if (n == 0) { 1 } else { n * fact(n - 1) } };
+			let g = f;
+			let f = 0;
+			g(4);`,
+			24,
+		},
+		{"fn add(a, b) { a + b }; add(1);", "wrong number of arguments to `add`. got=1, want=2"},
+		{"fn add(a, b = 1) { a + b }; add(1, 2, 3);", "wrong number of arguments to `add`. got=3, want=1 to 2"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(t, tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v), input: %s",
+					evaluated, evaluated, tt.input)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q",
+					expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestNamedFunctionInspect(t *testing.T) {
+	evaluated := testEval(t, "fn double(x) { x * 2 }")
+	fn, ok := evaluated.(*object.Function)
+	if !ok {
+		t.Fatalf("object is not Function. got=%T (%+v)", evaluated, evaluated)
+	}
+	if fn.Name != "double" {
+		t.Errorf("function has wrong name. got=%q", fn.Name)
+	}
+	expected := "fn double(x) {\n(x * 2)\n}"
+	if fn.Inspect() != expected {
+		t.Errorf("Inspect() wrong. want=%q, got=%q", expected, fn.Inspect())
+	}
+}
+
+func TestConstAndBlockScoping(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"const x = 5; x;", 5},
+		{"const x = 5; let x = 6; x;", "cannot overwrite constant x"},
+		{"const x = 5; const x = 6; x;", "cannot overwrite constant x"},
+		{"const x = 5; if (true) { let x = 6; x }", 6},
+		{"const x = 5; if (true) { let x = 6; } x", 5},
+		{"let x = 5; if (true) { let x = 6; } x", 5},
+		{"if (true) { let y = 6; } y", "identifier not found: y"},
+		{"if (false) { 1 } else { let y = 6; } y", "identifier not found: y"},
+		{"let f = fn() { const x = 1; let x = 2; }; f();", "cannot overwrite constant x"},
+		{"let x = 1; let x = 2; x;", 2},
+		{"fn f() { 1 }; const f = 2; f;", 2},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(t, tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v), input: %s",
+					evaluated, evaluated, tt.input)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q",
+					expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestStrictMode(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let x = 1; let x = 2; x;", "x is already declared in this scope"},
+		{"fn f() { 1 }; let f = 2;", "f is already declared in this scope"},
+		{"let x = 1; if (true) { let x = 2; x }", 2},
+		{"let f = fn(x) { let x = 2; x }; f(1);", "x is already declared in this scope"},
+		{"let f = fn(a, a) { a }; f(1, 2);", "a is already declared in this scope"},
+		{"let f = fn(x) { if (true) { let x = 2; x } }; f(1);", 2},
+	}
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := parser.New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+		evaluated := Eval(program, object.NewStrictEnvironment())
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v), input: %s",
+					evaluated, evaluated, tt.input)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q",
+					expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestHashLiterals(t *testing.T) {
+	input := `let two = "two";
+	{
+		"one": 10 - 9,
+		two: 1 + 1,
+		"thr" + "ee": 6 / 2,
+		4: 4,
+		true: 5,
+		false: 6
+	}`
+
+	evaluated := testEval(t, input)
+	result, ok := evaluated.(*object.Hash)
+	if !ok {
+		t.Fatalf("Eval didn't return Hash. got=%T (%+v)", evaluated, evaluated)
+	}
+
+	expected := map[object.HashKey]int64{
+		(&object.String{Value: "one"}).HashKey():   1,
+		(&object.String{Value: "two"}).HashKey():   2,
+		(&object.String{Value: "three"}).HashKey(): 3,
+		(&object.Integer{Value: 4}).HashKey():      4,
+		TRUE.HashKey():                             5,
+		FALSE.HashKey():                            6,
+	}
+	if len(result.Pairs) != len(expected) {
+		t.Fatalf("Hash has wrong num of pairs. got=%d", len(result.Pairs))
+	}
+	for expectedKey, expectedValue := range expected {
+		pair, ok := result.Pairs[expectedKey]
+		if !ok {
+			t.Errorf("no pair for given key in Pairs")
+			continue
+		}
+		testIntegerObject(t, pair.Value, expectedValue)
+	}
+
+	if result.Inspect() != "{false: 6, true: 5, 4: 4, one: 1, three: 3, two: 2}" {
+		t.Errorf("wrong Inspect. got=%q", result.Inspect())
+	}
+}
+
+func TestHashIndexExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`{"foo": 5}["foo"]`, 5},
+		{`{"foo": 5}["bar"]`, nil},
+		{`let key = "foo"; {"foo": 5}[key]`, 5},
+		{`{}["foo"]`, nil},
+		{`{5: 5}[5]`, 5},
+		{`{true: 5}[true]`, 5},
+		{`{false: 5}[false]`, 5},
+		{`{"a": {"b": [1, 2]}}["a"]["b"][1]`, 2},
+		{`{"name": "Monkey"}[fn(x) { x }];`, "unusable as hash key: FUNCTION"},
+		{`{[1]: 2}`, "unusable as hash key: ARRAY"},
+		{`{"a": 1}[{"a": 1}]`, "unusable as hash key: HASH"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(t, tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		default:
+			testNullObject(t, evaluated)
+		}
+	}
+}
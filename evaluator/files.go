@@ -0,0 +1,133 @@
+package evaluator
+
+import (
+	"errors"
+	"io/fs"
+	"path"
+
+	object "github.com/Artypuppet/monkey/object"
+	sandbox "github.com/Artypuppet/monkey/sandbox"
+)
+
+// method that returns the FS of ip and the path given as the argument of
+// the builtin called name, or an error if there is no FS or the argument is
+// not a string.
+func (ip *Interpreter) fileArgument(name string, arg object.Object) (sandbox.FS, string, *object.Error) {
+	if ip.FS == nil {
+		return nil, "", newError("%s: no file system", name)
+	}
+	s, ok := arg.(*object.String)
+	if !ok {
+		return nil, "", newError("argument to `%s` must be STRING, got %s", name, arg.Type())
+	}
+	return ip.FS, path.Clean(s.Value), nil
+}
+
+// method implementing read_file(path), which returns what the file holds.
+func (ip *Interpreter) builtinReadFile(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+	fsys, name, err := ip.fileArgument("read_file", args[0])
+	if err != nil {
+		return err
+	}
+	data, readErr := fs.ReadFile(fsys, name)
+	if readErr != nil {
+		return newError("read_file: %s", readErr)
+	}
+	return &object.String{Value: string(data)}
+}
+
+// method implementing write_file(path, content), which makes the file hold
+// content.
+func (ip *Interpreter) builtinWriteFile(args ...object.Object) object.Object {
+	return ip.writeFile("write_file", sandbox.FS.WriteFile, args)
+}
+
+// method implementing append_file(path, content), which adds content at the
+// end of the file.
+func (ip *Interpreter) builtinAppendFile(args ...object.Object) object.Object {
+	return ip.writeFile("append_file", sandbox.FS.AppendFile, args)
+}
+
+// method doing the work of the builtin called name, writing its second
+// argument to the file named by its first with write.
+func (ip *Interpreter) writeFile(name string, write func(fsys sandbox.FS, name string, data []byte) error, args []object.Object) object.Object {
+	if len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+	fsys, file, err := ip.fileArgument(name, args[0])
+	if err != nil {
+		return err
+	}
+	content, ok := args[1].(*object.String)
+	if !ok {
+		return newError("second argument to `%s` must be STRING, got %s", name, args[1].Type())
+	}
+	if err := write(fsys, file, []byte(content.Value)); err != nil {
+		return newError("%s: %s", name, err)
+	}
+	return NULL
+}
+
+// method implementing list_dir(path?), which returns the names in the
+// directory, "." if not given, in sorted order.
+func (ip *Interpreter) builtinListDir(args ...object.Object) object.Object {
+	if len(args) > 1 {
+		return newError("wrong number of arguments. got=%d, want=0 to 1", len(args))
+	}
+	var arg object.Object = &object.String{Value: "."}
+	if len(args) == 1 {
+		arg = args[0]
+	}
+	fsys, name, err := ip.fileArgument("list_dir", arg)
+	if err != nil {
+		return err
+	}
+	entries, readErr := fs.ReadDir(fsys, name)
+	if readErr != nil {
+		return newError("list_dir: %s", readErr)
+	}
+	names := make([]object.Object, len(entries))
+	for i, entry := range entries {
+		names[i] = &object.String{Value: entry.Name()}
+	}
+	return &object.Array{Elements: names}
+}
+
+// method implementing exists(path), which reports whether there is a file
+// or directory at path.
+func (ip *Interpreter) builtinExists(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+	fsys, name, err := ip.fileArgument("exists", args[0])
+	if err != nil {
+		return err
+	}
+	_, statErr := fs.Stat(fsys, name)
+	if errors.Is(statErr, fs.ErrNotExist) {
+		return FALSE
+	}
+	if statErr != nil {
+		return newError("exists: %s", statErr)
+	}
+	return TRUE
+}
+
+// method implementing remove(path), which removes the file or empty
+// directory.
+func (ip *Interpreter) builtinRemove(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+	fsys, name, err := ip.fileArgument("remove", args[0])
+	if err != nil {
+		return err
+	}
+	if err := fsys.Remove(name); err != nil {
+		return newError("remove: %s", err)
+	}
+	return NULL
+}
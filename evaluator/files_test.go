@@ -0,0 +1,91 @@
+package evaluator
+
+import (
+	"io/fs"
+	"testing"
+
+	object "github.com/Artypuppet/monkey/object"
+	sandbox "github.com/Artypuppet/monkey/sandbox"
+)
+
+// the policy of the tests, allowing to read and write files.
+var filePolicy = NewPolicy(object.FS_READ_CAP, object.FS_WRITE_CAP)
+
+func TestFileBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`read_file("data/in.csv")`, "a,b\n1,2\n"},
+		{`read_file("./data/../data/in.csv")`, "a,b\n1,2\n"},
+		{`write_file("out.txt", "x"); read_file("out.txt")`, "x"},
+		{`write_file("notes.txt", "new"); read_file("notes.txt")`, "new"},
+		{`append_file("notes.txt", "!"); append_file("notes.txt", "?"); read_file("notes.txt")`, "old!?"},
+		{`list_dir()`, []string{"data", "notes.txt"}},
+		{`list_dir("data")`, []string{"in.csv"}},
+		{`exists("notes.txt")`, true},
+		{`exists("data")`, true},
+		{`exists("missing")`, false},
+		{`remove("notes.txt"); exists("notes.txt")`, false},
+		{`read_file("missing")`, "read_file: open missing: file does not exist"},
+		{`read_file("../x")`, "read_file: open ../x: invalid argument"},
+		{`remove("data")`, "remove: remove data: directory not empty"},
+		{`read_file(1)`, "argument to `read_file` must be STRING, got INTEGER"},
+		{`write_file("a", 1)`, "second argument to `write_file` must be STRING, got INTEGER"},
+		{`write_file("a")`, "wrong number of arguments. got=1, want=2"},
+	}
+
+	for _, tt := range tests {
+		fsys := sandbox.NewMemFS(map[string]string{"data/in.csv": "a,b\n1,2\n", "notes.txt": "old"})
+		result := evalWith(t, &Interpreter{FS: fsys, Policy: filePolicy}, tt.input)
+		switch expected := tt.expected.(type) {
+		case string:
+			switch result := result.(type) {
+			case *object.String:
+				if result.Value != expected {
+					t.Errorf("%s: expected %q, got %q", tt.input, expected, result.Value)
+				}
+			case *object.Error:
+				if result.Message != expected {
+					t.Errorf("%s: expected error %q, got %q", tt.input, expected, result.Message)
+				}
+			default:
+				t.Errorf("%s: unexpected result %s", tt.input, result.Inspect())
+			}
+		case bool:
+			testBooleanObject(t, result, expected)
+		case []string:
+			arr, ok := result.(*object.Array)
+			if !ok || len(arr.Elements) != len(expected) {
+				t.Errorf("%s: expected %v, got %s", tt.input, expected, result.Inspect())
+				continue
+			}
+			for i, name := range expected {
+				testStringObject(t, arr.Elements[i], name)
+			}
+		}
+	}
+}
+
+func TestFileBuiltinsPolicy(t *testing.T) {
+	memFS := sandbox.NewMemFS(map[string]string{"a.txt": "a"})
+	fsys := sandbox.Restrict(memFS, sandbox.ReadOnly)
+
+	if result := evalWith(t, &Interpreter{FS: fsys, Policy: filePolicy}, `read_file("a.txt")`); result.Inspect() != "a" {
+		t.Errorf("reading a file failed: %s", result.Inspect())
+	}
+	result := evalWith(t, &Interpreter{FS: fsys, Policy: filePolicy}, `write_file("a.txt", "b")`)
+	if err, ok := result.(*object.Error); !ok || err.Message != "write_file: write a.txt: permission denied" {
+		t.Errorf("expected a permission error, got %s", result.Inspect())
+	}
+	if data, _ := fs.ReadFile(memFS, "a.txt"); string(data) != "a" {
+		t.Errorf("file changed to %q", data)
+	}
+}
+
+func TestFileBuiltinsWithoutFS(t *testing.T) {
+	result := evalWith(t, &Interpreter{Policy: filePolicy}, `exists("a.txt")`)
+	if err, ok := result.(*object.Error); !ok || err.Message != "exists: no file system" {
+		t.Errorf("expected an error, got %s", result.Inspect())
+	}
+}
@@ -0,0 +1,64 @@
+package evaluator
+
+import (
+	ast "github.com/Artypuppet/monkey/ast"
+	object "github.com/Artypuppet/monkey/object"
+)
+
+// interface implemented by tools that follow an evaluation step by step,
+// like a debugger. The methods are called on the goroutine doing the
+// evaluation, which waits for them to return.
+type Hook interface {
+	// Before is called before a statement or an expression is evaluated in
+	// env, and before the arm an if expression takes. If it returns an error
+	// the evaluation stops with that error.
+	Before(node ast.Node, env *object.Environment) *object.Error
+	// EnterCall is called when a call to fn starts, env being the environment
+	// holding its parameters.
+	EnterCall(fn *object.Function, args []object.Object, env *object.Environment)
+	// ExitCall is called when the call to fn is done. result is nil if the
+	// call ended in a tail call, which takes its place on the call stack.
+	ExitCall(fn *object.Function, result object.Object)
+}
+
+// helper methods that call the hook if there is one.
+
+func (ip *Interpreter) before(node ast.Node, env *object.Environment) *object.Error {
+	if ip.Hook == nil || node == nil {
+		return nil
+	}
+	return ip.Hook.Before(node, env)
+}
+
+func (ip *Interpreter) enterCall(fn *object.Function, args []object.Object, env *object.Environment) {
+	if ip.Hook != nil {
+		ip.Hook.EnterCall(fn, args, env)
+	}
+}
+
+func (ip *Interpreter) exitCall(fn *object.Function, result object.Object) {
+	if ip.Hook == nil {
+		return
+	}
+	if _, ok := result.(*tailCall); ok {
+		result = nil
+	}
+	ip.Hook.ExitCall(fn, result)
+}
+
+// function that returns the name tools should show for fn. Functions bound
+// with let have no name of their own so the environment they were made in
+// is searched for them.
+func FunctionName(fn *object.Function) string {
+	if fn.Name != "" {
+		return fn.Name
+	}
+	for env := fn.Env; env != nil; env = env.Outer() {
+		for _, name := range env.Names() {
+			if obj, _ := env.Get(name); obj == fn {
+				return name
+			}
+		}
+	}
+	return "<anonymous>"
+}
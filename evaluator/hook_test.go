@@ -0,0 +1,98 @@
+package evaluator
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	ast "github.com/Artypuppet/monkey/ast"
+	lexer "github.com/Artypuppet/monkey/lexer"
+	object "github.com/Artypuppet/monkey/object"
+	parser "github.com/Artypuppet/monkey/parser"
+)
+
+// struct defining a hook that records what it is told and stops the
+// evaluation at the node with the String stopAt.
+type recordingHook struct {
+	events []string
+	stopAt string
+}
+
+func (h *recordingHook) Before(node ast.Node, env *object.Environment) *object.Error {
+	h.events = append(h.events, fmt.Sprintf("%T %s", node, node))
+	if node.String() == h.stopAt {
+		return &object.Error{Message: "stopped"}
+	}
+	return nil
+}
+
+func (h *recordingHook) EnterCall(fn *object.Function, args []object.Object, env *object.Environment) {
+	h.events = append(h.events, fmt.Sprintf("enter %s %v", fn.Name, env.Names()))
+}
+
+func (h *recordingHook) ExitCall(fn *object.Function, result object.Object) {
+	if result == nil {
+		h.events = append(h.events, fmt.Sprintf("exit %s by tail call", fn.Name))
+		return
+	}
+	h.events = append(h.events, fmt.Sprintf("exit %s %s", fn.Name, result.Inspect()))
+}
+
+func TestHook(t *testing.T) {
+	input := `fn f(n) { if (n > 0) { f(n - 1) } else { -n } }
+let x = f(1);`
+	expected := []string{
+		"*ast.ExpressionStatement fn f(n) if(n > 0) f((n - 1))else (-n)",
+		"*ast.FunctionLiteral fn f(n) if(n > 0) f((n - 1))else (-n)",
+		"*ast.LetStatement let x = f(1);",
+		"*ast.CallExpression f(1)",
+		"*ast.Identifier f",
+		"*ast.IntegerLiteral 1",
+		"enter f [n]",
+		"*ast.ExpressionStatement if(n > 0) f((n - 1))else (-n)",
+		"*ast.IfExpression if(n > 0) f((n - 1))else (-n)",
+		"*ast.InfixExpression (n > 0)",
+		"*ast.Identifier n",
+		"*ast.IntegerLiteral 0",
+		"*ast.BlockStatement f((n - 1))",
+		"*ast.ExpressionStatement f((n - 1))",
+		"*ast.CallExpression f((n - 1))",
+		"*ast.Identifier f",
+		"*ast.InfixExpression (n - 1)",
+		"*ast.Identifier n",
+		"*ast.IntegerLiteral 1",
+		"exit f by tail call",
+		"enter f [n]",
+		"*ast.ExpressionStatement if(n > 0) f((n - 1))else (-n)",
+		"*ast.IfExpression if(n > 0) f((n - 1))else (-n)",
+		"*ast.InfixExpression (n > 0)",
+		"*ast.Identifier n",
+		"*ast.IntegerLiteral 0",
+		"*ast.BlockStatement (-n)",
+		"*ast.ExpressionStatement (-n)",
+		"*ast.PrefixExpression (-n)",
+		"*ast.Identifier n",
+		"exit f 0",
+	}
+
+	program := parser.New(lexer.New(input)).ParseProgram()
+	hook := &recordingHook{}
+	ip := &Interpreter{Hook: hook}
+	env := object.NewEnvironment()
+	ip.Eval(program, env)
+	if !reflect.DeepEqual(hook.events, expected) {
+		t.Errorf("wrong events.\nexpected=%s\ngot=     %s", strings.Join(expected, "\n          "), strings.Join(hook.events, "\n          "))
+	}
+	testIntegerObject(t, Eval(&ast.Identifier{Value: "x"}, env), 0)
+
+	// an error from Before stops the evaluation.
+	hook = &recordingHook{stopAt: "(n - 1)"}
+	result := (&Interpreter{Hook: hook}).Eval(program, object.NewEnvironment())
+	if err, ok := result.(*object.Error); !ok || err.Message != "stopped" {
+		t.Errorf("expected the evaluation to stop. got=%s", result.Inspect())
+	}
+	if last := hook.events[len(hook.events)-1]; last != "exit f ERROR: stopped" {
+		t.Errorf("expected the call to exit with the error. got=%q", last)
+	}
+}
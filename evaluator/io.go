@@ -0,0 +1,246 @@
+package evaluator
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	object "github.com/Artypuppet/monkey/object"
+)
+
+// struct defining a builtin needing the interpreter running it, like the
+// ones using its Stdout, Stdin and FS, and the capabilities it needs.
+type boundBuiltin struct {
+	fn           func(ip *Interpreter, args ...object.Object) object.Object
+	capabilities []object.Capability
+}
+
+// map from the name of a builtin needing the interpreter to its definition.
+// The builtin an interpreter hands out is a closure over it, see
+// interpreterBuiltin.
+var interpreterBuiltins = map[string]boundBuiltin{
+	"puts":     {fn: (*Interpreter).builtinPuts},
+	"print":    {fn: (*Interpreter).builtinPrint},
+	"println":  {fn: (*Interpreter).builtinPrintln},
+	"input":    {fn: (*Interpreter).builtinInput},
+	"readline": {fn: (*Interpreter).builtinReadline},
+
+	"read_file":   {(*Interpreter).builtinReadFile, []object.Capability{object.FS_READ_CAP}},
+	"write_file":  {(*Interpreter).builtinWriteFile, []object.Capability{object.FS_WRITE_CAP}},
+	"append_file": {(*Interpreter).builtinAppendFile, []object.Capability{object.FS_WRITE_CAP}},
+	"list_dir":    {(*Interpreter).builtinListDir, []object.Capability{object.FS_READ_CAP}},
+	"exists":      {(*Interpreter).builtinExists, []object.Capability{object.FS_READ_CAP}},
+	"remove":      {(*Interpreter).builtinRemove, []object.Capability{object.FS_WRITE_CAP}},
+}
+
+// method that returns the builtin of ip called name, nil if there is none.
+// The builtin is made once per interpreter so that it is always the same
+// object.
+func (ip *Interpreter) interpreterBuiltin(name string) *object.Builtin {
+	def, ok := interpreterBuiltins[name]
+	if !ok {
+		return nil
+	}
+	if builtin, ok := ip.bound[name]; ok {
+		return builtin
+	}
+	if ip.bound == nil {
+		ip.bound = make(map[string]*object.Builtin)
+	}
+	builtin := &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			return def.fn(ip, args...)
+		},
+		Name:         name,
+		Capabilities: def.capabilities,
+	}
+	ip.bound[name] = builtin
+	return builtin
+}
+
+// methods returning the streams of ip, the ones of the process if they are
+// not set. The reader is buffered once so that no input is lost between
+// calls.
+
+func (ip *Interpreter) stdout() io.Writer {
+	if ip.Stdout == nil {
+		return os.Stdout
+	}
+	return ip.Stdout
+}
+
+func (ip *Interpreter) stdin() *bufio.Reader {
+	if ip.stdinReader == nil {
+		var in io.Reader = os.Stdin
+		if ip.Stdin != nil {
+			in = ip.Stdin
+		}
+		if r, ok := in.(*bufio.Reader); ok {
+			ip.stdinReader = r
+		} else {
+			ip.stdinReader = bufio.NewReader(in)
+		}
+	}
+	return ip.stdinReader
+}
+
+// ----------------------------------Output-----------------------------------
+
+// method implementing puts(args...), which writes every argument on a line
+// of its own.
+func (ip *Interpreter) builtinPuts(args ...object.Object) object.Object {
+	var out strings.Builder
+	for _, arg := range args {
+		out.WriteString(arg.Inspect() + "\n")
+	}
+	return ip.write("puts", out.String())
+}
+
+// method implementing print(args...), which writes its arguments separated
+// by spaces.
+func (ip *Interpreter) builtinPrint(args ...object.Object) object.Object {
+	return ip.write("print", joinArguments(args))
+}
+
+// method implementing println(args...), which is print followed by a newline.
+func (ip *Interpreter) builtinPrintln(args ...object.Object) object.Object {
+	return ip.write("println", joinArguments(args)+"\n")
+}
+
+func joinArguments(args []object.Object) string {
+	parts := make([]string, len(args))
+	for i, arg := range args {
+		parts[i] = arg.Inspect()
+	}
+	return strings.Join(parts, " ")
+}
+
+// method that writes s to the Stdout of ip for the builtin called name.
+func (ip *Interpreter) write(name, s string) object.Object {
+	if _, err := io.WriteString(ip.stdout(), s); err != nil {
+		return newError("%s: %s", name, err)
+	}
+	return NULL
+}
+
+// ----------------------------------Format-----------------------------------
+
+// function implementing format(template, args...), which returns template
+// with its verbs replaced by the arguments like Go's fmt.Sprintf. The verbs
+// are %s and %v for any value, %d for integers, %t for booleans, %q for
+// strings, quoted, and %% for a percent sign. Flags and widths go between
+// the % and the verb, e.g. %-5d.
+func builtinFormat(args ...object.Object) object.Object {
+	if len(args) < 1 {
+		return newError("wrong number of arguments. got=%d, want at least 1", len(args))
+	}
+	template, ok := args[0].(*object.String)
+	if !ok {
+		return newError("first argument to `format` must be STRING, got %s", args[0].Type())
+	}
+	args = args[1:]
+
+	var out strings.Builder
+	s := template.Value
+	used := 0
+	for i := 0; i < len(s); i++ {
+		if s[i] != '%' {
+			out.WriteByte(s[i])
+			continue
+		}
+		// the verb is the first letter after the flags and width.
+		start := i
+		for i++; i < len(s) && strings.IndexByte("+-# 0123456789.", s[i]) >= 0; i++ {
+		}
+		if i == len(s) {
+			return newError("format: unfinished verb %s", s[start:])
+		}
+		spec, verb := s[start:i], s[i]
+		if verb == '%' {
+			out.WriteByte('%')
+			continue
+		}
+		if used == len(args) {
+			return newError("format: missing argument for %s", s[start:i+1])
+		}
+		arg := args[used]
+		used++
+		value, err := formatArgument(verb, arg)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(&out, spec+string(verb), value)
+	}
+	if used < len(args) {
+		return newError("format: %d arguments left over", len(args)-used)
+	}
+	return &object.String{Value: out.String()}
+}
+
+// function that returns the Go value arg is formatted as by verb, or an
+// error if verb does not take values of its type.
+func formatArgument(verb byte, arg object.Object) (interface{}, *object.Error) {
+	switch verb {
+	case 's', 'v':
+		return arg.Inspect(), nil
+	case 'd':
+		if i, ok := arg.(*object.Integer); ok {
+			return i.Value, nil
+		}
+	case 't':
+		if b, ok := arg.(*object.Boolean); ok {
+			return b.Value, nil
+		}
+	case 'q':
+		if s, ok := arg.(*object.String); ok {
+			return s.Value, nil
+		}
+	default:
+		return nil, newError("format: unknown verb %%%c", verb)
+	}
+	return nil, newError("format: %%%c can't format %s", verb, arg.Type())
+}
+
+// ----------------------------------Input------------------------------------
+
+// method implementing input(prompt?), which writes the prompt if there is
+// one and reads a line.
+func (ip *Interpreter) builtinInput(args ...object.Object) object.Object {
+	if len(args) > 1 {
+		return newError("wrong number of arguments. got=%d, want=0 to 1", len(args))
+	}
+	if len(args) == 1 {
+		prompt, ok := args[0].(*object.String)
+		if !ok {
+			return newError("argument to `input` must be STRING, got %s", args[0].Type())
+		}
+		if err := ip.write("input", prompt.Value); isError(err) {
+			return err
+		}
+	}
+	return ip.readLine("input")
+}
+
+// method implementing readline(), which reads a line.
+func (ip *Interpreter) builtinReadline(args ...object.Object) object.Object {
+	if len(args) != 0 {
+		return newError("wrong number of arguments. got=%d, want=0", len(args))
+	}
+	return ip.readLine("readline")
+}
+
+// method that reads a line from the Stdin of ip for the builtin called name.
+// The line is returned without its line ending, and the end of the input is
+// null.
+func (ip *Interpreter) readLine(name string) object.Object {
+	line, err := ip.stdin().ReadString('\n')
+	if err == io.EOF && line == "" {
+		return NULL
+	}
+	if err != nil && err != io.EOF {
+		return newError("%s: %s", name, err)
+	}
+	return &object.String{Value: strings.TrimRight(line, "\r\n")}
+}
@@ -0,0 +1,101 @@
+package evaluator
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	object "github.com/Artypuppet/monkey/object"
+)
+
+func TestOutputBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`puts("a", 1, [true])`, "a\n1\n[true]\n"},
+		{`puts()`, ""},
+		{`print("a", 1); print("b")`, "a 1b"},
+		{`println("x =", {"k": 2})`, "x = {k: 2}\n"},
+		{`println()`, "\n"},
+		{`let p = print; p(format("%d%%", 50))`, "50%"},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		result := evalWith(t, &Interpreter{Stdout: &out}, tt.input)
+		if result != NULL {
+			t.Errorf("%s: expected null, got %s", tt.input, result.Inspect())
+		}
+		if out.String() != tt.expected {
+			t.Errorf("%s: wrong output. expected=%q, got=%q", tt.input, tt.expected, out.String())
+		}
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+func TestOutputError(t *testing.T) {
+	result := evalWith(t, &Interpreter{Stdout: failingWriter{}}, `println("a")`)
+	if err, ok := result.(*object.Error); !ok || err.Message != "println: disk full" {
+		t.Errorf("wrong result: %s", result.Inspect())
+	}
+}
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`format("plain")`, "plain"},
+		{`format("%s and %v", "a", [1, "b"])`, "a and [1, b]"},
+		{`format("%5d|%-5d|%05d", 42, 42, -42)`, "   42|42   |-0042"},
+		{`format("%t %q", true, "hi")`, `true "hi"`},
+		{`format("100%%")`, "100%"},
+		{`format("%d", "a")`, "ERROR: format: %d can't format STRING"},
+		{`format("%d %d", 1)`, "ERROR: format: missing argument for %d"},
+		{`format("%d", 1, 2)`, "ERROR: format: 1 arguments left over"},
+		{`format("%x", 1)`, "ERROR: format: unknown verb %x"},
+		{`format("50%")`, "ERROR: format: unfinished verb %"},
+		{`format(1)`, "ERROR: first argument to `format` must be STRING, got INTEGER"},
+		{`format()`, "ERROR: wrong number of arguments. got=0, want at least 1"},
+	}
+
+	for _, tt := range tests {
+		if result := testEval(t, tt.input); result.Inspect() != tt.expected {
+			t.Errorf("%s: expected=%q, got=%q", tt.input, tt.expected, result.Inspect())
+		}
+	}
+}
+
+func TestInputBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		stdin    string
+		expected string
+		output   string
+	}{
+		{`input("name? ")`, "Ann\nrest\n", "Ann", "name? "},
+		{`input()`, "a line\r\n", "a line", ""},
+		{`[readline(), readline(), readline()]`, "one\ntwo", "[one, two, null]", ""},
+		{`readline()`, "", "null", ""},
+		{`readline(1)`, "", "ERROR: wrong number of arguments. got=1, want=0", ""},
+		{`input(1)`, "", "ERROR: argument to `input` must be STRING, got INTEGER", ""},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		result := evalWith(t, &Interpreter{Stdout: &out, Stdin: strings.NewReader(tt.stdin)}, tt.input)
+		if result.Inspect() != tt.expected {
+			t.Errorf("%s: expected=%q, got=%q", tt.input, tt.expected, result.Inspect())
+		}
+		if out.String() != tt.output {
+			t.Errorf("%s: wrong output. expected=%q, got=%q", tt.input, tt.output, out.String())
+		}
+	}
+}
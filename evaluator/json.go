@@ -0,0 +1,355 @@
+package evaluator
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	object "github.com/Artypuppet/monkey/object"
+)
+
+// ---------------------------------Decoding----------------------------------
+
+// function implementing the json_parse builtin.
+// It turns a JSON document into Monkey objects: objects become hashes with
+// string keys, arrays become arrays and null becomes NULL. Monkey only has
+// integers so a number with a fraction or an exponent is an error.
+func jsonParse(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+	str, ok := args[0].(*object.String)
+	if !ok {
+		return newError("argument to `json_parse` must be STRING, got %s", args[0].Type())
+	}
+
+	d := &decoder{input: str.Value}
+	d.skipSpace()
+	value := d.value()
+	if d.err != nil {
+		return d.err
+	}
+	d.skipSpace()
+	if d.pos < len(d.input) {
+		return d.errorf("invalid character %s after top-level value", d.quoteChar())
+	}
+	return value
+}
+
+// struct holding the state of json_parse. pos is a byte offset into input
+// and err is the first error found, after which decoding stops.
+type decoder struct {
+	input string
+	pos   int
+	err   *object.Error
+}
+
+// method that records an error at the current offset and returns it.
+func (d *decoder) errorf(format string, a ...interface{}) *object.Error {
+	if d.err == nil {
+		d.err = newError("json_parse: %s at offset %d", fmt.Sprintf(format, a...), d.pos)
+	}
+	return d.err
+}
+
+// helper method that quotes the character at the current offset for errors.
+func (d *decoder) quoteChar() string {
+	return strconv.QuoteRune(rune(d.input[d.pos]))
+}
+
+func (d *decoder) skipSpace() {
+	for d.pos < len(d.input) {
+		switch d.input[d.pos] {
+		case ' ', '\t', '\n', '\r':
+			d.pos++
+		default:
+			return
+		}
+	}
+}
+
+// method that decodes the value starting at the current offset.
+func (d *decoder) value() object.Object {
+	if d.pos >= len(d.input) {
+		return d.errorf("unexpected end of input")
+	}
+	switch c := d.input[d.pos]; {
+	case c == '{':
+		return d.object()
+	case c == '[':
+		return d.array()
+	case c == '"':
+		if s, ok := d.string(); ok {
+			return &object.String{Value: s}
+		}
+		return d.err
+	case c == '-' || ('0' <= c && c <= '9'):
+		return d.number()
+	case c == 't':
+		return d.literal("true", TRUE)
+	case c == 'f':
+		return d.literal("false", FALSE)
+	case c == 'n':
+		return d.literal("null", NULL)
+	}
+	return d.errorf("invalid character %s looking for beginning of value", d.quoteChar())
+}
+
+// method that decodes true, false or null.
+func (d *decoder) literal(word string, value object.Object) object.Object {
+	for i := 0; i < len(word); i++ {
+		if d.pos >= len(d.input) {
+			return d.errorf("unexpected end of input")
+		}
+		if d.input[d.pos] != word[i] {
+			return d.errorf("invalid character %s in literal %s", d.quoteChar(), word)
+		}
+		d.pos++
+	}
+	return value
+}
+
+// method that decodes a number, which has to be an integer that fits in an int64.
+func (d *decoder) number() object.Object {
+	start := d.pos
+	if d.input[d.pos] == '-' {
+		d.pos++
+	}
+	switch {
+	case d.pos >= len(d.input):
+		return d.errorf("unexpected end of input")
+	case d.input[d.pos] == '0':
+		// a leading zero is a number on its own.
+		d.pos++
+	case '1' <= d.input[d.pos] && d.input[d.pos] <= '9':
+		for d.pos < len(d.input) && '0' <= d.input[d.pos] && d.input[d.pos] <= '9' {
+			d.pos++
+		}
+	default:
+		return d.errorf("invalid character %s in numeric literal", d.quoteChar())
+	}
+	if d.pos < len(d.input) {
+		if c := d.input[d.pos]; c == '.' || c == 'e' || c == 'E' {
+			d.pos = start
+			return d.errorf("number is not an integer")
+		}
+	}
+
+	literal := d.input[start:d.pos]
+	value, err := strconv.ParseInt(literal, 10, 64)
+	if err != nil {
+		d.pos = start
+		return d.errorf("number %s out of range", literal)
+	}
+	return &object.Integer{Value: value}
+}
+
+// method that decodes a string. It finds the closing quote and leaves the
+// escape sequences to encoding/json.
+func (d *decoder) string() (string, bool) {
+	start := d.pos
+	d.pos++
+	for {
+		if d.pos >= len(d.input) {
+			d.errorf("unexpected end of input")
+			return "", false
+		}
+		switch c := d.input[d.pos]; {
+		case c == '"':
+			d.pos++
+			var s string
+			if err := json.Unmarshal([]byte(d.input[start:d.pos]), &s); err != nil {
+				d.pos = start
+				d.errorf("invalid string: %s", err)
+				return "", false
+			}
+			return s, true
+		case c == '\\':
+			d.pos += 2
+		case c < ' ':
+			d.errorf("invalid character %s in string literal", d.quoteChar())
+			return "", false
+		default:
+			d.pos++
+		}
+	}
+}
+
+// method that decodes an array.
+func (d *decoder) array() object.Object {
+	d.pos++
+	elements := []object.Object{}
+	d.skipSpace()
+	if d.pos < len(d.input) && d.input[d.pos] == ']' {
+		d.pos++
+		return &object.Array{Elements: elements}
+	}
+	for {
+		d.skipSpace()
+		value := d.value()
+		if d.err != nil {
+			return d.err
+		}
+		elements = append(elements, value)
+
+		d.skipSpace()
+		if d.pos >= len(d.input) {
+			return d.errorf("unexpected end of input")
+		}
+		switch d.input[d.pos] {
+		case ',':
+			d.pos++
+		case ']':
+			d.pos++
+			return &object.Array{Elements: elements}
+		default:
+			return d.errorf("invalid character %s after array element", d.quoteChar())
+		}
+	}
+}
+
+// method that decodes an object into a hash. A key that appears twice keeps
+// its last value.
+func (d *decoder) object() object.Object {
+	d.pos++
+	pairs := make(map[object.HashKey]object.HashPair)
+	d.skipSpace()
+	if d.pos < len(d.input) && d.input[d.pos] == '}' {
+		d.pos++
+		return &object.Hash{Pairs: pairs}
+	}
+	for {
+		d.skipSpace()
+		if d.pos >= len(d.input) {
+			return d.errorf("unexpected end of input")
+		}
+		if d.input[d.pos] != '"' {
+			return d.errorf("invalid character %s looking for beginning of object key string", d.quoteChar())
+		}
+		s, ok := d.string()
+		if !ok {
+			return d.err
+		}
+		key := &object.String{Value: s}
+
+		d.skipSpace()
+		if d.pos >= len(d.input) {
+			return d.errorf("unexpected end of input")
+		}
+		if d.input[d.pos] != ':' {
+			return d.errorf("invalid character %s after object key", d.quoteChar())
+		}
+		d.pos++
+		d.skipSpace()
+		value := d.value()
+		if d.err != nil {
+			return d.err
+		}
+		pairs[key.HashKey()] = object.HashPair{Key: key, Value: value}
+
+		d.skipSpace()
+		if d.pos >= len(d.input) {
+			return d.errorf("unexpected end of input")
+		}
+		switch d.input[d.pos] {
+		case ',':
+			d.pos++
+		case '}':
+			d.pos++
+			return &object.Hash{Pairs: pairs}
+		default:
+			return d.errorf("invalid character %s after object key:value pair", d.quoteChar())
+		}
+	}
+}
+
+// ---------------------------------Encoding----------------------------------
+
+// function implementing the json_stringify builtin.
+// It encodes a value as compact JSON, or indented by two spaces if the
+// optional second argument is true. Hash keys are written in the order of
+// Inspect and integer and boolean keys become strings, it is an error if two
+// keys become the same string. Functions and builtins have no JSON form and
+// are errors.
+func jsonStringify(args ...object.Object) object.Object {
+	if len(args) < 1 || len(args) > 2 {
+		return newError("wrong number of arguments. got=%d, want=1 to 2", len(args))
+	}
+	pretty := false
+	if len(args) == 2 {
+		b, ok := args[1].(*object.Boolean)
+		if !ok {
+			return newError("second argument to `json_stringify` must be BOOLEAN, got %s", args[1].Type())
+		}
+		pretty = b.Value
+	}
+
+	var buf bytes.Buffer
+	if err := encodeJSON(&buf, args[0]); err != nil {
+		return err
+	}
+	if pretty {
+		var out bytes.Buffer
+		json.Indent(&out, buf.Bytes(), "", "  ")
+		return &object.String{Value: out.String()}
+	}
+	return &object.String{Value: buf.String()}
+}
+
+// helper function that writes obj to buf as compact JSON.
+func encodeJSON(buf *bytes.Buffer, obj object.Object) *object.Error {
+	switch obj := obj.(type) {
+	case *object.Integer:
+		buf.WriteString(strconv.FormatInt(obj.Value, 10))
+	case *object.Boolean:
+		buf.WriteString(strconv.FormatBool(obj.Value))
+	case *object.Null:
+		buf.WriteString("null")
+	case *object.String:
+		encodeJSONString(buf, obj.Value)
+	case *object.Array:
+		buf.WriteByte('[')
+		for i, el := range obj.Elements {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := encodeJSON(buf, el); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	case *object.Hash:
+		buf.WriteByte('{')
+		seen := make(map[string]bool, len(obj.Pairs))
+		for i, pair := range obj.SortedPairs() {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			key := pair.Key.Inspect()
+			if seen[key] {
+				return newError("json_stringify: duplicate key %q", key)
+			}
+			seen[key] = true
+			encodeJSONString(buf, key)
+			buf.WriteByte(':')
+			if err := encodeJSON(buf, pair.Value); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	default:
+		return newError("json_stringify: cannot encode %s", obj.Type())
+	}
+	return nil
+}
+
+// helper function that writes s to buf as a JSON string. Unlike the default
+// of encoding/json, <, > and & are left as they are.
+func encodeJSONString(buf *bytes.Buffer, s string) {
+	var out bytes.Buffer
+	enc := json.NewEncoder(&out)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	buf.Write(bytes.TrimSuffix(out.Bytes(), []byte("\n")))
+}
@@ -0,0 +1,117 @@
+package evaluator
+
+import (
+	"testing"
+
+	object "github.com/Artypuppet/monkey/object"
+)
+
+func TestJSONParse(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`1`, "1"},
+		{` -42 `, "-42"},
+		{`"a\"bé\n"`, "a\"bé\n"},
+		{`true`, "true"},
+		{`null`, "null"},
+		{`[]`, "[]"},
+		{`[1, [true, false], "x"]`, "[1, [true, false], x]"},
+		{`{}`, "{}"},
+		{`{"b": {"c": null}, "a": [1], "a": 2}`, "{a: 2, b: {c: null}}"},
+	}
+	for _, tt := range tests {
+		result := jsonParse(&object.String{Value: tt.input})
+		if result.Inspect() != tt.expected {
+			t.Errorf("json_parse(%q) wrong. expected=%q, got=%q", tt.input, tt.expected, result.Inspect())
+		}
+	}
+}
+
+func TestJSONParseErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{``, "json_parse: unexpected end of input at offset 0"},
+		{`[1, 2`, "json_parse: unexpected end of input at offset 5"},
+		{`[1,]`, "json_parse: invalid character ']' looking for beginning of value at offset 3"},
+		{`{"a" 1}`, "json_parse: invalid character '1' after object key at offset 5"},
+		{`{1: 2}`, "json_parse: invalid character '1' looking for beginning of object key string at offset 1"},
+		{`[1 2]`, "json_parse: invalid character '2' after array element at offset 3"},
+		{`tru`, "json_parse: unexpected end of input at offset 3"},
+		{`nul!`, "json_parse: invalid character '!' in literal null at offset 3"},
+		{`1 2`, "json_parse: invalid character '2' after top-level value at offset 2"},
+		{`012`, "json_parse: invalid character '1' after top-level value at offset 1"},
+		{`[1.5]`, "json_parse: number is not an integer at offset 1"},
+		{`99999999999999999999`, "json_parse: number 99999999999999999999 out of range at offset 0"},
+		{`"a` + "\n" + `"`, "json_parse: invalid character '\\n' in string literal at offset 2"},
+	}
+	for _, tt := range tests {
+		result := jsonParse(&object.String{Value: tt.input})
+		errObj, ok := result.(*object.Error)
+		if !ok {
+			t.Errorf("json_parse(%q) is not Error. got=%T (%+v)", tt.input, result, result)
+			continue
+		}
+		if errObj.Message != tt.expected {
+			t.Errorf("wrong error message. expected=%q, got=%q", tt.expected, errObj.Message)
+		}
+	}
+
+	result := testEval(t, `json_parse(1)`)
+	if errObj, ok := result.(*object.Error); !ok || errObj.Message != "argument to `json_parse` must be STRING, got INTEGER" {
+		t.Errorf("wrong result for json_parse(1). got=%s", result.Inspect())
+	}
+}
+
+func TestJSONStringify(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`json_stringify(-5)`, `-5`},
+		{`json_stringify("<a>&")`, `"<a>&"`},
+		{`json_stringify([1, true, "x", first([])])`, `[1,true,"x",null]`},
+		{`json_stringify({"b": [], 1: {}, true: "t"})`, `{"true":"t","1":{},"b":[]}`},
+		{`json_stringify({"a": [1, 2], "b": {}}, true)`, "{\n  \"a\": [\n    1,\n    2\n  ],\n  \"b\": {}\n}"},
+		{`json_stringify([], false)`, `[]`},
+		{`json_stringify({1: "a", "1": "b"})`, `json_stringify: duplicate key "1"`},
+		{`json_stringify([{true: 1, "true": 2}])`, `json_stringify: duplicate key "true"`},
+		{`json_stringify(fn(x) { x })`, "json_stringify: cannot encode FUNCTION"},
+		{`json_stringify([1, len])`, "json_stringify: cannot encode BUILTIN"},
+		{`json_stringify(1, 2)`, "second argument to `json_stringify` must be BOOLEAN, got INTEGER"},
+		{`json_stringify()`, "wrong number of arguments. got=0, want=1 to 2"},
+	}
+	for _, tt := range tests {
+		result := testEval(t, tt.input)
+		switch result := result.(type) {
+		case *object.String:
+			if result.Value != tt.expected {
+				t.Errorf("%s wrong. expected=%q, got=%q", tt.input, tt.expected, result.Value)
+			}
+		case *object.Error:
+			if result.Message != tt.expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", tt.expected, result.Message)
+			}
+		default:
+			t.Errorf("%s is not String or Error. got=%T (%+v)", tt.input, result, result)
+		}
+	}
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	input := `{"list":[1,-2,{"nested":true}],"name":"mon\"key\\","none":null}`
+	parsed := jsonParse(&object.String{Value: input})
+	if isError(parsed) {
+		t.Fatalf("json_parse failed: %s", parsed.Inspect())
+	}
+	result, ok := jsonStringify(parsed).(*object.String)
+	if !ok {
+		t.Fatalf("json_stringify failed")
+	}
+	if result.Value != input {
+		t.Errorf("round trip wrong. expected=%q, got=%q", input, result.Value)
+	}
+}
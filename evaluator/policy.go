@@ -0,0 +1,85 @@
+package evaluator
+
+import (
+	"strings"
+
+	ast "github.com/Artypuppet/monkey/ast"
+	object "github.com/Artypuppet/monkey/object"
+)
+
+// struct defining what a run of a program may do to the host: the
+// capabilities it is granted, which the builtins it calls have to have, and
+// who is told about those calls.
+type Policy struct {
+	Grants []object.Capability
+	// called for every call of a builtin needing capabilities, before it runs
+	// and whether it is allowed or not. Nil if no one listens.
+	Audit func(event AuditEvent)
+}
+
+// constructor for a policy granting capabilities.
+func NewPolicy(grants ...object.Capability) *Policy {
+	return &Policy{Grants: grants}
+}
+
+// method that reports whether p grants c. A nil policy grants nothing.
+func (p *Policy) Granted(c object.Capability) bool {
+	if p == nil {
+		return false
+	}
+	for _, grant := range p.Grants {
+		if grant == c {
+			return true
+		}
+	}
+	return false
+}
+
+// struct describing a call of a builtin needing capabilities.
+type AuditEvent struct {
+	Builtin      string
+	Args         []object.Object
+	Capabilities []object.Capability // the ones the builtin needs
+	Denied       []object.Capability // the ones of them not granted, none if the call is allowed
+	// the innermost call of a builtin in the program, see CallSite. It is
+	// the one of the builtin itself unless it was called by another builtin.
+	Call *ast.CallExpression
+}
+
+// method that reports whether the call was allowed to go on.
+func (e AuditEvent) Allowed() bool {
+	return len(e.Denied) == 0
+}
+
+// method that checks that the policy of ip grants the capabilities of
+// builtin, telling the audit callback about the call. It returns the error
+// the call fails with if they are not granted.
+func (ip *Interpreter) authorize(builtin *object.Builtin, args []object.Object) *object.Error {
+	name := builtin.Name
+	if name == "" {
+		name = "builtin"
+	}
+	var denied []object.Capability
+	for _, c := range builtin.Capabilities {
+		if !ip.Policy.Granted(c) {
+			denied = append(denied, c)
+		}
+	}
+	if ip.Policy != nil && ip.Policy.Audit != nil {
+		ip.Policy.Audit(AuditEvent{
+			Builtin:      name,
+			Args:         args,
+			Capabilities: builtin.Capabilities,
+			Denied:       denied,
+			Call:         ip.callSite,
+		})
+	}
+	if len(denied) == 0 {
+		return nil
+	}
+	missing := make([]string, len(denied))
+	for i, c := range denied {
+		missing[i] = string(c)
+	}
+	return newError("permission denied: `%s` needs %s", name, strings.Join(missing, ", "))
+}
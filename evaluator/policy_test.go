@@ -0,0 +1,86 @@
+package evaluator
+
+import (
+	"reflect"
+	"testing"
+
+	object "github.com/Artypuppet/monkey/object"
+	sandbox "github.com/Artypuppet/monkey/sandbox"
+)
+
+func TestPolicy(t *testing.T) {
+	tests := []struct {
+		input    string
+		policy   *Policy
+		expected string
+	}{
+		{`read_file("a.txt")`, NewPolicy(object.FS_READ_CAP), "a"},
+		{`read_file("a.txt")`, nil, "ERROR: permission denied: `read_file` needs fs-read"},
+		{`write_file("a.txt", "b")`, NewPolicy(object.FS_READ_CAP), "ERROR: permission denied: `write_file` needs fs-write"},
+		{`let f = read_file; f("a.txt")`, NewPolicy(object.ENV_CAP), "ERROR: permission denied: `read_file` needs fs-read"},
+		// builtins not reaching the host need no capabilities.
+		{`len(format("%d", 10))`, nil, "2"},
+	}
+
+	for _, tt := range tests {
+		result := evalWith(t, &Interpreter{FS: sandbox.NewMemFS(map[string]string{"a.txt": "a"}), Policy: tt.policy}, tt.input)
+		if result.Inspect() != tt.expected {
+			t.Errorf("%s: expected %q, got %q", tt.input, tt.expected, result.Inspect())
+		}
+	}
+}
+
+func TestPolicyOfEmbedderBuiltins(t *testing.T) {
+	called := false
+	ip := &Interpreter{Builtins: map[string]*object.Builtin{
+		"secret": {
+			Fn: func(args ...object.Object) object.Object {
+				called = true
+				return NULL
+			},
+			Name:         "secret",
+			Capabilities: []object.Capability{object.ENV_CAP, object.PROCESS_CAP},
+		},
+	}, Policy: NewPolicy(object.ENV_CAP)}
+	result := evalWith(t, ip, `secret()`)
+	if result.Inspect() != "ERROR: permission denied: `secret` needs process" || called {
+		t.Errorf("expected the call to be denied, got %s", result.Inspect())
+	}
+}
+
+func TestAudit(t *testing.T) {
+	var events []AuditEvent
+	policy := NewPolicy(object.FS_READ_CAP)
+	policy.Audit = func(event AuditEvent) {
+		events = append(events, event)
+	}
+	ip := &Interpreter{FS: sandbox.NewMemFS(map[string]string{"a.txt": "a"}), Policy: policy}
+	evalWith(t, ip, "len(\"x\");\nread_file(\"a.txt\");\nremove(\"a.txt\")")
+
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(events))
+	}
+	tests := []struct {
+		builtin string
+		denied  []object.Capability
+		line    int
+	}{
+		{"read_file", nil, 2},
+		{"remove", []object.Capability{object.FS_WRITE_CAP}, 3},
+	}
+	for i, tt := range tests {
+		event := events[i]
+		if event.Builtin != tt.builtin {
+			t.Errorf("events[%d]: wrong builtin. expected=%s, got=%s", i, tt.builtin, event.Builtin)
+		}
+		if !reflect.DeepEqual(event.Denied, tt.denied) || event.Allowed() != (tt.denied == nil) {
+			t.Errorf("events[%d]: wrong denied capabilities. expected=%v, got=%v", i, tt.denied, event.Denied)
+		}
+		if len(event.Args) != 1 || event.Args[0].Inspect() != "a.txt" {
+			t.Errorf("events[%d]: wrong arguments %v", i, event.Args)
+		}
+		if event.Call == nil || event.Call.Token.Line != tt.line {
+			t.Errorf("events[%d]: wrong call site %v", i, event.Call)
+		}
+	}
+}
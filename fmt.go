@@ -0,0 +1,57 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	format "github.com/Artypuppet/monkey/format"
+)
+
+// function implementing `monkey fmt [-w] files...`.
+// The formatted files are printed to stdout, or written back to the files
+// that changed with -w. Files that don't parse are left alone.
+func fmtCommand(args []string) int {
+	flags := flag.NewFlagSet("fmt", flag.ContinueOnError)
+	write := flags.Bool("w", false, "write the result back to the file instead of stdout")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: monkey fmt [-w] files...\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+
+	code := 0
+	for _, path := range flags.Args() {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "monkey: %s\n", err)
+			code = 1
+			continue
+		}
+		program := parseSource(path, string(src))
+		if program == nil {
+			code = 1
+			continue
+		}
+
+		formatted := format.Program(program)
+		if !*write {
+			fmt.Print(formatted)
+			continue
+		}
+		if formatted == string(src) {
+			continue
+		}
+		if err := os.WriteFile(path, []byte(formatted), 0644); err != nil {
+			fmt.Fprintf(os.Stderr, "monkey: %s\n", err)
+			code = 1
+		}
+	}
+	return code
+}
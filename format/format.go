@@ -0,0 +1,442 @@
+package format
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+
+	ast "github.com/Artypuppet/monkey/ast"
+	lexer "github.com/Artypuppet/monkey/lexer"
+	parser "github.com/Artypuppet/monkey/parser"
+	token "github.com/Artypuppet/monkey/token"
+)
+
+// the layout of the formatted source.
+const (
+	indentWidth = 4  // spaces per level of indentation
+	maxWidth    = 80 // lists that don't fit within this many columns are broken up
+)
+
+// function that parses src and returns it formatted.
+// If src doesn't parse the parser errors are returned, one per line.
+func Source(src []byte) ([]byte, error) {
+	p := parser.New(lexer.New(string(src)))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return nil, errors.New(strings.Join(p.Errors(), "\n"))
+	}
+	return []byte(Program(program)), nil
+}
+
+// function that pretty prints a parsed program in the canonical layout.
+// Blocks are indented on their own lines, lists of arguments and array
+// elements that are too long get one element per line, parentheses are only
+// written where precedence requires them and the comments recorded by the
+// parser are put back next to the statements they were next to.
+// At most one blank line is kept between statements.
+func Program(program *ast.Program) string {
+	p := &printer{comments: program.Comments}
+	return p.statements(program.Statements, 0, math.MaxInt, false)
+}
+
+// ---------------------------------Printer-----------------------------------
+
+// struct that holds the state of formatting a program.
+// Comments are printed in order so it only needs to know which one is next.
+type printer struct {
+	comments []*token.Token
+	next     int
+}
+
+// helper function that returns the indentation of the given depth.
+func indent(depth int) string {
+	return strings.Repeat(" ", depth*indentWidth)
+}
+
+// method that prints the comments before line on their own lines.
+// prevLine is the last source line printed so far, 0 if nothing was printed,
+// and is used to keep a blank line in front of a comment that had one.
+func (p *printer) leadingComments(out *strings.Builder, line, depth int, prevLine *int) {
+	for p.next < len(p.comments) && p.comments[p.next].Line < line {
+		c := p.comments[p.next]
+		if *prevLine != 0 && c.Line > *prevLine+1 {
+			out.WriteString("\n")
+		}
+		out.WriteString(indent(depth) + c.Literal + "\n")
+		*prevLine = c.Line
+		p.next++
+	}
+}
+
+// method that prints the comments up to and including line after the code
+// on that line. Only the first one fits on the line, the rest follow it.
+func (p *printer) trailingComments(out *strings.Builder, line, depth int) {
+	for first := true; p.next < len(p.comments) && p.comments[p.next].Line <= line; first = false {
+		if first {
+			out.WriteString(" ")
+		} else {
+			out.WriteString("\n" + indent(depth))
+		}
+		out.WriteString(p.comments[p.next].Literal)
+		p.next++
+	}
+}
+
+// method that prints a list of statements at depth, one per line.
+// The comments before end are printed along with them. In a block the last
+// statement is the value of the block and doesn't get a semicolon.
+func (p *printer) statements(stmts []ast.Statement, depth, end int, block bool) string {
+	var out strings.Builder
+	prevLine := 0
+	for i, stmt := range stmts {
+		start := startLine(stmt)
+		p.leadingComments(&out, start, depth, &prevLine)
+		if prevLine != 0 && start > prevLine+1 {
+			out.WriteString("\n")
+		}
+
+		out.WriteString(indent(depth))
+		out.WriteString(p.statement(stmt, depth))
+		if es, ok := stmt.(*ast.ExpressionStatement); ok {
+			last := i == len(stmts)-1
+			switch {
+			case block && last:
+			case endsWithBlock(es) && (last || !startsWithOperator(stmts[i+1])):
+			default:
+				out.WriteString(";")
+			}
+		}
+
+		prevLine = lastLine(stmt)
+		// a comment after the closing brace of a block on the same line
+		// belongs to the statement around the block.
+		trailing := prevLine
+		if end <= trailing {
+			trailing = end - 1
+		}
+		p.trailingComments(&out, trailing, depth)
+		out.WriteString("\n")
+	}
+	p.leadingComments(&out, end, depth, &prevLine)
+	return out.String()
+}
+
+// method that prints a statement without indentation in front of it.
+// Only let and return statements end with a semicolon, expression
+// statements get theirs from statements.
+func (p *printer) statement(stmt ast.Statement, depth int) string {
+	switch stmt := stmt.(type) {
+	case *ast.LetStatement:
+		prefix := stmt.Token.Literal + " " + stmt.Name.String() + " = "
+		return prefix + p.expression(stmt.Value, depth, len(indent(depth))+len(prefix)) + ";"
+	case *ast.ReturnStatement:
+		return "return " + p.expression(stmt.ReturnValue, depth, len(indent(depth))+len("return ")) + ";"
+	case *ast.ExpressionStatement:
+		return p.expression(stmt.Expression, depth, len(indent(depth)))
+	case *ast.BlockStatement:
+		return p.block(stmt, depth)
+	}
+	return ""
+}
+
+// method that prints a block whose braces are at depth.
+func (p *printer) block(b *ast.BlockStatement, depth int) string {
+	// without the closing brace the comments are left to the statement after.
+	end := 0
+	if b.End != nil {
+		end = b.End.Line
+	}
+	body := p.statements(b.Statements, depth+1, end, true)
+	if body == "" {
+		return "{}"
+	}
+	return "{\n" + body + indent(depth) + "}"
+}
+
+// method that prints an expression that starts at column col of a line
+// indented to depth. The column is only needed to decide where lists break.
+func (p *printer) expression(exp ast.Expression, depth, col int) string {
+	switch exp := exp.(type) {
+	case *ast.Identifier:
+		return exp.Value
+	case *ast.IntegerLiteral:
+		return strconv.FormatInt(exp.Value, 10)
+	case *ast.StringLiteral:
+		return `"` + exp.Value + `"`
+	case *ast.Boolean:
+		return strconv.FormatBool(exp.Value)
+	case *ast.PrefixExpression:
+		return exp.Operator + p.operand(exp.Right, parser.PREFIX, depth, col+len(exp.Operator))
+	case *ast.InfixExpression:
+		prec := parser.Precedence(exp.Token.Type)
+		left := p.operand(exp.Left, prec, depth, col)
+		// operators are left associative so an operand on the right with the
+		// same precedence needs parentheses as well.
+		right := p.operand(exp.Right, prec+1, depth, advance(col, left+" "+exp.Operator+" "))
+		return left + " " + exp.Operator + " " + right
+	case *ast.IfExpression:
+		out := "if (" + p.expression(exp.Condition, depth, col+len("if (")) + ") " + p.block(exp.Consequence, depth)
+		if exp.Alternative != nil {
+			out += " else " + p.block(exp.Alternative, depth)
+		}
+		return out
+	case *ast.FunctionLiteral:
+		return p.functionLiteral(exp, depth)
+	case *ast.CallExpression:
+		function := p.operand(exp.Function, parser.CALL, depth, col)
+		return function + p.list("(", p.expressionItems(exp.Arguments), ")", exp.Token.Line, depth, advance(col, function))
+	case *ast.ArrayLiteral:
+		return p.list("[", p.expressionItems(exp.Elements), "]", exp.Token.Line, depth, col)
+	case *ast.HashLiteral:
+		return p.list("{", p.pairItems(exp.Pairs), "}", exp.Token.Line, depth, col)
+	case *ast.IndexExpression:
+		left := p.operand(exp.Left, parser.INDEX, depth, col)
+		return left + "[" + p.expression(exp.Index, depth, advance(col, left+"[")) + "]"
+	case *ast.SpreadExpression:
+		return "..." + p.expression(exp.Value, depth, col+len("..."))
+	}
+	return ""
+}
+
+// helper function that returns the column after printing s at column col.
+func advance(col int, s string) int {
+	if i := strings.LastIndex(s, "\n"); i >= 0 {
+		return len(s) - i - 1
+	}
+	return col + len(s)
+}
+
+// method that prints an operand of an operator with precedence prec,
+// in parentheses if it binds less tightly than the operator.
+func (p *printer) operand(exp ast.Expression, prec, depth, col int) string {
+	if precedence(exp) < prec {
+		return "(" + p.expression(exp, depth, col+1) + ")"
+	}
+	return p.expression(exp, depth, col)
+}
+
+// helper function that returns how tightly an expression binds.
+// Everything that isn't made of an operator can be an operand as is.
+func precedence(exp ast.Expression) int {
+	switch exp := exp.(type) {
+	case *ast.InfixExpression:
+		return parser.Precedence(exp.Token.Type)
+	case *ast.PrefixExpression:
+		return parser.PREFIX
+	case *ast.SpreadExpression:
+		// a spread takes everything after it.
+		return parser.LOWEST
+	}
+	return math.MaxInt
+}
+
+// method that prints a function literal, its body indented below it.
+func (p *printer) functionLiteral(fl *ast.FunctionLiteral, depth int) string {
+	params := ast.FormatParameters(fl.Parameters, fl.Defaults, fl.Rest, func(exp ast.Expression) string {
+		return p.expression(exp, depth, 0)
+	})
+	out := "fn"
+	if fl.Name != nil {
+		out += " " + fl.Name.Value
+	}
+	out += "(" + params + ")"
+	if fl.ReturnType != nil {
+		out += ": " + fl.ReturnType.String()
+	}
+	return out + " " + p.block(fl.Body, depth)
+}
+
+// struct defining an element of a list: the lines it spans in the source
+// and how to print it starting at a column of a line indented to depth.
+type item struct {
+	first, last int
+	print       func(depth, col int) string
+}
+
+// helper method that turns the arguments of a call or the elements of an
+// array literal into list items.
+func (p *printer) expressionItems(exps []ast.Expression) []item {
+	items := []item{}
+	for _, exp := range exps {
+		exp := exp
+		items = append(items, item{
+			first: startLineOf(exp),
+			last:  lastLine(exp),
+			print: func(depth, col int) string { return p.expression(exp, depth, col) },
+		})
+	}
+	return items
+}
+
+// helper method that turns the pairs of a hash literal into list items.
+func (p *printer) pairItems(pairs []ast.HashPair) []item {
+	items := []item{}
+	for _, pair := range pairs {
+		pair := pair
+		items = append(items, item{
+			first: startLineOf(pair.Key),
+			last:  lastLine(pair.Value),
+			print: func(depth, col int) string {
+				key := p.expression(pair.Key, depth, col) + ": "
+				return key + p.expression(pair.Value, depth, advance(col, key))
+			},
+		})
+	}
+	return items
+}
+
+// method that prints the elements of a call, an array or a hash literal
+// between open and close. They stay on one line if that fits within maxWidth
+// and no comment sits between them, otherwise each one gets its own line.
+// line is the line of the opening token in the source.
+func (p *printer) list(open string, items []item, close string, line, depth, col int) string {
+	if len(items) == 0 {
+		return open + close
+	}
+
+	start := p.next
+	if !p.commentsBefore(items[len(items)-1].first, line) {
+		parts := []string{}
+		c := col + len(open)
+		fits := true
+		for i, it := range items {
+			s := it.print(depth, c)
+			if strings.Contains(s, "\n") && i != len(items)-1 {
+				fits = false
+				break
+			}
+			parts = append(parts, s)
+			c = advance(c, s+", ")
+		}
+		out := open + strings.Join(parts, ", ") + close
+		firstLine, _, _ := strings.Cut(out, "\n")
+		if fits && col+len(firstLine) <= maxWidth {
+			return out
+		}
+		// the elements are printed again so the comments in them are too.
+		p.next = start
+	}
+
+	var out strings.Builder
+	out.WriteString(open + "\n")
+	prevLine := 0
+	for i, it := range items {
+		p.leadingComments(&out, it.first, depth+1, &prevLine)
+		out.WriteString(indent(depth+1) + it.print(depth+1, len(indent(depth+1))))
+		if i != len(items)-1 {
+			out.WriteString(",")
+		}
+		prevLine = it.last
+		p.trailingComments(&out, prevLine, depth+1)
+		out.WriteString("\n")
+	}
+	out.WriteString(indent(depth) + close)
+	return out.String()
+}
+
+// helper method that reports whether there is a comment left to print that
+// is after line from but before line to.
+func (p *printer) commentsBefore(to, from int) bool {
+	return p.next < len(p.comments) && p.comments[p.next].Line >= from && p.comments[p.next].Line < to
+}
+
+// ---------------------------------Positions---------------------------------
+
+// helper function that reports whether an expression statement ends with the
+// closing brace of a block so that it doesn't need a semicolon.
+func endsWithBlock(es *ast.ExpressionStatement) bool {
+	switch exp := es.Expression.(type) {
+	case *ast.IfExpression:
+		return true
+	case *ast.FunctionLiteral:
+		return exp.Name != nil
+	}
+	return false
+}
+
+// helper function that reports whether the printed statement starts with a
+// token the parser would take as continuing the expression before it, e.g. a
+// call after an if expression. The statement before then needs a semicolon.
+func startsWithOperator(stmt ast.Statement) bool {
+	es, ok := stmt.(*ast.ExpressionStatement)
+	if !ok {
+		return false
+	}
+	exp := es.Expression
+	for {
+		switch e := exp.(type) {
+		case *ast.InfixExpression:
+			if precedence(e.Left) < parser.Precedence(e.Token.Type) {
+				return true
+			}
+			exp = e.Left
+		case *ast.CallExpression:
+			if precedence(e.Function) < parser.CALL {
+				return true
+			}
+			exp = e.Function
+		case *ast.IndexExpression:
+			if precedence(e.Left) < parser.INDEX {
+				return true
+			}
+			exp = e.Left
+		case *ast.PrefixExpression:
+			return e.Operator == "-"
+		case *ast.ArrayLiteral:
+			return true
+		default:
+			return false
+		}
+	}
+}
+
+// helper function that returns the line a statement starts on.
+func startLine(stmt ast.Statement) int {
+	switch stmt := stmt.(type) {
+	case *ast.LetStatement:
+		return stmt.Token.Line
+	case *ast.ReturnStatement:
+		return stmt.Token.Line
+	case *ast.ExpressionStatement:
+		return stmt.Token.Line
+	case *ast.BlockStatement:
+		return stmt.Token.Line
+	}
+	return 0
+}
+
+// helper function that returns the line an expression starts on.
+func startLineOf(exp ast.Expression) int {
+	switch exp := exp.(type) {
+	case *ast.InfixExpression:
+		return startLineOf(exp.Left)
+	case *ast.CallExpression:
+		return startLineOf(exp.Function)
+	case *ast.IndexExpression:
+		return startLineOf(exp.Left)
+	case *ast.HashLiteral:
+		return exp.Token.Line
+	case nil:
+		return 0
+	}
+	if tokens := ast.Tokens(exp); len(tokens) != 0 {
+		return tokens[0].Line
+	}
+	return 0
+}
+
+// helper function that returns the last line of the source a node was parsed
+// from, as far as it can be told from the tokens kept in the ast.
+func lastLine(node ast.Node) int {
+	line := 0
+	ast.Inspect(node, func(n ast.Node) bool {
+		for _, tok := range ast.Tokens(n) {
+			if tok.Line > line {
+				line = tok.Line
+			}
+		}
+		return n != nil
+	})
+	return line
+}
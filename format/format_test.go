@@ -0,0 +1,102 @@
+package format
+
+import (
+	"testing"
+
+	lexer "github.com/Artypuppet/monkey/lexer"
+	parser "github.com/Artypuppet/monkey/parser"
+)
+
+func TestSource(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let x=5", "let x = 5;\n"},
+		{"const  y : int = 10;x", "const y: int = 10;\nx;\n"},
+		{"return a+b;", "return a + b;\n"},
+		// only the parentheses that precedence requires are kept.
+		{"((1 + 2)) * 3;", "(1 + 2) * 3;\n"},
+		{"1 + (2 * 3);", "1 + 2 * 3;\n"},
+		{"a - (b - c); (a - b) - c;", "a - (b - c);\na - b - c;\n"},
+		{"-(a + b); !(-a); (-a)[0]; (f)(x); (a + b)(c);", "-(a + b);\n!-a;\n(-a)[0];\nf(x);\n(a + b)(c);\n"},
+		{"(a < b) == (c > d)", "a < b == c > d;\n"},
+		{"f(...(a), [...b, 1])", "f(...a, [...b, 1]);\n"},
+		// blocks are indented on their own lines and their value has no semicolon.
+		{
+			"let f = fn(a: int, b = 2, ...c): int { let d = a + b; d }",
+			"let f = fn(a: int, b = 2, ...c): int {\n    let d = a + b;\n    d\n};\n",
+		},
+		{
+			"if (x) { 1 } else { if (y) { 2 } }",
+			"if (x) {\n    1\n} else {\n    if (y) {\n        2\n    }\n}\n",
+		},
+		{"fn f() {} f()", "fn f() {}\nf();\n"},
+		// the semicolon after a block is kept where the next line would continue it.
+		{"if (x) { 1 }; (a + b)(c)", "if (x) {\n    1\n};\n(a + b)(c);\n"},
+		{"if (x) { 1 }; (a + b)", "if (x) {\n    1\n}\na + b;\n"},
+		{"fn f() { 1 }; -a", "fn f() {\n    1\n};\n-a;\n"},
+		{"if (x) { 1 }; [1]", "if (x) {\n    1\n};\n[1];\n"},
+		// long lists get one element per line.
+		{
+			`puts("aaaaaaaaaaaaaaaaaaaa", "bbbbbbbbbbbbbbbbbbbbbbbbbb", "cccccccccccccccccccccccccc")`,
+			"puts(\n    \"aaaaaaaaaaaaaaaaaaaa\",\n    \"bbbbbbbbbbbbbbbbbbbbbbbbbb\",\n    \"cccccccccccccccccccccccccc\"\n);\n",
+		},
+		{
+			"let f = fn() { [aaaaaaaaaaaaaaaaaaaaaa, bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb, ccccccccccccc] }",
+			"let f = fn() {\n    [\n        aaaaaaaaaaaaaaaaaaaaaa,\n        bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb,\n        ccccccccccccc\n    ]\n};\n",
+		},
+		{`let h={"a":1,true:[1,2]}`, "let h = {\"a\": 1, true: [1, 2]};\n"},
+		{
+			`{"aaaaaaaaaaaaaaaaaaaaaaaa": 1, "bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb": 2, "ccccccccccccc": 3}`,
+			"{\n    \"aaaaaaaaaaaaaaaaaaaaaaaa\": 1,\n    \"bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb\": 2,\n    \"ccccccccccccc\": 3\n};\n",
+		},
+		// a function as the last argument stays on the line of the call.
+		{"map(a, fn(x) { x * 2 })", "map(a, fn(x) {\n    x * 2\n});\n"},
+		// comments are kept where they were.
+		{"// head\n\n\nlet x = 1; // one\n// two\nx", "// head\n\nlet x = 1; // one\n// two\nx;\n"},
+		{"let f = fn() {\n// nothing\n}", "let f = fn() {\n    // nothing\n};\n"},
+		{"let f = fn() { 1 } // after", "let f = fn() {\n    1\n}; // after\n"},
+		{"f(a, // first\nb)", "f(\n    a, // first\n    b\n);\n"},
+		{"let x = 1;\n\n\n\nlet y = 2;\nlet z = 3;", "let x = 1;\n\nlet y = 2;\nlet z = 3;\n"},
+	}
+
+	for _, tt := range tests {
+		got, err := Source([]byte(tt.input))
+		if err != nil {
+			t.Errorf("input %q: unexpected error: %s", tt.input, err)
+			continue
+		}
+		if string(got) != tt.expected {
+			t.Errorf("input %q: wrong output.\nwant=%q\ngot= %q", tt.input, tt.expected, got)
+			continue
+		}
+
+		// formatting the output again must not change it.
+		again, err := Source(got)
+		if err != nil {
+			t.Errorf("input %q: output does not parse: %s", tt.input, err)
+			continue
+		}
+		if string(again) != string(got) {
+			t.Errorf("input %q: output not stable.\nfirst= %q\nsecond=%q", tt.input, got, again)
+		}
+
+		// and the program must be the same.
+		original := parser.New(lexer.New(tt.input)).ParseProgram()
+		formatted := parser.New(lexer.New(string(got))).ParseProgram()
+		if original.String() != formatted.String() {
+			t.Errorf("input %q: program changed.\nwant=%q\ngot= %q", tt.input, original.String(), formatted.String())
+		}
+	}
+}
+
+func TestSourceErrors(t *testing.T) {
+	_, err := Source([]byte("let = 5;"))
+	if err == nil {
+		t.Fatalf("expected an error")
+	}
+	if err.Error() != "expected next token to be IDENT, got = instead\nno prefix parse function for = found" {
+		t.Errorf("wrong error. got=%q", err.Error())
+	}
+}
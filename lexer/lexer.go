@@ -1,6 +1,8 @@
 package lexer
 
 import (
+	"strings"
+
 	token "github.com/Artypuppet/monkey/token"
 )
 
@@ -12,17 +14,24 @@ type Lexer struct {
 	ch           byte   // the current character in input
 	position     int    // represents the index of the current ch character in the input
 	readPosition int    // represents the index of the next character after ch in the input
+	line         int    // the line of the current ch character, starting at 1
+	lineStart    int    // the index in the input at which the current line starts
+	comments     []*token.Token
 }
 
 // constructor for lexer
 func New(input string) *Lexer {
-	l := &Lexer{input: input}
+	l := &Lexer{input: input, line: 1}
 	l.readChar()
 	return l
 }
 
 // helper method to get the next character in the input string
 func (l *Lexer) readChar() {
+	if l.ch == '\n' && l.position < len(l.input) {
+		l.line++
+		l.lineStart = l.readPosition
+	}
 	if l.readPosition >= len(l.input) {
 		l.ch = 0
 	} else {
@@ -32,10 +41,24 @@ func (l *Lexer) readChar() {
 	l.readPosition++
 }
 
+// method that returns the next token in the input along with its position.
 func (l *Lexer) NextToken() *token.Token {
-	var tok *token.Token
-	// ignore any whitespace between characters
+	// ignore any whitespace and comments between characters
 	l.skipWhiteSpace()
+	for l.ch == '/' && l.peekChar() == '/' {
+		l.readComment()
+		l.skipWhiteSpace()
+	}
+
+	line, column := l.line, l.position-l.lineStart+1
+	tok := l.readToken()
+	tok.Line, tok.Column = line, column
+	return tok
+}
+
+// helper method that reads the token starting at the current character.
+func (l *Lexer) readToken() *token.Token {
+	var tok *token.Token
 
 	switch l.ch {
 	case '=':
@@ -53,6 +76,8 @@ func (l *Lexer) NextToken() *token.Token {
 		tok = newToken(token.RPAREN, l.ch)
 	case ',':
 		tok = newToken(token.COMMA, l.ch)
+	case ':':
+		tok = newToken(token.COLON, l.ch)
 	case '+':
 		tok = newToken(token.PLUS, l.ch)
 	case '{':
@@ -76,6 +101,14 @@ func (l *Lexer) NextToken() *token.Token {
 		tok = newToken(token.LT, l.ch)
 	case '>':
 		tok = newToken(token.GT, l.ch)
+	case '.':
+		if l.peekChar() == '.' && l.peekCharN(2) == '.' {
+			l.readChar()
+			l.readChar()
+			tok = &token.Token{Type: token.ELLIPSIS, Literal: "..."}
+		} else {
+			tok = newToken(token.ILLEGAL, l.ch)
+		}
 	case '[':
 		tok = newToken(token.LBRACKET, l.ch)
 	case ']':
@@ -123,6 +156,24 @@ func (l *Lexer) readDigit() string {
 	return l.input[initialPos:l.position]
 }
 
+// helper method that reads a // comment up to the end of the line.
+// Comments are not returned by NextToken but kept so that tools like the
+// formatter can put them back.
+func (l *Lexer) readComment() {
+	tok := &token.Token{Type: token.COMMENT, Line: l.line, Column: l.position - l.lineStart + 1}
+	initialPos := l.position
+	for l.ch != '\n' && l.ch != 0 {
+		l.readChar()
+	}
+	tok.Literal = strings.TrimRight(l.input[initialPos:l.position], " \t\r")
+	l.comments = append(l.comments, tok)
+}
+
+// method that returns the comments read so far in the order they appear.
+func (l *Lexer) Comments() []*token.Token {
+	return l.comments
+}
+
 // helper method to ignore whitespace between characters
 func (l *Lexer) skipWhiteSpace() {
 	for l.ch == ' ' || l.ch == '\t' || l.ch == '\n' || l.ch == '\r' {
@@ -139,6 +190,15 @@ func (l *Lexer) peekChar() byte {
 	}
 }
 
+// helper method to get the character n positions after the current one
+// without advancing the pointers. peekCharN(1) is the same as peekChar().
+func (l *Lexer) peekCharN(n int) byte {
+	if l.position+n >= len(l.input) {
+		return 0
+	}
+	return l.input[l.position+n]
+}
+
 // function to create a new token with the given TokenType and Literal value ch and returns a pointer to it.
 func newToken(tokenType token.TokenType, ch byte) *token.Token {
 	return &token.Token{Type: tokenType, Literal: string(ch)}
@@ -114,6 +114,11 @@ func TestNextToken(t *testing.T) {
 		{token.INT, "2"},
 		{token.RBRACKET, "]"},
 		{token.SEMICOLON, ";"},
+		{token.LBRACE, "{"},
+		{token.STRING, "foo"},
+		{token.COLON, ":"},
+		{token.STRING, "bar"},
+		{token.RBRACE, "}"},
 		{token.EOF, ""},
 	}
 	l := New(input)
@@ -130,3 +135,76 @@ func TestNextToken(t *testing.T) {
 		}
 	}
 }
+
+func TestTokenPositions(t *testing.T) {
+	input := "let x = 5;\n  x + \"a\nb\";\n\tfn(...y) {}"
+	tests := []struct {
+		expectedLiteral string
+		expectedLine    int
+		expectedColumn  int
+	}{
+		{"let", 1, 1},
+		{"x", 1, 5},
+		{"=", 1, 7},
+		{"5", 1, 9},
+		{";", 1, 10},
+		{"x", 2, 3},
+		{"+", 2, 5},
+		{"a\nb", 2, 7},
+		{";", 3, 3},
+		{"fn", 4, 2},
+		{"(", 4, 4},
+		{"...", 4, 5},
+		{"y", 4, 8},
+		{")", 4, 9},
+		{"{", 4, 11},
+		{"}", 4, 12},
+		{"", 4, 13},
+	}
+	l := New(input)
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+		if tok.Line != tt.expectedLine || tok.Column != tt.expectedColumn {
+			t.Errorf("tests[%d] - position of %q wrong. expected=%d:%d, got=%d:%d",
+				i, tok.Literal, tt.expectedLine, tt.expectedColumn, tok.Line, tok.Column)
+		}
+	}
+}
+
+func TestComments(t *testing.T) {
+	input := "// leading\nlet x = 10 / 2; // half\n//\nx"
+	expectedTokens := []string{"let", "x", "=", "10", "/", "2", ";", "x", ""}
+	expectedComments := []struct {
+		literal string
+		line    int
+		column  int
+	}{
+		{"// leading", 1, 1},
+		{"// half", 2, 17},
+		{"//", 3, 1},
+	}
+
+	l := New(input)
+	for i, expected := range expectedTokens {
+		tok := l.NextToken()
+		if tok.Literal != expected {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q", i, expected, tok.Literal)
+		}
+	}
+
+	comments := l.Comments()
+	if len(comments) != len(expectedComments) {
+		t.Fatalf("wrong number of comments. expected=%d, got=%d", len(expectedComments), len(comments))
+	}
+	for i, tt := range expectedComments {
+		c := comments[i]
+		if c.Type != token.COMMENT || c.Literal != tt.literal || c.Line != tt.line || c.Column != tt.column {
+			t.Errorf("comments[%d] wrong. expected=%q at %d:%d, got=%s %q at %d:%d",
+				i, tt.literal, tt.line, tt.column, c.Type, c.Literal, c.Line, c.Column)
+		}
+	}
+}
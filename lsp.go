@@ -0,0 +1,22 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	lsp "github.com/Artypuppet/monkey/lsp"
+)
+
+// function implementing `monkey lsp`, which runs a language server for
+// editors over stdin and stdout.
+func lspCommand(args []string) int {
+	if len(args) != 0 {
+		fmt.Fprintf(os.Stderr, "usage: monkey lsp\n")
+		return 2
+	}
+	if err := lsp.NewServer(os.Stdin, os.Stdout).Run(); err != nil {
+		fmt.Fprintf(os.Stderr, "monkey: %s\n", err)
+		return 1
+	}
+	return 0
+}
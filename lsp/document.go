@@ -0,0 +1,338 @@
+package lsp
+
+import (
+	"sort"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	ast "github.com/Artypuppet/monkey/ast"
+	evaluator "github.com/Artypuppet/monkey/evaluator"
+	lexer "github.com/Artypuppet/monkey/lexer"
+	parser "github.com/Artypuppet/monkey/parser"
+	resolver "github.com/Artypuppet/monkey/resolver"
+	testrunner "github.com/Artypuppet/monkey/testrunner"
+	token "github.com/Artypuppet/monkey/token"
+	types "github.com/Artypuppet/monkey/types"
+)
+
+// struct holding an open document and what is known about it.
+// program and info are from the last version of the text that parsed, so
+// navigation and completion keep working while the user is typing.
+type document struct {
+	text        string
+	lines       []string
+	program     *ast.Program
+	info        *resolver.Info
+	diagnostics []Diagnostic
+}
+
+// function that analyses text, the contents of the document at uri, the
+// way `monkey check` does: it is parsed, and if that succeeds resolved and
+// type checked. prev is the document the text replaces, or nil.
+func newDocument(uri, text string, prev *document) *document {
+	doc := &document{text: text, lines: strings.Split(text, "\n"), diagnostics: []Diagnostic{}}
+
+	p := parser.New(lexer.New(text))
+	program := p.ParseProgram()
+	if errors := p.PositionedErrors(); len(errors) != 0 {
+		for _, e := range errors {
+			doc.addDiagnostic(e.Line, e.Column, severityError, "parser", e.Message)
+		}
+		if prev != nil {
+			doc.program, doc.info = prev.program, prev.info
+		}
+		return doc
+	}
+
+	doc.program = program
+	doc.info = resolver.Analyze(program, testrunner.Globals(uri))
+	for _, d := range doc.info.Diagnostics {
+		severity := severityError
+		if d.Severity == resolver.Warning {
+			severity = severityWarning
+		}
+		doc.addDiagnostic(d.Line, d.Column, severity, "resolver", d.Message)
+	}
+	for _, e := range types.Check(program) {
+		doc.addDiagnostic(e.Line, e.Column, severityError, "types", e.Message)
+	}
+	return doc
+}
+
+// helper method that adds a diagnostic covering the word at line:column.
+func (d *document) addDiagnostic(line, column, severity int, source, message string) {
+	d.diagnostics = append(d.diagnostics, Diagnostic{
+		Range:    d.wordRange(line, column),
+		Severity: severity,
+		Source:   source,
+		Message:  message,
+	})
+}
+
+// --------------------------------Positions----------------------------------
+
+// Positions in the ast have a line and a column starting at 1, with the
+// column counting bytes. The protocol counts both from 0 and characters in
+// UTF-16 code units.
+
+// method that converts line:column of the ast into a Position.
+func (d *document) position(line, column int) Position {
+	if line < 1 || line > len(d.lines) {
+		return Position{Line: max(line-1, 0)}
+	}
+	text := d.lines[line-1]
+	offset := min(max(column-1, 0), len(text))
+	return Position{Line: line - 1, Character: utf16Len(text[:offset])}
+}
+
+// method that converts a Position into line:column of the ast.
+func (d *document) lineColumn(pos Position) (int, int) {
+	if pos.Line < 0 || pos.Line >= len(d.lines) {
+		return pos.Line + 1, pos.Character + 1
+	}
+	text := d.lines[pos.Line]
+	offset, units := 0, 0
+	for offset < len(text) && units < pos.Character {
+		r, size := utf8.DecodeRuneInString(text[offset:])
+		units += len(utf16.Encode([]rune{r}))
+		offset += size
+	}
+	return pos.Line + 1, offset + 1
+}
+
+func utf16Len(s string) int {
+	return len(utf16.Encode([]rune(s)))
+}
+
+// method that returns the range of the identifier or number at line:column,
+// or of the single character there if there is none.
+func (d *document) wordRange(line, column int) Range {
+	start := d.position(line, column)
+	end := column
+	if line >= 1 && line <= len(d.lines) {
+		text := d.lines[line-1]
+		for end-1 < len(text) && isWordByte(text[end-1]) {
+			end++
+		}
+	}
+	if end == column {
+		end++
+	}
+	return Range{Start: start, End: d.position(line, end)}
+}
+
+func isWordByte(b byte) bool {
+	return b == '_' || 'a' <= b && b <= 'z' || 'A' <= b && b <= 'Z' || '0' <= b && b <= '9'
+}
+
+// method that returns the range covered by tok. A token that spans lines,
+// like a string, only has its first line covered.
+func (d *document) tokenRange(tok *token.Token) Range {
+	literal, _, _ := strings.Cut(tok.Literal, "\n")
+	length := len(literal)
+	if tok.Type == token.STRING {
+		length += 2
+	}
+	return Range{Start: d.position(tok.Line, tok.Column), End: d.position(tok.Line, tok.Column+length)}
+}
+
+// method that returns the range of the whole text.
+func (d *document) fullRange() Range {
+	last := len(d.lines) - 1
+	return Range{End: Position{Line: last, Character: utf16Len(d.lines[last])}}
+}
+
+// ----------------------------------Names------------------------------------
+
+// method that returns the identifier at pos, which may also be right after
+// its last character, or nil.
+func (d *document) identifierAt(pos Position) *ast.Identifier {
+	if d.program == nil {
+		return nil
+	}
+	line, column := d.lineColumn(pos)
+	var found *ast.Identifier
+	ast.Inspect(d.program, func(n ast.Node) bool {
+		if ident, ok := n.(*ast.Identifier); ok && ident.Token.Line == line &&
+			ident.Token.Column <= column && column <= ident.Token.Column+len(ident.Value) {
+			found = ident
+		}
+		return found == nil && n != nil
+	})
+	return found
+}
+
+// method that returns the identifier declaring the name at pos, which is the
+// identifier itself if it is a declaration, or nil.
+func (d *document) definition(pos Position) *ast.Identifier {
+	ident := d.identifierAt(pos)
+	if ident == nil {
+		return nil
+	}
+	if decl, ok := d.info.Definitions[ident]; ok {
+		return decl
+	}
+	for _, s := range d.info.Scopes {
+		for _, name := range s.Names {
+			if name == ident {
+				return ident
+			}
+		}
+	}
+	return nil
+}
+
+// method that shows the signature of the builtin at pos, if it is not
+// shadowed by a name of the program.
+func (d *document) hover(pos Position) *Hover {
+	ident := d.identifierAt(pos)
+	if ident == nil || d.definition(pos) != nil {
+		return nil
+	}
+	sig, ok := types.BuiltinSignature(ident.Value)
+	if !ok {
+		return nil
+	}
+	r := d.tokenRange(ident.Token)
+	return &Hover{
+		Contents: MarkupContent{Kind: "markdown", Value: "```monkey\n" + sig + "\n```\nbuiltin function"},
+		Range:    &r,
+	}
+}
+
+// method that lists the names that can be used at pos: the names of the
+// scopes around it declared before it, the builtins and the keywords.
+// Inner names come first and hide outer names that are the same.
+func (d *document) completion(pos Position) []CompletionItem {
+	items := []CompletionItem{}
+	seen := make(map[string]bool)
+	add := func(item CompletionItem) {
+		if !seen[item.Label] {
+			seen[item.Label] = true
+			items = append(items, item)
+		}
+	}
+
+	if d.info != nil {
+		line, column := d.lineColumn(pos)
+		var inner *resolver.Scope
+		for _, s := range d.info.Scopes {
+			if s.Contains(line, column) {
+				inner = s
+			}
+		}
+		for s := inner; s != nil; s = s.Outer {
+			for i := len(s.Names) - 1; i >= 0; i-- {
+				tok := s.Names[i].Token
+				if tok.Line < line || tok.Line == line && tok.Column+len(tok.Literal) < column {
+					add(CompletionItem{Label: s.Names[i].Value, Kind: completionVariable})
+				}
+			}
+		}
+	}
+
+	for _, name := range evaluator.BuiltinNames() {
+		sig, _ := types.BuiltinSignature(name)
+		add(CompletionItem{Label: name, Kind: completionFunction, Detail: sig})
+	}
+	keywords := []string{}
+	for keyword := range token.Idents {
+		keywords = append(keywords, keyword)
+	}
+	sort.Strings(keywords)
+	for _, keyword := range keywords {
+		add(CompletionItem{Label: keyword, Kind: completionKeyword})
+	}
+	return items
+}
+
+// ---------------------------------Symbols-----------------------------------
+
+// method that returns the symbols of the document: its let and const
+// bindings and named functions, with those of function bodies nested in them.
+func (d *document) symbols() []DocumentSymbol {
+	if d.program == nil {
+		return []DocumentSymbol{}
+	}
+	return d.statementSymbols(d.program.Statements)
+}
+
+func (d *document) statementSymbols(stmts []ast.Statement) []DocumentSymbol {
+	symbols := []DocumentSymbol{}
+	for _, stmt := range stmts {
+		var name *ast.Identifier
+		var fl *ast.FunctionLiteral
+		kind := symbolVariable
+
+		switch stmt := stmt.(type) {
+		case *ast.LetStatement:
+			name = stmt.Name
+			if stmt.IsConst() {
+				kind = symbolConstant
+			}
+			fl, _ = stmt.Value.(*ast.FunctionLiteral)
+		case *ast.ExpressionStatement:
+			if f, ok := stmt.Expression.(*ast.FunctionLiteral); ok && f.Name != nil {
+				name, fl = f.Name, f
+			}
+		}
+		if name == nil {
+			continue
+		}
+
+		symbol := DocumentSymbol{
+			Name:           name.Value,
+			Kind:           kind,
+			Range:          Range{Start: d.position(startToken(stmt).Line, startToken(stmt).Column), End: d.end(stmt)},
+			SelectionRange: d.tokenRange(name.Token),
+		}
+		if name.Type != nil {
+			symbol.Detail = name.Type.String()
+		}
+		if fl != nil {
+			symbol.Kind = symbolFunction
+			symbol.Detail = signature(fl)
+			if fl.Body != nil {
+				symbol.Children = d.statementSymbols(fl.Body.Statements)
+			}
+		}
+		symbols = append(symbols, symbol)
+	}
+	return symbols
+}
+
+// helper function that returns the signature of a function literal, e.g.
+// fn(a: int, b = 1, ...c): int.
+func signature(fl *ast.FunctionLiteral) string {
+	out := "fn(" + ast.FormatParameters(fl.Parameters, fl.Defaults, fl.Rest, ast.Expression.String) + ")"
+	if fl.ReturnType != nil {
+		out += ": " + fl.ReturnType.String()
+	}
+	return out
+}
+
+// helper function that returns the first token of a statement.
+func startToken(stmt ast.Statement) *token.Token {
+	if tokens := ast.Tokens(stmt); len(tokens) != 0 {
+		return tokens[0]
+	}
+	return &token.Token{}
+}
+
+// method that returns the position after the last token of node.
+func (d *document) end(node ast.Node) Position {
+	var last *token.Token
+	ast.Inspect(node, func(n ast.Node) bool {
+		for _, tok := range ast.Tokens(n) {
+			if last == nil || tok.Line > last.Line || tok.Line == last.Line && tok.Column > last.Column {
+				last = tok
+			}
+		}
+		return n != nil
+	})
+	if last == nil {
+		return Position{}
+	}
+	return d.tokenRange(last).End
+}
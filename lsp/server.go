@@ -0,0 +1,274 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+
+	format "github.com/Artypuppet/monkey/format"
+)
+
+// struct defining a language server talking JSON-RPC over a pair of streams,
+// usually stdin and stdout. It keeps the text of the open documents and
+// analyses a document every time it changes.
+type Server struct {
+	in        *bufio.Reader
+	out       io.Writer
+	documents map[string]*document // open documents by URI
+	shutdown  bool                 // whether the client asked the server to shut down
+}
+
+// constructor for a server reading requests from in and writing to out.
+func NewServer(in io.Reader, out io.Writer) *Server {
+	return &Server{
+		in:        bufio.NewReader(in),
+		out:       out,
+		documents: make(map[string]*document),
+	}
+}
+
+// map from the name of a request to the method handling it.
+var requestHandlers = map[string]func(s *Server, params json.RawMessage) (interface{}, error){
+	"initialize":                  (*Server).initialize,
+	"shutdown":                    (*Server).shutdownRequest,
+	"textDocument/definition":     (*Server).definition,
+	"textDocument/hover":          (*Server).hover,
+	"textDocument/documentSymbol": (*Server).documentSymbol,
+	"textDocument/completion":     (*Server).completion,
+	"textDocument/formatting":     (*Server).formatting,
+}
+
+// map from the name of a notification to the method handling it.
+var notificationHandlers = map[string]func(s *Server, params json.RawMessage) error{
+	"textDocument/didOpen":   (*Server).didOpen,
+	"textDocument/didChange": (*Server).didChange,
+	"textDocument/didClose":  (*Server).didClose,
+}
+
+// error returned by Run when the client says exit without asking the server
+// to shut down first, which the protocol counts as a failure.
+var ErrExitWithoutShutdown = errors.New("lsp: exit before shutdown")
+
+// method that serves requests until the client sends exit or closes the
+// input. It only fails if the streams do.
+func (s *Server) Run() error {
+	for {
+		data, err := readMessage(s.in)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var req request
+		if err := json.Unmarshal(data, &req); err != nil {
+			if err := s.replyError(nil, &responseError{Code: codeParseError, Message: err.Error()}); err != nil {
+				return err
+			}
+			continue
+		}
+		if req.Method == "exit" {
+			if !s.shutdown {
+				return ErrExitWithoutShutdown
+			}
+			return nil
+		}
+		if err := s.handle(&req); err != nil {
+			return err
+		}
+	}
+}
+
+// method that dispatches a message to its handler and sends the reply.
+// Notifications without a handler, like initialized, are ignored.
+func (s *Server) handle(req *request) error {
+	if req.isNotification() {
+		if handler, ok := notificationHandlers[req.Method]; ok {
+			return handler(s, req.Params)
+		}
+		return nil
+	}
+
+	handler, ok := requestHandlers[req.Method]
+	if !ok {
+		return s.replyError(req.ID, &responseError{Code: codeMethodNotFound, Message: "method not found: " + req.Method})
+	}
+	result, err := handler(s, req.Params)
+	if err != nil {
+		var rerr *responseError
+		if !errors.As(err, &rerr) {
+			rerr = &responseError{Code: codeRequestFailed, Message: err.Error()}
+		}
+		return s.replyError(req.ID, rerr)
+	}
+	return writeMessage(s.out, &response{JSONRPC: "2.0", ID: req.ID, Result: result})
+}
+
+func (s *Server) replyError(id json.RawMessage, err *responseError) error {
+	if id == nil {
+		id = json.RawMessage("null")
+	}
+	return writeMessage(s.out, &errorResponse{JSONRPC: "2.0", ID: id, Error: err})
+}
+
+func (s *Server) notify(method string, params interface{}) error {
+	return writeMessage(s.out, &notification{JSONRPC: "2.0", Method: method, Params: params})
+}
+
+// helper function that decodes the params of a message into v.
+func decodeParams(params json.RawMessage, v interface{}) error {
+	if err := json.Unmarshal(params, v); err != nil {
+		return &responseError{Code: codeInvalidParams, Message: err.Error()}
+	}
+	return nil
+}
+
+// helper method that returns the open document with the given URI.
+func (s *Server) document(uri string) (*document, error) {
+	doc, ok := s.documents[uri]
+	if !ok {
+		return nil, &responseError{Code: codeInvalidParams, Message: "unknown document: " + uri}
+	}
+	return doc, nil
+}
+
+// -------------------------------Lifecycle---------------------------------
+
+func (s *Server) initialize(params json.RawMessage) (interface{}, error) {
+	return &InitializeResult{
+		Capabilities: ServerCapabilities{
+			TextDocumentSync:           syncFull,
+			DefinitionProvider:         true,
+			HoverProvider:              true,
+			DocumentSymbolProvider:     true,
+			CompletionProvider:         &CompletionOptions{},
+			DocumentFormattingProvider: true,
+		},
+		ServerInfo: ServerInfo{Name: "monkey"},
+	}, nil
+}
+
+func (s *Server) shutdownRequest(params json.RawMessage) (interface{}, error) {
+	s.shutdown = true
+	return nil, nil
+}
+
+// -----------------------------Synchronization-----------------------------
+
+func (s *Server) didOpen(params json.RawMessage) error {
+	var p DidOpenTextDocumentParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil
+	}
+	s.documents[p.TextDocument.URI] = newDocument(p.TextDocument.URI, p.TextDocument.Text, nil)
+	return s.publishDiagnostics(p.TextDocument.URI)
+}
+
+func (s *Server) didChange(params json.RawMessage) error {
+	var p DidChangeTextDocumentParams
+	if err := json.Unmarshal(params, &p); err != nil || len(p.ContentChanges) == 0 {
+		return nil
+	}
+	uri := p.TextDocument.URI
+	// with full synchronization the last change holds the whole text.
+	text := p.ContentChanges[len(p.ContentChanges)-1].Text
+	s.documents[uri] = newDocument(uri, text, s.documents[uri])
+	return s.publishDiagnostics(uri)
+}
+
+func (s *Server) didClose(params json.RawMessage) error {
+	var p DidCloseTextDocumentParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil
+	}
+	delete(s.documents, p.TextDocument.URI)
+	// the diagnostics of a closed document are cleared.
+	return s.notify("textDocument/publishDiagnostics", &PublishDiagnosticsParams{
+		URI:         p.TextDocument.URI,
+		Diagnostics: []Diagnostic{},
+	})
+}
+
+func (s *Server) publishDiagnostics(uri string) error {
+	return s.notify("textDocument/publishDiagnostics", &PublishDiagnosticsParams{
+		URI:         uri,
+		Diagnostics: s.documents[uri].diagnostics,
+	})
+}
+
+// ---------------------------------Features---------------------------------
+
+func (s *Server) definition(params json.RawMessage) (interface{}, error) {
+	var p TextDocumentPositionParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	doc, err := s.document(p.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	decl := doc.definition(p.Position)
+	if decl == nil {
+		return nil, nil
+	}
+	return &Location{URI: p.TextDocument.URI, Range: doc.tokenRange(decl.Token)}, nil
+}
+
+func (s *Server) hover(params json.RawMessage) (interface{}, error) {
+	var p TextDocumentPositionParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	doc, err := s.document(p.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	return doc.hover(p.Position), nil
+}
+
+func (s *Server) documentSymbol(params json.RawMessage) (interface{}, error) {
+	var p DocumentSymbolParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	doc, err := s.document(p.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	return doc.symbols(), nil
+}
+
+func (s *Server) completion(params json.RawMessage) (interface{}, error) {
+	var p CompletionParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	doc, err := s.document(p.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	return doc.completion(p.Position), nil
+}
+
+// method that formats a whole document with the format package. The result
+// is a single edit replacing the text, or no edit if it is formatted already.
+func (s *Server) formatting(params json.RawMessage) (interface{}, error) {
+	var p DocumentFormattingParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	doc, err := s.document(p.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	formatted, err := format.Source([]byte(doc.text))
+	if err != nil {
+		return nil, err
+	}
+	if string(formatted) == doc.text {
+		return []TextEdit{}, nil
+	}
+	return []TextEdit{{Range: doc.fullRange(), NewText: string(formatted)}}, nil
+}
@@ -0,0 +1,348 @@
+package lsp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testURI = "file:///test.mk"
+
+// struct defining a client that queues messages for a server and reads the
+// messages the server sent back once it ran.
+type testClient struct {
+	in     bytes.Buffer
+	nextID int
+}
+
+func (c *testClient) request(method string, params interface{}) int {
+	c.nextID++
+	writeMessage(&c.in, map[string]interface{}{"jsonrpc": "2.0", "id": c.nextID, "method": method, "params": params})
+	return c.nextID
+}
+
+func (c *testClient) notify(method string, params interface{}) {
+	writeMessage(&c.in, map[string]interface{}{"jsonrpc": "2.0", "method": method, "params": params})
+}
+
+// struct defining any message sent by the server.
+type testMessage struct {
+	ID     *int            `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+	Result json.RawMessage `json:"result"`
+	Error  *responseError  `json:"error"`
+}
+
+// helper function that runs a server on the messages queued in c and returns
+// the responses by request ID and the notifications in order.
+func run(t *testing.T, c *testClient) (map[int]testMessage, []testMessage) {
+	t.Helper()
+	var out bytes.Buffer
+	if err := NewServer(&c.in, &out).Run(); err != nil {
+		t.Fatalf("server failed: %s", err)
+	}
+
+	responses := make(map[int]testMessage)
+	notifications := []testMessage{}
+	r := bufio.NewReader(&out)
+	for {
+		data, err := readMessage(r)
+		if err != nil {
+			break
+		}
+		var msg testMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			t.Fatalf("invalid message %s: %s", data, err)
+		}
+		if msg.ID != nil {
+			responses[*msg.ID] = msg
+		} else {
+			notifications = append(notifications, msg)
+		}
+	}
+	return responses, notifications
+}
+
+// helper function that decodes the result of a response into v.
+func result(t *testing.T, msg testMessage, v interface{}) {
+	t.Helper()
+	if msg.Error != nil {
+		t.Fatalf("request failed: %s", msg.Error.Message)
+	}
+	if err := json.Unmarshal(msg.Result, v); err != nil {
+		t.Fatalf("invalid result %s: %s", msg.Result, err)
+	}
+}
+
+func open(c *testClient, text string) {
+	c.notify("textDocument/didOpen", DidOpenTextDocumentParams{
+		TextDocument: TextDocumentItem{URI: testURI, LanguageID: "monkey", Version: 1, Text: text},
+	})
+}
+
+func at(line, character int) TextDocumentPositionParams {
+	return TextDocumentPositionParams{
+		TextDocument: TextDocumentIdentifier{URI: testURI},
+		Position:     Position{Line: line, Character: character},
+	}
+}
+
+func TestLifecycle(t *testing.T) {
+	c := &testClient{}
+	initialize := c.request("initialize", map[string]interface{}{"capabilities": map[string]interface{}{}})
+	c.notify("initialized", map[string]interface{}{})
+	unknown := c.request("textDocument/rename", map[string]interface{}{})
+	shutdown := c.request("shutdown", nil)
+	c.notify("exit", nil)
+
+	responses, _ := run(t, c)
+	var init InitializeResult
+	result(t, responses[initialize], &init)
+	caps := init.Capabilities
+	if caps.TextDocumentSync != syncFull || !caps.DefinitionProvider || !caps.HoverProvider ||
+		!caps.DocumentSymbolProvider || caps.CompletionProvider == nil || !caps.DocumentFormattingProvider {
+		t.Errorf("wrong capabilities: %+v", caps)
+	}
+	if err := responses[unknown].Error; err == nil || err.Code != codeMethodNotFound {
+		t.Errorf("expected method not found for an unknown request. got=%+v", responses[unknown])
+	}
+	if msg, ok := responses[shutdown]; !ok || msg.Error != nil || string(msg.Result) != "null" {
+		t.Errorf("wrong shutdown response: %+v", msg)
+	}
+
+	c = &testClient{}
+	c.notify("exit", nil)
+	if err := NewServer(&c.in, &bytes.Buffer{}).Run(); err != ErrExitWithoutShutdown {
+		t.Errorf("expected ErrExitWithoutShutdown. got=%v", err)
+	}
+}
+
+func TestDiagnostics(t *testing.T) {
+	c := &testClient{}
+	open(c, "let x = ;")
+	c.notify("textDocument/didChange", DidChangeTextDocumentParams{
+		TextDocument:   TextDocumentIdentifier{URI: testURI},
+		ContentChanges: []TextDocumentContentChangeEvent{{Text: "let s = \"€𝄞\"; let y = s + zz;\nlen(1, 2)"}},
+	})
+	c.notify("textDocument/didClose", DidCloseTextDocumentParams{TextDocument: TextDocumentIdentifier{URI: testURI}})
+
+	_, notifications := run(t, c)
+	got := []string{}
+	for _, n := range notifications {
+		var p PublishDiagnosticsParams
+		if err := json.Unmarshal(n.Params, &p); err != nil || n.Method != "textDocument/publishDiagnostics" || p.URI != testURI {
+			t.Fatalf("unexpected notification %s %s", n.Method, n.Params)
+		}
+		diagnostics := []string{}
+		for _, d := range p.Diagnostics {
+			diagnostics = append(diagnostics, fmt.Sprintf("%d:%d-%d:%d %d %s: %s", d.Range.Start.Line, d.Range.Start.Character,
+				d.Range.End.Line, d.Range.End.Character, d.Severity, d.Source, d.Message))
+		}
+		got = append(got, strings.Join(diagnostics, "; "))
+	}
+
+	expected := []string{
+		"0:8-0:9 1 parser: no prefix parse function for ; found",
+		// € is one UTF-16 code unit and 𝄞 two, but they take seven bytes.
+		"0:19-0:20 2 resolver: y declared and not used; 0:27-0:29 1 resolver: undefined: zz; " +
+			"1:3-1:4 1 types: wrong number of arguments to len. got=2, want=1",
+		"",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("wrong diagnostics.\nexpected=%q\ngot=     %q", expected, got)
+	}
+}
+
+func TestDiagnosticsOfTestFile(t *testing.T) {
+	// test files may use the assertions of the test runner.
+	text := "assert_eq(1, 1); assert(true)"
+	if doc := newDocument("file:///math_test.mk", text, nil); len(doc.diagnostics) != 0 {
+		t.Errorf("unexpected diagnostics for a test file: %+v", doc.diagnostics)
+	}
+	if doc := newDocument(testURI, text, nil); len(doc.diagnostics) != 2 {
+		t.Errorf("expected the assertions to be undefined. got=%+v", doc.diagnostics)
+	}
+}
+
+// the document used by the tests of the features, with positions as
+// line:character counted from 0.
+const testSource = `let total = 10;
+const add = fn(a, b: int): int {
+  let sum = a + b;
+  sum
+};
+fn twice(f, x) { f(f(x)) }
+let size = len(add(total, 1));
+`
+
+func TestDefinition(t *testing.T) {
+	tests := []struct {
+		line, character int
+		expected        string // the range of the declaration, empty for null
+	}{
+		{3, 3, "2:6-2:9"},    // sum
+		{2, 12, "1:15-1:16"}, // a
+		{6, 20, "0:4-0:9"},   // total
+		{6, 16, "1:6-1:9"},   // add
+		{5, 18, "5:9-5:10"},  // f
+		{0, 6, "0:4-0:9"},    // the declaration of total itself
+		{6, 12, ""},          // len is a builtin
+		{6, 10, ""},          // not a name
+	}
+
+	c := &testClient{}
+	open(c, testSource)
+	ids := []int{}
+	for _, tt := range tests {
+		ids = append(ids, c.request("textDocument/definition", at(tt.line, tt.character)))
+	}
+	responses, _ := run(t, c)
+
+	for i, tt := range tests {
+		var loc *Location
+		result(t, responses[ids[i]], &loc)
+		got := ""
+		if loc != nil {
+			if loc.URI != testURI {
+				t.Errorf("wrong URI %q", loc.URI)
+			}
+			got = fmt.Sprintf("%d:%d-%d:%d", loc.Range.Start.Line, loc.Range.Start.Character, loc.Range.End.Line, loc.Range.End.Character)
+		}
+		if got != tt.expected {
+			t.Errorf("wrong definition at %d:%d. expected=%q, got=%q", tt.line, tt.character, tt.expected, got)
+		}
+	}
+}
+
+func TestHover(t *testing.T) {
+	c := &testClient{}
+	open(c, testSource+"let first = 1; first;\n")
+	builtin := c.request("textDocument/hover", at(6, 12))
+	name := c.request("textDocument/hover", at(6, 5))
+	shadowed := c.request("textDocument/hover", at(7, 16))
+	responses, _ := run(t, c)
+
+	var hover *Hover
+	result(t, responses[builtin], &hover)
+	if hover == nil || hover.Contents.Value != "```monkey\nlen(any): int\n```\nbuiltin function" {
+		t.Errorf("wrong hover for len: %+v", hover)
+	}
+	if hover != nil && (hover.Range == nil || *hover.Range != (Range{Position{6, 11}, Position{6, 14}})) {
+		t.Errorf("wrong hover range for len: %+v", hover.Range)
+	}
+	for _, id := range []int{name, shadowed} {
+		if string(responses[id].Result) != "null" {
+			t.Errorf("expected no hover. got=%s", responses[id].Result)
+		}
+	}
+}
+
+func TestDocumentSymbol(t *testing.T) {
+	c := &testClient{}
+	open(c, testSource)
+	id := c.request("textDocument/documentSymbol", DocumentSymbolParams{TextDocument: TextDocumentIdentifier{URI: testURI}})
+	responses, _ := run(t, c)
+
+	var symbols []DocumentSymbol
+	result(t, responses[id], &symbols)
+	got := []string{}
+	var describe func(prefix string, symbols []DocumentSymbol)
+	describe = func(prefix string, symbols []DocumentSymbol) {
+		for _, s := range symbols {
+			got = append(got, fmt.Sprintf("%s%s %d %q %d:%d-%d:%d", prefix, s.Name, s.Kind, s.Detail,
+				s.Range.Start.Line, s.Range.Start.Character, s.Range.End.Line, s.Range.End.Character))
+			describe(prefix+s.Name+".", s.Children)
+		}
+	}
+	describe("", symbols)
+
+	expected := []string{
+		`total 13 "" 0:0-0:14`,
+		`add 12 "fn(a, b: int): int" 1:0-4:1`,
+		`add.sum 13 "" 2:2-2:17`,
+		`twice 12 "fn(f, x)" 5:0-5:26`,
+		`size 13 "" 6:0-6:29`,
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("wrong symbols.\nexpected=%q\ngot=     %q", expected, got)
+	}
+}
+
+func TestCompletion(t *testing.T) {
+	c := &testClient{}
+	open(c, testSource)
+	inside := c.request("textDocument/completion", at(3, 2))
+	top := c.request("textDocument/completion", at(1, 0))
+	// a change that doesn't parse keeps the names of the last version that did.
+	c.notify("textDocument/didChange", DidChangeTextDocumentParams{
+		TextDocument:   TextDocumentIdentifier{URI: testURI},
+		ContentChanges: []TextDocumentContentChangeEvent{{Text: testSource + "let x = "}},
+	})
+	broken := c.request("textDocument/completion", at(7, 0))
+	responses, _ := run(t, c)
+
+	labels := func(id int) []string {
+		var items []CompletionItem
+		result(t, responses[id], &items)
+		names := []string{}
+		for _, item := range items {
+			if item.Kind == completionVariable {
+				names = append(names, item.Label)
+			}
+		}
+		if len(items) == len(names) {
+			t.Errorf("expected builtins and keywords as well. got=%v", items)
+		}
+		return names
+	}
+
+	tests := []struct {
+		id       int
+		expected []string
+	}{
+		{inside, []string{"sum", "b", "a", "add", "total"}},
+		{top, []string{"total"}},
+		{broken, []string{"size", "twice", "add", "total"}},
+	}
+	for _, tt := range tests {
+		if got := labels(tt.id); !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("wrong completion. expected=%v, got=%v", tt.expected, got)
+		}
+	}
+}
+
+func TestFormatting(t *testing.T) {
+	c := &testClient{}
+	open(c, "let x=1\nx")
+	changed := c.request("textDocument/formatting", DocumentFormattingParams{TextDocument: TextDocumentIdentifier{URI: testURI}})
+	c.notify("textDocument/didChange", DidChangeTextDocumentParams{
+		TextDocument:   TextDocumentIdentifier{URI: testURI},
+		ContentChanges: []TextDocumentContentChangeEvent{{Text: "let x = 1;\nx;\n"}},
+	})
+	unchanged := c.request("textDocument/formatting", DocumentFormattingParams{TextDocument: TextDocumentIdentifier{URI: testURI}})
+	c.notify("textDocument/didChange", DidChangeTextDocumentParams{
+		TextDocument:   TextDocumentIdentifier{URI: testURI},
+		ContentChanges: []TextDocumentContentChangeEvent{{Text: "let = 1"}},
+	})
+	broken := c.request("textDocument/formatting", DocumentFormattingParams{TextDocument: TextDocumentIdentifier{URI: testURI}})
+	responses, _ := run(t, c)
+
+	var edits []TextEdit
+	result(t, responses[changed], &edits)
+	expected := []TextEdit{{Range: Range{End: Position{1, 1}}, NewText: "let x = 1;\nx;\n"}}
+	if !reflect.DeepEqual(edits, expected) {
+		t.Errorf("wrong edits. expected=%+v, got=%+v", expected, edits)
+	}
+	result(t, responses[unchanged], &edits)
+	if len(edits) != 0 {
+		t.Errorf("expected no edits. got=%+v", edits)
+	}
+	if err := responses[broken].Error; err == nil || err.Code != codeRequestFailed {
+		t.Errorf("expected formatting to fail. got=%+v", responses[broken])
+	}
+}
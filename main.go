@@ -2,13 +2,40 @@ package main
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"os/user"
 
+	ast "github.com/Artypuppet/monkey/ast"
+	lexer "github.com/Artypuppet/monkey/lexer"
+	parser "github.com/Artypuppet/monkey/parser"
 	repl "github.com/Artypuppet/monkey/repl"
 )
 
+// map from the name of a subcommand to the function implementing it.
+// Each function gets the arguments after the subcommand and returns the exit code.
+var commands = map[string]func(args []string) int{
+	"check": checkCommand,
+	"dap":   dapCommand,
+	"debug": debugCommand,
+	"fmt":   fmtCommand,
+	"lsp":   lspCommand,
+	"parse": parseCommand,
+	"run":   runCommand,
+	"test":  testCommand,
+}
+
 func main() {
+	if len(os.Args) > 1 {
+		command, ok := commands[os.Args[1]]
+		if !ok {
+			fmt.Fprintf(os.Stderr, "monkey: unknown command %q\n", os.Args[1])
+			fmt.Fprintf(os.Stderr, "usage: monkey [command] [arguments]\n")
+			os.Exit(2)
+		}
+		os.Exit(command(os.Args[2:]))
+	}
+
 	user, err := user.Current()
 	if err != nil {
 		panic(err)
@@ -16,5 +43,46 @@ func main() {
 	fmt.Printf("Hello %s! This is the Monkey programming language!\n",
 		user.Username)
 	fmt.Printf("Feel free to type in commands\n")
+	fmt.Printf("Type :help for the REPL commands\n")
 	repl.Start(os.Stdin, os.Stdout)
 }
+
+// function that reads and parses the file at path.
+// Any error is printed to stderr prefixed with the path, in which case
+// the returned program is nil.
+func parseFile(path string) *ast.Program {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "monkey: %s\n", err)
+		return nil
+	}
+	return parseSource(path, string(src))
+}
+
+// function that parses src, the contents of the file at path, printing
+// any error to stderr like parseFile.
+func parseSource(path, src string) *ast.Program {
+	p := parser.New(lexer.New(src))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		for _, msg := range p.Errors() {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", path, msg)
+		}
+		return nil
+	}
+	return program
+}
+
+// function that creates the file at path and lets write fill it, as for
+// profiles and reports.
+func writeFile(path string, write func(w io.Writer) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -3,6 +3,8 @@ package object
 import (
 	"bytes"
 	"fmt"
+	"hash/fnv"
+	"sort"
 	"strings"
 
 	ast "github.com/Artypuppet/monkey/ast"
@@ -25,6 +27,7 @@ const (
 	FUNCTION_OBJ     = "FUNCTION"
 	BUILTIN_OBJ      = "BUILTIN"
 	ARRAY_OBJ        = "ARRAY"
+	HASH_OBJ         = "HASH"
 )
 
 // this interface defines the top level value representation of
@@ -153,20 +156,31 @@ func (e *Error) Inspect() string {
 // PITFALL: maps are reference types as in they are not copied when passing
 // to a function or returned from a function.
 type Environment struct {
-	store map[string]Object
-	outer *Environment
+	store  map[string]Object
+	consts map[string]bool // names in store that were bound with const
+	outer  *Environment
+	strict bool // in strict mode a name can't be declared twice in the same scope
 }
 
 // Function to that return an an instance of the Environment struct
 func NewEnvironment() *Environment {
 	s := make(map[string]Object)
-	return &Environment{store: s, outer: nil}
+	return &Environment{store: s, consts: make(map[string]bool), outer: nil}
+}
+
+// Function that returns a new Environment in strict mode.
+// Every environment enclosed by it is in strict mode as well.
+func NewStrictEnvironment() *Environment {
+	env := NewEnvironment()
+	env.strict = true
+	return env
 }
 
 // Function that returns a new Environment with a ptr to its outer environment
 func NewEnclosedEnvironment(outer *Environment) *Environment {
 	enclosed := NewEnvironment()
 	enclosed.outer = outer
+	enclosed.strict = outer.strict
 	return enclosed
 }
 
@@ -186,20 +200,58 @@ func (e *Environment) Get(identifier string) (Object, bool) {
 	return obj, ok
 }
 
+// method that returns the names bound in this environment, not counting
+// its outer environments, in sorted order.
+func (e *Environment) Names() []string {
+	names := make([]string, 0, len(e.store))
+	for name := range e.store {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// getter to return the environment enclosing this one, nil for the outermost.
+func (e *Environment) Outer() *Environment {
+	return e.outer
+}
+
 // method to set the object for an identifier
 // The identifier here is node.Name.Value where node is a LetStatement
 // Name is an identifier struct and Value is a string.
+// It refuses to overwrite a const binding of this environment and, in strict
+// mode, any binding of this environment. In that case an Error is returned
+// instead of val. Bindings of outer environments are never affected.
 func (e *Environment) Set(identifier string, val Object) Object {
+	if e.consts[identifier] {
+		return &Error{Message: "cannot overwrite constant " + identifier}
+	}
+	if _, ok := e.store[identifier]; ok && e.strict {
+		return &Error{Message: identifier + " is already declared in this scope"}
+	}
 	e.store[identifier] = val
 	return val
 }
 
+// method to bind an identifier that can't be overwritten afterwards.
+// It follows the same rules as Set.
+func (e *Environment) SetConst(identifier string, val Object) Object {
+	result := e.Set(identifier, val)
+	if _, ok := result.(*Error); !ok {
+		e.consts[identifier] = true
+	}
+	return result
+}
+
 // -----------------------------Function Object-----------------------
 
 // struct to represent function object in our environment
 // It implements the Object interface.
 type Function struct {
+	Name       string // empty for anonymous functions
 	Parameters []*ast.Identifier
+	Defaults   []ast.Expression // default value of each parameter, nil if the parameter has none
+	Rest       *ast.Identifier  // collects the remaining arguments, nil if there is none
 	Body       *ast.BlockStatement
 	Env        *Environment
 }
@@ -212,14 +264,12 @@ func (f *Function) Type() ObjectType {
 func (f *Function) Inspect() string {
 	var out bytes.Buffer
 
-	params := []string{}
-
-	for _, p := range f.Parameters {
-		params = append(params, p.String())
-	}
 	out.WriteString("fn")
+	if f.Name != "" {
+		out.WriteString(" " + f.Name)
+	}
 	out.WriteString("(")
-	out.WriteString(strings.Join(params, ", "))
+	out.WriteString(ast.FormatParameters(f.Parameters, f.Defaults, f.Rest, ast.Expression.String))
 	out.WriteString(") {\n")
 	out.WriteString(f.Body.String())
 	out.WriteString("\n}")
@@ -231,10 +281,28 @@ func (f *Function) Inspect() string {
 
 // stuct that is a wrapper around a builtin function
 // It implements the object interface.
+// Builtins reaching out of the program, to files or the environment, name
+// themselves and the capabilities a program has to be granted to call them.
 type Builtin struct {
-	Fn BuiltinFunction
+	Fn           BuiltinFunction
+	Name         string
+	Capabilities []Capability
 }
 
+// type of the rights of a program to use the host it runs on.
+type Capability string
+
+const (
+	FS_READ_CAP  Capability = "fs-read"  // reading files and listing directories
+	FS_WRITE_CAP Capability = "fs-write" // creating, changing and removing files
+	ENV_CAP      Capability = "env"      // reading environment variables
+	TIME_CAP     Capability = "time"     // reading the clock
+	PROCESS_CAP  Capability = "process"  // running other programs
+)
+
+// all the capabilities there are.
+var Capabilities = []Capability{FS_READ_CAP, FS_WRITE_CAP, ENV_CAP, TIME_CAP, PROCESS_CAP}
+
 // methods implementing the object interface.
 func (b *Builtin) Type() ObjectType {
 	return BUILTIN_OBJ
@@ -270,3 +338,92 @@ func (a *Array) Inspect() string {
 
 	return out.String()
 }
+
+// --------------------------------Hash Object--------------------------
+
+// struct used as the key of a hash in Go. Two objects that are equal have
+// the same HashKey, the Type keeps e.g. 1 and "1" apart.
+type HashKey struct {
+	Type  ObjectType
+	Value uint64
+}
+
+// interface implemented by the objects that can be used as keys of a hash.
+type Hashable interface {
+	HashKey() HashKey
+}
+
+// methods that implement the Hashable interface.
+func (b *Boolean) HashKey() HashKey {
+	var value uint64
+	if b.Value {
+		value = 1
+	}
+	return HashKey{Type: b.Type(), Value: value}
+}
+
+func (i *Integer) HashKey() HashKey {
+	return HashKey{Type: i.Type(), Value: uint64(i.Value)}
+}
+
+func (s *String) HashKey() HashKey {
+	h := fnv.New64a()
+	h.Write([]byte(s.Value))
+	return HashKey{Type: s.Type(), Value: h.Sum64()}
+}
+
+// struct holding a key of a hash along with its value, since the HashKey
+// alone can't give the key back.
+type HashPair struct {
+	Key   Object
+	Value Object
+}
+
+// struct representing a hash, which maps keys to values.
+// It implements the object interface.
+type Hash struct {
+	Pairs map[HashKey]HashPair
+}
+
+// methods implementing the object interface.
+func (h *Hash) Type() ObjectType {
+	return HASH_OBJ
+}
+
+func (h *Hash) Inspect() string {
+	var out bytes.Buffer
+
+	pairs := []string{}
+	for _, pair := range h.SortedPairs() {
+		pairs = append(pairs, pair.Key.Inspect()+": "+pair.Value.Inspect())
+	}
+	out.WriteString("{")
+	out.WriteString(strings.Join(pairs, ", "))
+	out.WriteString("}")
+
+	return out.String()
+}
+
+// method that returns the pairs of the hash in a stable order, since Go
+// randomizes the order of maps: booleans, then integers, then strings, each
+// sorted by value.
+func (h *Hash) SortedPairs() []HashPair {
+	pairs := make([]HashPair, 0, len(h.Pairs))
+	for _, pair := range h.Pairs {
+		pairs = append(pairs, pair)
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		a, b := pairs[i].Key, pairs[j].Key
+		if a.Type() != b.Type() {
+			return a.Type() < b.Type()
+		}
+		switch a := a.(type) {
+		case *Integer:
+			return a.Value < b.(*Integer).Value
+		case *Boolean:
+			return !a.Value && b.(*Boolean).Value
+		}
+		return a.Inspect() < b.Inspect()
+	})
+	return pairs
+}
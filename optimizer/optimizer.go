@@ -0,0 +1,357 @@
+package optimizer
+
+import (
+	"strconv"
+
+	ast "github.com/Artypuppet/monkey/ast"
+	token "github.com/Artypuppet/monkey/token"
+)
+
+// struct that holds the state of an optimizer pass.
+type optimizer struct {
+	declarations map[string]int            // how many times each name is declared in the program
+	constants    map[string]ast.Expression // names currently bound to a literal that can be inlined
+}
+
+// function that rewrites program into a cheaper program that evaluates to the
+// same result, producing the same errors. It is meant to run between parsing
+// and evaluation and changes the program in place.
+//
+//   - infix and prefix expressions on integer, string and boolean literals are
+//     folded into a literal, unless evaluating them would be an error.
+//   - if expressions with a literal condition are replaced by the branch that
+//     is taken, or lose the branch that isn't when that can't be done.
+//   - a let or const bound to a literal is inlined into the uses of its name
+//     that follow it in its scope, if the name is declared nowhere else in the
+//     program. The binding itself is kept.
+func Optimize(program *ast.Program) *ast.Program {
+	o := &optimizer{
+		declarations: make(map[string]int),
+		constants:    make(map[string]ast.Expression),
+	}
+	ast.Inspect(program, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.LetStatement:
+			o.declarations[n.Name.Value]++
+		case *ast.FunctionLiteral:
+			if n.Name != nil {
+				o.declarations[n.Name.Value]++
+			}
+			for _, p := range n.Parameters {
+				o.declarations[p.Value]++
+			}
+			if n.Rest != nil {
+				o.declarations[n.Rest.Value]++
+			}
+		}
+		return n != nil
+	})
+
+	return ast.Rewrite(program, o.optimize).(*ast.Program)
+}
+
+// method called by ast.Rewrite on every node after its children. The
+// statements of a block are rewritten in order, so a constant is known by the
+// time the statements after it are rewritten.
+func (o *optimizer) optimize(node ast.Node) ast.Node {
+	switch node := node.(type) {
+	case *ast.Identifier:
+		if lit, ok := o.constants[node.Value]; ok {
+			return copyLiteral(lit, node.Token)
+		}
+	case *ast.LetStatement:
+		if isLiteral(node.Value) && o.declarations[node.Name.Value] == 1 {
+			o.constants[node.Name.Value] = node.Value
+		}
+	case *ast.PrefixExpression:
+		return foldPrefix(node)
+	case *ast.InfixExpression:
+		return foldInfix(node)
+	case *ast.IfExpression:
+		return pruneIf(node)
+	case *ast.BlockStatement:
+		// the constants of the block go out of scope with it.
+		for _, stmt := range node.Statements {
+			if let, ok := stmt.(*ast.LetStatement); ok {
+				delete(o.constants, let.Name.Value)
+			}
+		}
+		node.Statements = spliceIfs(node.Statements)
+	case *ast.Program:
+		node.Statements = spliceIfs(node.Statements)
+	}
+	return node
+}
+
+// ---------------------------------Literals----------------------------------
+
+// helper function that reports whether exp is an integer, string or boolean literal.
+func isLiteral(exp ast.Expression) bool {
+	switch exp.(type) {
+	case *ast.IntegerLiteral, *ast.StringLiteral, *ast.Boolean:
+		return true
+	}
+	return false
+}
+
+// helper functions that make literals in place of an expression starting at tok.
+
+func newInteger(value int64, tok *token.Token) *ast.IntegerLiteral {
+	lit := strconv.FormatInt(value, 10)
+	return &ast.IntegerLiteral{Token: at(token.INT, lit, tok), Value: value}
+}
+
+func newString(value string, tok *token.Token) *ast.StringLiteral {
+	return &ast.StringLiteral{Token: at(token.STRING, value, tok), Value: value}
+}
+
+func newBoolean(value bool, tok *token.Token) *ast.Boolean {
+	if value {
+		return &ast.Boolean{Token: at(token.TRUE, "true", tok), Value: true}
+	}
+	return &ast.Boolean{Token: at(token.FALSE, "false", tok), Value: false}
+}
+
+// helper function that returns a token with the position of tok.
+func at(tokenType token.TokenType, literal string, tok *token.Token) *token.Token {
+	t := &token.Token{Type: tokenType, Literal: literal}
+	if tok != nil {
+		t.Line, t.Column = tok.Line, tok.Column
+	}
+	return t
+}
+
+// helper function that copies a literal to the position of tok.
+func copyLiteral(exp ast.Expression, tok *token.Token) ast.Expression {
+	switch exp := exp.(type) {
+	case *ast.IntegerLiteral:
+		return newInteger(exp.Value, tok)
+	case *ast.StringLiteral:
+		return newString(exp.Value, tok)
+	case *ast.Boolean:
+		return newBoolean(exp.Value, tok)
+	}
+	return exp
+}
+
+// helper function that returns the token an expression starts with, the
+// first of the tokens kept in it.
+func startToken(exp ast.Expression) *token.Token {
+	var first *token.Token
+	ast.Inspect(exp, func(n ast.Node) bool {
+		for _, tok := range ast.Tokens(n) {
+			if first == nil || tok.Line < first.Line || tok.Line == first.Line && tok.Column < first.Column {
+				first = tok
+			}
+		}
+		return n != nil
+	})
+	return first
+}
+
+// ---------------------------------Folding-----------------------------------
+
+// function that folds ! and - applied to a literal, following
+// evalPrefixExpression: ! of anything but a boolean is false and - only
+// works on integers.
+func foldPrefix(pe *ast.PrefixExpression) ast.Expression {
+	switch right := pe.Right.(type) {
+	case *ast.Boolean:
+		if pe.Operator == "!" {
+			return newBoolean(!right.Value, pe.Token)
+		}
+	case *ast.IntegerLiteral:
+		switch pe.Operator {
+		case "!":
+			return newBoolean(false, pe.Token)
+		case "-":
+			return newInteger(-right.Value, pe.Token)
+		}
+	case *ast.StringLiteral:
+		if pe.Operator == "!" {
+			return newBoolean(false, pe.Token)
+		}
+	}
+	return pe
+}
+
+// function that folds an infix expression of two literals, following
+// evalInfixExpression. Expressions that are errors at runtime, including a
+// division by zero, are left for the evaluator to report.
+func foldInfix(ie *ast.InfixExpression) ast.Expression {
+	if !isLiteral(ie.Left) || !isLiteral(ie.Right) {
+		return ie
+	}
+	tok := startToken(ie.Left)
+
+	switch left := ie.Left.(type) {
+	case *ast.IntegerLiteral:
+		if right, ok := ie.Right.(*ast.IntegerLiteral); ok {
+			return foldIntegers(ie, left.Value, right.Value, tok)
+		}
+	case *ast.StringLiteral:
+		if right, ok := ie.Right.(*ast.StringLiteral); ok {
+			switch ie.Operator {
+			case "+":
+				return newString(left.Value+right.Value, tok)
+			case "==":
+				return newBoolean(left.Value == right.Value, tok)
+			case "!=":
+				return newBoolean(left.Value != right.Value, tok)
+			}
+			return ie
+		}
+	case *ast.Boolean:
+		if right, ok := ie.Right.(*ast.Boolean); ok {
+			switch ie.Operator {
+			case "==":
+				return newBoolean(left.Value == right.Value, tok)
+			case "!=":
+				return newBoolean(left.Value != right.Value, tok)
+			}
+			return ie
+		}
+	}
+
+	// values of different types are never equal.
+	switch ie.Operator {
+	case "==":
+		return newBoolean(false, tok)
+	case "!=":
+		return newBoolean(true, tok)
+	}
+	return ie
+}
+
+// helper function that folds an infix expression of two integers.
+func foldIntegers(ie *ast.InfixExpression, left, right int64, tok *token.Token) ast.Expression {
+	switch ie.Operator {
+	case "+":
+		return newInteger(left+right, tok)
+	case "-":
+		return newInteger(left-right, tok)
+	case "*":
+		return newInteger(left*right, tok)
+	case "/":
+		if right == 0 {
+			return ie
+		}
+		return newInteger(left/right, tok)
+	case "<":
+		return newBoolean(left < right, tok)
+	case ">":
+		return newBoolean(left > right, tok)
+	case "==":
+		return newBoolean(left == right, tok)
+	case "!=":
+		return newBoolean(left != right, tok)
+	}
+	return ie
+}
+
+// -------------------------------If Expressions------------------------------
+
+// helper function that reports whether the condition of an if expression is
+// a literal and if so whether it is truthy. Like isTruthy in the evaluator
+// only false is falsy among the literals.
+func constantCondition(ie *ast.IfExpression) (truthy, ok bool) {
+	if !isLiteral(ie.Condition) {
+		return false, false
+	}
+	if b, isBool := ie.Condition.(*ast.Boolean); isBool {
+		return b.Value, true
+	}
+	return true, true
+}
+
+// function that simplifies an if expression with a literal condition.
+// If the branch that is taken holds a single expression and no declaration
+// the if expression is replaced by it. Otherwise the branch that is not
+// taken is dropped, turning if (false) { a } else { b } into if (true) { b }.
+func pruneIf(ie *ast.IfExpression) ast.Expression {
+	truthy, ok := constantCondition(ie)
+	if !ok {
+		return ie
+	}
+	taken := ie.Alternative
+	if truthy {
+		taken = ie.Consequence
+	}
+
+	if taken != nil && len(taken.Statements) == 1 && !declares(taken) {
+		if es, ok := taken.Statements[0].(*ast.ExpressionStatement); ok && es.Expression != nil {
+			return es.Expression
+		}
+	}
+
+	switch {
+	case truthy:
+		ie.Alternative = nil
+	case ie.Alternative != nil:
+		ie.Condition = newBoolean(true, startToken(ie.Condition))
+		ie.Consequence, ie.Alternative = ie.Alternative, nil
+	default:
+		ie.Consequence = &ast.BlockStatement{Token: ie.Consequence.Token, End: ie.Consequence.End}
+	}
+	return ie
+}
+
+// helper function that reports whether a block binds a name in its own
+// scope, which it would leak into the enclosing scope without its braces.
+func declares(block *ast.BlockStatement) bool {
+	for _, stmt := range block.Statements {
+		switch stmt := stmt.(type) {
+		case *ast.LetStatement:
+			return true
+		case *ast.ExpressionStatement:
+			if fl, ok := stmt.Expression.(*ast.FunctionLiteral); ok && fl.Name != nil {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// function that replaces the if statements left in a list of statements
+// that have a literal condition by the statements of the branch taken.
+// A statement whose value is never used is dropped if no branch is taken.
+// The last statement is the value of the list so an if there has to stay
+// unless its branch has statements to take its place.
+func spliceIfs(stmts []ast.Statement) []ast.Statement {
+	out := []ast.Statement{}
+	for i, stmt := range stmts {
+		es, ok := stmt.(*ast.ExpressionStatement)
+		if !ok {
+			out = append(out, stmt)
+			continue
+		}
+		ie, ok := es.Expression.(*ast.IfExpression)
+		if !ok {
+			out = append(out, stmt)
+			continue
+		}
+		truthy, ok := constantCondition(ie)
+		if !ok {
+			out = append(out, stmt)
+			continue
+		}
+
+		// pruneIf already moved the branch taken to the consequence.
+		var taken []ast.Statement
+		if truthy {
+			taken = ie.Consequence.Statements
+		}
+		last := i == len(stmts)-1
+		switch {
+		case declares(ie.Consequence):
+			out = append(out, stmt)
+		case len(taken) > 0:
+			out = append(out, taken...)
+		case !last:
+			// nothing runs and the value is not used.
+		default:
+			out = append(out, stmt)
+		}
+	}
+	return out
+}
@@ -0,0 +1,63 @@
+package optimizer
+
+import (
+	"testing"
+
+	lexer "github.com/Artypuppet/monkey/lexer"
+	parser "github.com/Artypuppet/monkey/parser"
+)
+
+func TestOptimize(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		// folding
+		{"1 + 2 * 3", "7"},
+		{"(10 - 4) / 2 == 3", "true"},
+		{"1 < 2; 2 > 3; 1 != 1", "truefalsefalse"},
+		{`"foo" + "bar"`, "foobar"},
+		{`"a" == "a"; "a" != "a"`, "truefalse"},
+		{"true == false; true != false", "falsetrue"},
+		{`1 == "1"; true != 1`, "falsetrue"},
+		{"-(2 + 3); !true; !5; !!false", "-5falsefalsefalse"},
+		{"x + 1 * 2", "(x + 2)"},
+		// errors are left for the evaluator.
+		{"5 / 0", "(5 / 0)"},
+		{`1 + "a"; "a" - "b"; true + true; -"a"; -true`, `(1 + a)(a - b)(true + true)(-a)(-true)`},
+		// if expressions with a literal condition.
+		{"if (1 < 2) { 10 } else { 20 }", "10"},
+		{"if (false) { 10 } else { 20 }", "20"},
+		{"if (false) { 10 }", "iffalse "},
+		{"if (false) { 10 }; 5", "5"},
+		{"if (x) { 10 }", "ifx 10"},
+		{"if (true) { f(1); g(2) }; 3", "f(1)g(2)3"},
+		{"fn(x) { if (true) { return x; } 0 }", "fn(x) return x;0"},
+		// blocks that declare names keep their scope.
+		{"if (true) { let y = 1; y }", "iftrue let y = 1;1"},
+		{"if (false) { 1 } else { let y = 2; y + 1 }", "iftrue let y = 2;3"},
+		// let constants are inlined after their declaration.
+		{"let x = 2 * 3; let y = x + 1; y * x", "let x = 6;let y = 7;42"},
+		{`const s = "a"; s + "b"`, "const s = a;ab"},
+		{"let x = 5; let f = fn() { x }; f()", "let x = 5;let f = fn() 5;f()"},
+		{"let f = fn() { x }; let x = 5; f()", "let f = fn() x;let x = 5;f()"},
+		// names declared more than once are never inlined.
+		{"let x = 1; let x = 2; x", "let x = 1;let x = 2;x"},
+		{"let x = 1; let f = fn(x) { x }; x", "let x = 1;let f = fn(x) x;x"},
+		{"let x = [1]; x", "let x = [1];x"},
+		// constants of a block are gone after it.
+		{"if (y) { let x = 1; x }; x", "ify let x = 1;1x"},
+	}
+
+	for _, tt := range tests {
+		p := parser.New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		if len(p.Errors()) != 0 {
+			t.Fatalf("input %q: parser errors: %v", tt.input, p.Errors())
+		}
+		got := Optimize(program).String()
+		if got != tt.expected {
+			t.Errorf("input %q: wrong result.\nwant=%q\ngot= %q", tt.input, tt.expected, got)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	ast "github.com/Artypuppet/monkey/ast"
+)
+
+// function implementing `monkey parse [-json] file`.
+// It prints the syntax tree of the file, as the program's String() by default
+// or as the indented JSON of ast.ToJSON with -json.
+func parseCommand(args []string) int {
+	flags := flag.NewFlagSet("parse", flag.ContinueOnError)
+	asJSON := flags.Bool("json", false, "print the tree as JSON")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: monkey parse [-json] file\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+
+	program := parseFile(flags.Arg(0))
+	if program == nil {
+		return 1
+	}
+	if !*asJSON {
+		fmt.Println(program.String())
+		return 0
+	}
+
+	data, err := ast.ToJSON(program)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "monkey: %s\n", err)
+		return 1
+	}
+	var out bytes.Buffer
+	json.Indent(&out, data, "", "  ")
+	out.WriteByte('\n')
+	os.Stdout.Write(out.Bytes())
+	return 0
+}
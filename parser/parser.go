@@ -27,6 +27,7 @@ type Parser struct {
 	curToken  *token.Token
 	peekToken *token.Token
 	errors    []string
+	positions []Error // the errors of errors with their positions
 	// maps for tokenTypes and their associated parse functions.
 	prefixParseFns map[token.TokenType]prefixParseFn
 	infixParseFns  map[token.TokenType]infixParseFn
@@ -54,6 +55,8 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerPrefix(token.IF, p.parseIfExpression)
 	p.registerPrefix(token.FUNCTION, p.parseFunctionLiteral)
 	p.registerPrefix(token.LBRACKET, p.parseArrayLiteral)
+	p.registerPrefix(token.ELLIPSIS, p.parseSpreadExpression)
+	p.registerPrefix(token.LBRACE, p.parseHashLiteral)
 
 	// Register infix parse fns.
 	// All token types here are associated with the same function
@@ -97,6 +100,7 @@ func (p *Parser) ParseProgram() *ast.Program {
 		}
 		p.nextToken()
 	}
+	program.Comments = p.l.Comments()
 	return program
 }
 
@@ -104,7 +108,7 @@ func (p *Parser) ParseProgram() *ast.Program {
 // parses a statement based on the token type of the cur token.
 func (p *Parser) parseStatement() ast.Statement {
 	switch p.curToken.Type {
-	case token.LET:
+	case token.LET, token.CONST:
 		return p.parseLetStatement()
 	case token.RETURN:
 		return p.parseReturnStatement()
@@ -139,16 +143,40 @@ func (p *Parser) Errors() []string {
 	return p.errors
 }
 
+// struct describing a parse error.
+// Line and Column are the position of the token the error is about.
+type Error struct {
+	Line    int
+	Column  int
+	Message string
+}
+
+func (e Error) String() string {
+	return fmt.Sprintf("%d:%d: %s", e.Line, e.Column, e.Message)
+}
+
+// getter to return the errors with their positions, in the same order as Errors.
+func (p *Parser) PositionedErrors() []Error {
+	return p.positions
+}
+
+// method that appends a new error about tok to the errors slice.
+func (p *Parser) errorAt(tok *token.Token, format string, a ...interface{}) {
+	msg := fmt.Sprintf(format, a...)
+	p.errors = append(p.errors, msg)
+	p.positions = append(p.positions, Error{Line: tok.Line, Column: tok.Column, Message: msg})
+}
+
 // method that appends a new error to the errors slice for nextToken.
 func (p *Parser) peekError(t token.TokenType) {
-	msg := fmt.Sprintf("expected next token to be %s, got %s instead",
+	p.errorAt(p.peekToken, "expected next token to be %s, got %s instead",
 		t, p.peekToken.Type)
-	p.errors = append(p.errors, msg)
 }
 
 // ------------------------------Let Statement Parsing---------------------------------
 
 // function to parse a let statement.
+// const statements have the same shape and are parsed by it as well.
 // It creates a LetStatement struct
 // and then checks if it is followed by an identifier
 // and if the identifier is followed by an assignment sign
@@ -159,6 +187,9 @@ func (p *Parser) parseLetStatement() *ast.LetStatement {
 		return nil
 	}
 	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	if !p.parseOptionalTypeAnnotation(stmt.Name) {
+		return nil
+	}
 
 	if !p.expectPeek(token.ASSIGN) {
 		return nil
@@ -226,6 +257,15 @@ var precedences = map[token.TokenType]int{
 	token.LBRACKET: INDEX,
 }
 
+// function that returns the precedence of an operator token when it is
+// used as an infix operator, or LOWEST if it isn't one.
+func Precedence(tokenType token.TokenType) int {
+	if p, ok := precedences[tokenType]; ok {
+		return p
+	}
+	return LOWEST
+}
+
 // helper method to check the precendence of the next Token
 // While parsing infix expression we first encounter the left expression
 // we peek to the next Token to get its precedence.
@@ -259,8 +299,7 @@ func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
 
 // method to handle prefix parsing errors.
 func (p *Parser) noPrefixParseFnError(t token.TokenType) {
-	msg := fmt.Sprintf("no prefix parse function for %s found", t)
-	p.errors = append(p.errors, msg)
+	p.errorAt(p.curToken, "no prefix parse function for %s found", t)
 }
 
 // method that parses expressions.
@@ -295,8 +334,7 @@ func (p *Parser) parseIdentifier() ast.Expression {
 func (p *Parser) parseIntegerLiteral() ast.Expression {
 	val, err := strconv.ParseInt(p.curToken.Literal, 0, 64)
 	if err != nil {
-		msg := fmt.Sprintf("could not parse %q as integer", p.curToken.Literal)
-		p.errors = append(p.errors, msg)
+		p.errorAt(p.curToken, "could not parse %q as integer", p.curToken.Literal)
 		return nil
 	}
 	return &ast.IntegerLiteral{Token: p.curToken, Value: val}
@@ -413,6 +451,9 @@ func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 		}
 		p.nextToken()
 	}
+	if p.curTokenIs(token.RBRACE) {
+		block.End = p.curToken
+	}
 	return block
 }
 
@@ -432,13 +473,30 @@ func (p *Parser) parseFunctionLiteral() ast.Expression {
 
 	lit := &ast.FunctionLiteral{Token: p.curToken}
 
+	// the name of a named function e.g. fn add(x, y) { x + y }
+	if p.peekTokenIs(token.IDENT) {
+		p.nextToken()
+		lit.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	}
+
 	// move forward the token if expectPeek is true curToken is IF at this point
 	if !p.expectPeek(token.LPAREN) {
 		return nil
 	}
 
 	// curToken is now '('
-	lit.Parameters = p.parseFunctionParameters()
+	if !p.parseFunctionParameters(lit) {
+		return nil
+	}
+
+	// the optional return type e.g. fn(x: int): int { x }
+	if p.peekTokenIs(token.COLON) {
+		p.nextToken()
+		p.nextToken()
+		if lit.ReturnType = p.parseTypeAnnotation(); lit.ReturnType == nil {
+			return nil
+		}
+	}
 
 	if !p.expectPeek(token.LBRACE) {
 		return nil
@@ -449,32 +507,143 @@ func (p *Parser) parseFunctionLiteral() ast.Expression {
 	return lit
 }
 
-func (p *Parser) parseFunctionParameters() []*ast.Identifier {
-
-	parameters := []*ast.Identifier{}
+// function that parses the parameter list of a function literal into lit.
+// Each parameter is an identifier optionally followed by '= default' and
+// the last one may instead be a '...rest' parameter. Once a parameter has
+// a default value all the following ones must have one as well.
+// It returns false if the parameter list could not be parsed.
+func (p *Parser) parseFunctionParameters(lit *ast.FunctionLiteral) bool {
 
 	if p.peekTokenIs(token.RPAREN) {
 		p.nextToken()
-		return nil
+		return true
 	}
 
-	p.nextToken()
+	hasDefaults := false
+	for {
+		// skip the '(' or ',' so that curToken is the start of the next parameter
+		p.nextToken()
+
+		if p.curTokenIs(token.ELLIPSIS) {
+			if !p.expectPeek(token.IDENT) {
+				return false
+			}
+			lit.Rest = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+			if !p.parseOptionalTypeAnnotation(lit.Rest) {
+				return false
+			}
+			// the rest parameter has to be the last one.
+			return p.expectPeek(token.RPAREN)
+		}
 
-	// curToken is now some identifier
-	identifier := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
-	parameters = append(parameters, identifier)
+		if !p.curTokenIs(token.IDENT) {
+			p.errorAt(p.curToken, "expected parameter name, got %s instead", p.curToken.Type)
+			return false
+		}
+		param := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+		lit.Parameters = append(lit.Parameters, param)
+		if !p.parseOptionalTypeAnnotation(param) {
+			return false
+		}
 
-	for p.peekTokenIs(token.COMMA) {
-		// move the curToken such that we skip the comma and then curToken is again some identifier
-		p.nextToken()
+		var def ast.Expression
+		if p.peekTokenIs(token.ASSIGN) {
+			p.nextToken()
+			p.nextToken()
+			def = p.parseExpression(LOWEST)
+			hasDefaults = true
+		} else if hasDefaults {
+			p.errorAt(param.Token, "parameter %s without default follows a parameter with default", param.Value)
+			return false
+		}
+		lit.Defaults = append(lit.Defaults, def)
+
+		if !p.peekTokenIs(token.COMMA) {
+			break
+		}
 		p.nextToken()
-		parameters = append(parameters, &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal})
 	}
 
-	if !p.expectPeek(token.RPAREN) {
+	return p.expectPeek(token.RPAREN)
+}
+
+// -------------------------------Parse Type Annotation------------------------------
+
+// function that parses the type annotation of ident if the next token is ':'
+// It returns false if there is an annotation that could not be parsed.
+func (p *Parser) parseOptionalTypeAnnotation(ident *ast.Identifier) bool {
+	if !p.peekTokenIs(token.COLON) {
+		return true
+	}
+	p.nextToken()
+	p.nextToken()
+	ident.Type = p.parseTypeAnnotation()
+	return ident.Type != nil
+}
+
+// map of the named types that can be used in annotations.
+var typeNames = map[string]bool{
+	"int":    true,
+	"string": true,
+	"bool":   true,
+	"null":   true,
+	"any":    true,
+}
+
+// function that parses a type annotation starting at curToken.
+// An annotation is either a named type, [T] for arrays of T or
+// fn(T, U): R for functions where the return type is optional.
+func (p *Parser) parseTypeAnnotation() *ast.TypeAnnotation {
+	ta := &ast.TypeAnnotation{Token: p.curToken}
+
+	switch p.curToken.Type {
+	case token.IDENT:
+		if !typeNames[p.curToken.Literal] {
+			p.errorAt(p.curToken, "unknown type %s", p.curToken.Literal)
+			return nil
+		}
+		ta.Name = p.curToken.Literal
+	case token.LBRACKET:
+		ta.Name = "array"
+		p.nextToken()
+		if ta.Elem = p.parseTypeAnnotation(); ta.Elem == nil {
+			return nil
+		}
+		if !p.expectPeek(token.RBRACKET) {
+			return nil
+		}
+	case token.FUNCTION:
+		ta.Name = "fn"
+		if !p.expectPeek(token.LPAREN) {
+			return nil
+		}
+		for !p.peekTokenIs(token.RPAREN) {
+			p.nextToken()
+			param := p.parseTypeAnnotation()
+			if param == nil {
+				return nil
+			}
+			ta.Params = append(ta.Params, param)
+			if !p.peekTokenIs(token.COMMA) {
+				break
+			}
+			p.nextToken()
+		}
+		if !p.expectPeek(token.RPAREN) {
+			return nil
+		}
+		if p.peekTokenIs(token.COLON) {
+			p.nextToken()
+			p.nextToken()
+			if ta.Return = p.parseTypeAnnotation(); ta.Return == nil {
+				return nil
+			}
+		}
+	default:
+		p.errorAt(p.curToken, "expected a type, got %s instead", p.curToken.Type)
 		return nil
 	}
-	return parameters
+	return ta
 }
 
 // -------------------------------------Parse Call Expression------------------------
@@ -486,6 +655,9 @@ func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
 	// when this fn is called the curToken is '('
 	callExp := &ast.CallExpression{Token: p.curToken, Function: function}
 	callExp.Arguments = p.parseExpressionList(token.RPAREN)
+	if p.curTokenIs(token.RPAREN) {
+		callExp.End = p.curToken
+	}
 	return callExp
 }
 
@@ -520,6 +692,9 @@ func (p *Parser) parseArrayLiteral() ast.Expression {
 	array := &ast.ArrayLiteral{Token: p.curToken}
 
 	array.Elements = p.parseExpressionList(token.RBRACKET)
+	if p.curTokenIs(token.RBRACKET) {
+		array.End = p.curToken
+	}
 	return array
 }
 
@@ -548,6 +723,17 @@ func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
 	return list
 }
 
+// This function parses a spread expression e.g. ...args
+// The value is only checked to be an array once it is evaluated.
+func (p *Parser) parseSpreadExpression() ast.Expression {
+	exp := &ast.SpreadExpression{Token: p.curToken}
+
+	p.nextToken()
+	exp.Value = p.parseExpression(LOWEST)
+
+	return exp
+}
+
 // This function parses the index expression for arrays.
 // It is called for an infix expression. The expression
 // between [] should produce an integer.
@@ -563,3 +749,33 @@ func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
 
 	return exp
 }
+
+// This function parses a hash literal e.g. {"one": 1, "two": 2}
+// The curToken is '{' when it is called. Keys and values can be any
+// expression, whether a key can be hashed is only known once it is evaluated.
+func (p *Parser) parseHashLiteral() ast.Expression {
+	hash := &ast.HashLiteral{Token: p.curToken}
+
+	for !p.peekTokenIs(token.RBRACE) {
+		p.nextToken()
+		key := p.parseExpression(LOWEST)
+
+		if !p.expectPeek(token.COLON) {
+			return nil
+		}
+
+		p.nextToken()
+		value := p.parseExpression(LOWEST)
+		hash.Pairs = append(hash.Pairs, ast.HashPair{Key: key, Value: value})
+
+		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+
+	if !p.expectPeek(token.RBRACE) {
+		return nil
+	}
+	hash.End = p.curToken
+	return hash
+}
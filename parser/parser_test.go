@@ -2,6 +2,7 @@ package parser
 
 import (
 	"fmt"
+	"reflect"
 	"testing"
 
 	ast "github.com/Artypuppet/monkey/ast"
@@ -878,3 +879,340 @@ func TestParsingIndexExpressions(t *testing.T) {
 		return
 	}
 }
+
+func TestFunctionDefaultAndRestParameters(t *testing.T) {
+	tests := []struct {
+		input            string
+		expectedParams   []string
+		expectedDefaults []string
+		expectedRest     string
+		expectedString   string
+	}{
+		{
+			input:            "fn(x, y = 2) {};",
+			expectedParams:   []string{"x", "y"},
+			expectedDefaults: []string{"", "2"},
+			expectedString:   "fn(x, y = 2) ",
+		},
+		{
+			input:            "fn(x = 1 + 1, y = x) {};",
+			expectedParams:   []string{"x", "y"},
+			expectedDefaults: []string{"(1 + 1)", "x"},
+			expectedString:   "fn(x = (1 + 1), y = x) ",
+		},
+		{
+			input:            "fn(x, ...rest) {};",
+			expectedParams:   []string{"x"},
+			expectedDefaults: []string{""},
+			expectedRest:     "rest",
+			expectedString:   "fn(x, ...rest) ",
+		},
+		{
+			input:          "fn(...args) { args };",
+			expectedRest:   "args",
+			expectedString: "fn(...args) args",
+		},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		function := stmt.Expression.(*ast.FunctionLiteral)
+
+		if len(function.Parameters) != len(tt.expectedParams) {
+			t.Fatalf("length parameters wrong. want %d, got=%d\n",
+				len(tt.expectedParams), len(function.Parameters))
+		}
+		for i, ident := range tt.expectedParams {
+			testLiteralExpression(t, function.Parameters[i], ident)
+			def := function.Defaults[i]
+			if tt.expectedDefaults[i] == "" && def != nil {
+				t.Errorf("parameter %d has unexpected default %q", i, def.String())
+			} else if tt.expectedDefaults[i] != "" && (def == nil || def.String() != tt.expectedDefaults[i]) {
+				t.Errorf("parameter %d default wrong. want=%q, got=%v", i, tt.expectedDefaults[i], def)
+			}
+		}
+		if tt.expectedRest == "" && function.Rest != nil {
+			t.Errorf("unexpected rest parameter %q", function.Rest.Value)
+		} else if tt.expectedRest != "" && (function.Rest == nil || function.Rest.Value != tt.expectedRest) {
+			t.Errorf("rest parameter wrong. want=%q, got=%v", tt.expectedRest, function.Rest)
+		}
+		if function.String() != tt.expectedString {
+			t.Errorf("function.String() wrong. want=%q, got=%q", tt.expectedString, function.String())
+		}
+	}
+}
+
+func TestInvalidFunctionParameters(t *testing.T) {
+	tests := []string{
+		"fn(x = 1, y) {}",
+		"fn(...rest, x) {}",
+		"fn(1) {}",
+	}
+	for _, input := range tests {
+		p := New(lexer.New(input))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 {
+			t.Errorf("expected parser errors for %q", input)
+		}
+	}
+}
+
+func TestSpreadExpressionParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"add(...args)", "add(...args)"},
+		{"add(1, ...rest(xs))", "add(1, ...rest(xs))"},
+		{"[0, ...xs, 4]", "[0, ...xs, 4]"},
+	}
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+}
+
+func TestNamedFunctionLiteralParsing(t *testing.T) {
+	tests := []struct {
+		input        string
+		expectedName string
+		expected     string
+	}{
+		{"fn add(x, y) { x + y; }", "add", "fn add(x, y) (x + y)"},
+		{"let f = fn fact(n) { n };", "fact", "let f = fn fact(n) n;"},
+		{"fn(x) { x }", "", "fn(x) x"},
+	}
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		var function *ast.FunctionLiteral
+		switch stmt := program.Statements[0].(type) {
+		case *ast.ExpressionStatement:
+			function = stmt.Expression.(*ast.FunctionLiteral)
+		case *ast.LetStatement:
+			function = stmt.Value.(*ast.FunctionLiteral)
+		}
+		if tt.expectedName == "" && function.Name != nil {
+			t.Errorf("function has unexpected name %q", function.Name.Value)
+		} else if tt.expectedName != "" && !testIdentifier(t, function.Name, tt.expectedName) {
+			continue
+		}
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+}
+
+func TestConstStatements(t *testing.T) {
+	input := "const x = 5;"
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt, ok := program.Statements[0].(*ast.LetStatement)
+	if !ok {
+		t.Fatalf("stmt not *ast.LetStatement. got=%T", program.Statements[0])
+	}
+	if !stmt.IsConst() {
+		t.Errorf("stmt.IsConst() is false for %q", input)
+	}
+	if !testLiteralExpression(t, stmt.Value, 5) {
+		return
+	}
+	if program.String() != input {
+		t.Errorf("program.String() wrong. want=%q, got=%q", input, program.String())
+	}
+}
+
+func TestTypeAnnotationParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let x: int = 5;", "let x: int = 5;"},
+		{"let xs: [string] = [];", "let xs: [string] = [];"},
+		{"let f: fn(int, [bool]): any = g;", "let f: fn(int, [bool]): any = g;"},
+		{"let f: fn() = g;", "let f: fn() = g;"},
+		{"fn(a: int, b: string): bool { true }", "fn(a: int, b: string): bool true"},
+		{"fn(a: int = 1, ...rest: [int]) { a }", "fn(a: int = 1, ...rest: [int]) a"},
+		{"fn add(a, b): int { a + b }", "fn add(a, b): int (a + b)"},
+		{"fn(f: fn(int): int): null { f(1) }", "fn(f: fn(int): int): null f(1)"},
+	}
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+
+	program := New(lexer.New("let x: [int] = 1;")).ParseProgram()
+	name := program.Statements[0].(*ast.LetStatement).Name
+	if name.Value != "x" || name.Type == nil || name.Type.Name != "array" || name.Type.Elem.Name != "int" {
+		t.Errorf("wrong annotation on let name. got=%+v", name)
+	}
+}
+
+func TestInvalidTypeAnnotations(t *testing.T) {
+	tests := []string{
+		"let x: float = 1;",
+		"let x: = 1;",
+		"let x: [int = [];",
+		"fn(a: 1) {}",
+		"fn(a): {}",
+	}
+	for _, input := range tests {
+		p := New(lexer.New(input))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 {
+			t.Errorf("expected parser errors for %q", input)
+		}
+	}
+}
+
+func TestParsingHashLiterals(t *testing.T) {
+	input := `{"one": 1, "two": 2, "three": 3}`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	hash, ok := stmt.Expression.(*ast.HashLiteral)
+	if !ok {
+		t.Fatalf("exp is not ast.HashLiteral. got=%T", stmt.Expression)
+	}
+
+	expected := []struct {
+		key   string
+		value int64
+	}{{"one", 1}, {"two", 2}, {"three", 3}}
+	if len(hash.Pairs) != len(expected) {
+		t.Fatalf("hash.Pairs has wrong length. got=%d", len(hash.Pairs))
+	}
+	for i, pair := range hash.Pairs {
+		literal, ok := pair.Key.(*ast.StringLiteral)
+		if !ok {
+			t.Errorf("key is not ast.StringLiteral. got=%T", pair.Key)
+			continue
+		}
+		if literal.Value != expected[i].key {
+			t.Errorf("pair %d has wrong key. want=%q, got=%q", i, expected[i].key, literal.Value)
+		}
+		testIntegerLiteral(t, pair.Value, expected[i].value)
+	}
+}
+
+func TestParsingHashLiteralExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"{}", "{}"},
+		{`{"one": 0 + 1, true: 15 / 5, 3: x}`, "{one: (0 + 1), true: (15 / 5), 3: x}"},
+		{`let h = {"a": [1], "b": {"c": fn(x) { x }}};`, "let h = {a: [1], b: {c: fn(x) x}};"},
+	}
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+}
+
+func TestInvalidHashLiterals(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`{"a" 1}`, "expected next token to be :, got INT instead"},
+		{`{"a": 1 "b": 2}`, "expected next token to be ,, got STRING instead"},
+		{`{"a": 1,`, "no prefix parse function for EOF found"},
+	}
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 || p.Errors()[0] != tt.expected {
+			t.Errorf("input %q: wrong errors. want first=%q, got=%v", tt.input, tt.expected, p.Errors())
+		}
+	}
+}
+
+func TestASTJSONRoundTrip(t *testing.T) {
+	input := `// constants
+let x: [int] = [1, ...rest, {"k": -v}];
+const f = fn g(a: fn(int): bool, b = 2, ...c): int {
+	if (a(b) == !true) { return c[0] * 3; } else { h() }
+};
+f(x)`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	data, err := ast.ToJSON(program)
+	if err != nil {
+		t.Fatalf("ToJSON failed: %s", err)
+	}
+	node, err := ast.FromJSON(data)
+	if err != nil {
+		t.Fatalf("FromJSON failed: %s", err)
+	}
+	if !reflect.DeepEqual(node, program) {
+		t.Errorf("decoded program differs.\nexpected=%s\ngot=     %s", program, node)
+	}
+
+}
+
+func TestPositionedErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"let = 5;", []string{"1:5: expected next token to be IDENT, got = instead", "1:5: no prefix parse function for = found"}},
+		{"let x: foo = 1;", []string{"1:8: unknown type foo", "1:12: no prefix parse function for = found"}},
+		{"\n  fn(a = 1, b) {}", []string{
+			"2:13: parameter b without default follows a parameter with default",
+			"2:14: no prefix parse function for ) found",
+		}},
+	}
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+		errors := p.PositionedErrors()
+		if len(errors) != len(p.Errors()) {
+			t.Errorf("%q: got %d positioned errors for %d errors", tt.input, len(errors), len(p.Errors()))
+		}
+		got := []string{}
+		for _, e := range errors {
+			got = append(got, e.String())
+		}
+		if !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("wrong errors for %q.\nexpected=%q\ngot=     %q", tt.input, tt.expected, got)
+		}
+	}
+}
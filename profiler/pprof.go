@@ -0,0 +1,217 @@
+package profiler
+
+import (
+	"compress/gzip"
+	"io"
+	"strings"
+)
+
+// the names and units of the values of a sample.
+var sampleTypeNames = [sampleTypes][2]string{
+	callsValue:        {"calls", "count"},
+	cpuValue:          {"cpu", "nanoseconds"},
+	allocObjectsValue: {"alloc_objects", "count"},
+	allocSpaceValue:   {"alloc_space", "bytes"},
+}
+
+// the field numbers of profile.proto, the format read by `go tool pprof`.
+const (
+	profileSampleType        = 1
+	profileSample            = 2
+	profileLocation          = 4
+	profileFunction          = 5
+	profileStringTable       = 6
+	profileTimeNanos         = 9
+	profileDurationNanos     = 10
+	profilePeriodType        = 11
+	profilePeriod            = 12
+	profileDefaultSampleType = 14
+
+	valueTypeType = 1
+	valueTypeUnit = 2
+
+	sampleLocationID = 1
+	sampleValue      = 2
+
+	locationID   = 1
+	locationLine = 4
+
+	lineFunctionID = 1
+	lineLine       = 2
+
+	functionID         = 1
+	functionName       = 2
+	functionSystemName = 3
+	functionFilename   = 4
+	functionStartLine  = 5
+)
+
+// method that writes the profile to w as a gzipped pprof protobuf. Every
+// line of a Monkey function is a location, so pprof can show both the
+// functions and the lines the time went to.
+func (p *Profiler) WriteProfile(w io.Writer) error {
+	table := newStringTable()
+	var b protoBuffer
+
+	for _, names := range sampleTypeNames {
+		b.message(profileSampleType, func(b *protoBuffer) {
+			b.int64Field(valueTypeType, table.index(names[0]))
+			b.int64Field(valueTypeUnit, table.index(names[1]))
+		})
+	}
+
+	type location struct {
+		fn   *function
+		line int
+	}
+	locations := map[location]uint64{}
+	functions := []*function{}
+	seen := map[*function]bool{}
+	for _, s := range p.order {
+		ids := []uint64{}
+		for _, f := range s.stack {
+			loc := location{f.fn, f.line}
+			if _, ok := locations[loc]; !ok {
+				locations[loc] = uint64(len(locations) + 1)
+				b.message(profileLocation, func(b *protoBuffer) {
+					b.uint64Field(locationID, locations[loc])
+					b.message(locationLine, func(b *protoBuffer) {
+						b.uint64Field(lineFunctionID, f.fn.id)
+						b.int64Field(lineLine, int64(f.line))
+					})
+				})
+			}
+			if !seen[f.fn] {
+				seen[f.fn] = true
+				functions = append(functions, f.fn)
+			}
+			ids = append(ids, locations[loc])
+		}
+		b.message(profileSample, func(b *protoBuffer) {
+			b.packedUint64s(sampleLocationID, ids)
+			b.packedInt64s(sampleValue, s.values[:])
+		})
+	}
+
+	for _, f := range functions {
+		// pprof drops what is between angle brackets, like C++ templates,
+		// so <program> and <anonymous> lose theirs.
+		name := strings.TrimSuffix(strings.TrimPrefix(f.name, "<"), ">")
+		b.message(profileFunction, func(b *protoBuffer) {
+			b.uint64Field(functionID, f.id)
+			b.int64Field(functionName, table.index(name))
+			b.int64Field(functionSystemName, table.index(name))
+			b.int64Field(functionFilename, table.index(p.path))
+			b.int64Field(functionStartLine, int64(f.startLine))
+		})
+	}
+
+	b.int64Field(profileTimeNanos, p.start.UnixNano())
+	b.int64Field(profileDurationNanos, int64(p.duration))
+	b.message(profilePeriodType, func(b *protoBuffer) {
+		b.int64Field(valueTypeType, table.index(sampleTypeNames[cpuValue][0]))
+		b.int64Field(valueTypeUnit, table.index(sampleTypeNames[cpuValue][1]))
+	})
+	b.int64Field(profilePeriod, 1)
+	b.int64Field(profileDefaultSampleType, table.index(sampleTypeNames[cpuValue][0]))
+	// the string table goes last since the fields above fill it.
+	for _, s := range table.strings {
+		b.stringField(profileStringTable, s)
+	}
+
+	zw := gzip.NewWriter(w)
+	if _, err := zw.Write(b.data); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// struct defining the string table of a profile, whose first string has to
+// be empty.
+type stringTable struct {
+	strings []string
+	indices map[string]int64
+}
+
+func newStringTable() *stringTable {
+	return &stringTable{strings: []string{""}, indices: map[string]int64{"": 0}}
+}
+
+func (t *stringTable) index(s string) int64 {
+	i, ok := t.indices[s]
+	if !ok {
+		i = int64(len(t.strings))
+		t.strings = append(t.strings, s)
+		t.indices[s] = i
+	}
+	return i
+}
+
+// ---------------------------------Protobuf----------------------------------
+
+// struct defining a buffer the protobuf encoding of a message is written to.
+// Fields with a zero value are left out like proto3 does.
+type protoBuffer struct {
+	data []byte
+}
+
+// the wire types used by profile.proto.
+const (
+	wireVarint = 0
+	wireBytes  = 2
+)
+
+func (b *protoBuffer) varint(x uint64) {
+	for x >= 0x80 {
+		b.data = append(b.data, byte(x)|0x80)
+		x >>= 7
+	}
+	b.data = append(b.data, byte(x))
+}
+
+func (b *protoBuffer) tag(field, wire int) {
+	b.varint(uint64(field)<<3 | uint64(wire))
+}
+
+func (b *protoBuffer) uint64Field(field int, x uint64) {
+	if x == 0 {
+		return
+	}
+	b.tag(field, wireVarint)
+	b.varint(x)
+}
+
+func (b *protoBuffer) int64Field(field int, x int64) {
+	b.uint64Field(field, uint64(x))
+}
+
+func (b *protoBuffer) stringField(field int, s string) {
+	b.tag(field, wireBytes)
+	b.varint(uint64(len(s)))
+	b.data = append(b.data, s...)
+}
+
+func (b *protoBuffer) packedUint64s(field int, xs []uint64) {
+	b.message(field, func(b *protoBuffer) {
+		for _, x := range xs {
+			b.varint(x)
+		}
+	})
+}
+
+func (b *protoBuffer) packedInt64s(field int, xs []int64) {
+	b.message(field, func(b *protoBuffer) {
+		for _, x := range xs {
+			b.varint(uint64(x))
+		}
+	})
+}
+
+// method that writes a length delimited field whose content is written by f.
+func (b *protoBuffer) message(field int, f func(b *protoBuffer)) {
+	var inner protoBuffer
+	f(&inner)
+	b.tag(field, wireBytes)
+	b.varint(uint64(len(inner.data)))
+	b.data = append(b.data, inner.data...)
+}
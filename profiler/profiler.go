@@ -0,0 +1,183 @@
+package profiler
+
+import (
+	"runtime/metrics"
+	"strconv"
+	"strings"
+	"time"
+
+	ast "github.com/Artypuppet/monkey/ast"
+	evaluator "github.com/Artypuppet/monkey/evaluator"
+	object "github.com/Artypuppet/monkey/object"
+)
+
+// struct defining an instrumenting profiler. It is the evaluator.Hook of
+// the program it profiles and charges the time and the memory allocated
+// between two events of the evaluation to the Monkey call stack at that
+// moment, down to the line each call is at.
+type Profiler struct {
+	path      string
+	functions map[*ast.BlockStatement]*function
+	program   *function
+	stack     []frame            // innermost last
+	samples   map[string]*sample // by the key of their stack
+	order     []*sample          // the samples in the order they were made
+
+	// the clock and the allocation counters, replaced in tests.
+	now    func() time.Time
+	allocs func() (objects, bytes int64)
+
+	start, last    time.Time
+	objects, bytes int64
+	duration       time.Duration
+}
+
+// struct defining a Monkey function, or the program itself.
+type function struct {
+	id        uint64
+	name      string
+	startLine int
+}
+
+// struct defining a call on the stack and the line it is at.
+type frame struct {
+	fn   *function
+	line int
+}
+
+// struct holding what was measured for one call stack. The stack is stored
+// innermost first like the locations of a pprof sample.
+type sample struct {
+	stack  []frame
+	values [sampleTypes]int64
+}
+
+// the values of a sample, in the order of sampleTypeNames.
+const (
+	callsValue = iota
+	cpuValue
+	allocObjectsValue
+	allocSpaceValue
+	sampleTypes
+)
+
+// constructor for a profiler of the program in the file at path.
+func New(path string) *Profiler {
+	p := &Profiler{
+		path:      path,
+		functions: make(map[*ast.BlockStatement]*function),
+		samples:   make(map[string]*sample),
+		now:       time.Now,
+		allocs:    readAllocs,
+	}
+	p.program = &function{id: 1, name: "<program>", startLine: 1}
+	return p
+}
+
+// method that starts measuring. The time before it is not charged to any call.
+func (p *Profiler) Start() {
+	p.stack = []frame{{fn: p.program}}
+	p.start = p.now()
+	p.last = p.start
+	p.objects, p.bytes = p.allocs()
+}
+
+// method that stops measuring and charges what is left to the stack.
+func (p *Profiler) Stop() {
+	p.charge()
+	p.duration = p.last.Sub(p.start)
+}
+
+// -----------------------------------Hook------------------------------------
+
+// method implementing evaluator.Hook. It follows the line the innermost call
+// is at, which is the line of its current statement or call.
+func (p *Profiler) Before(node ast.Node, env *object.Environment) *object.Error {
+	var line int
+	switch node := node.(type) {
+	case *ast.LetStatement:
+		line = node.Token.Line
+	case *ast.ReturnStatement:
+		line = node.Token.Line
+	case *ast.ExpressionStatement:
+		line = node.Token.Line
+	case *ast.CallExpression:
+		line = node.Token.Line
+	default:
+		return nil
+	}
+	if top := &p.stack[len(p.stack)-1]; top.line != line {
+		p.charge()
+		top.line = line
+	}
+	return nil
+}
+
+// method implementing evaluator.Hook that pushes the call and counts it.
+func (p *Profiler) EnterCall(fn *object.Function, args []object.Object, env *object.Environment) {
+	p.charge()
+	f, ok := p.functions[fn.Body]
+	if !ok {
+		f = &function{
+			id:        uint64(len(p.functions) + 2),
+			name:      evaluator.FunctionName(fn),
+			startLine: fn.Body.Token.Line,
+		}
+		p.functions[fn.Body] = f
+	}
+	p.stack = append(p.stack, frame{fn: f, line: f.startLine})
+	p.current().values[callsValue]++
+}
+
+// method implementing evaluator.Hook that pops the call.
+func (p *Profiler) ExitCall(fn *object.Function, result object.Object) {
+	p.charge()
+	p.stack = p.stack[:len(p.stack)-1]
+}
+
+// --------------------------------Measuring----------------------------------
+
+// method that charges the time and the allocations since the last charge to
+// the current stack.
+func (p *Profiler) charge() {
+	now := p.now()
+	objects, bytes := p.allocs()
+	s := p.current()
+	s.values[cpuValue] += int64(now.Sub(p.last))
+	s.values[allocObjectsValue] += objects - p.objects
+	s.values[allocSpaceValue] += bytes - p.bytes
+	p.last, p.objects, p.bytes = now, objects, bytes
+}
+
+// method that returns the sample of the current stack, making it if needed.
+func (p *Profiler) current() *sample {
+	var key strings.Builder
+	for _, f := range p.stack {
+		key.WriteString(strconv.FormatUint(f.fn.id, 10))
+		key.WriteByte(':')
+		key.WriteString(strconv.Itoa(f.line))
+		key.WriteByte(' ')
+	}
+	s, ok := p.samples[key.String()]
+	if !ok {
+		stack := make([]frame, len(p.stack))
+		for i, f := range p.stack {
+			stack[len(stack)-1-i] = f
+		}
+		s = &sample{stack: stack}
+		p.samples[key.String()] = s
+		p.order = append(p.order, s)
+	}
+	return s
+}
+
+// the runtime metrics counting the allocations of the process.
+var allocMetrics = []string{"/gc/heap/allocs:objects", "/gc/heap/allocs:bytes"}
+
+// function that returns how many heap objects and bytes the process
+// allocated so far.
+func readAllocs() (int64, int64) {
+	m := []metrics.Sample{{Name: allocMetrics[0]}, {Name: allocMetrics[1]}}
+	metrics.Read(m)
+	return int64(m[0].Value.Uint64()), int64(m[1].Value.Uint64())
+}
@@ -0,0 +1,161 @@
+package profiler
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	evaluator "github.com/Artypuppet/monkey/evaluator"
+	lexer "github.com/Artypuppet/monkey/lexer"
+	object "github.com/Artypuppet/monkey/object"
+	parser "github.com/Artypuppet/monkey/parser"
+)
+
+const testProgram = `let add = fn(a, b) {
+  a + b
+};
+let twice = fn(x) {
+  let y = add(x, x);
+  add(y, 0)
+};
+let z = twice(1);
+add(z, 2);`
+
+// helper function that profiles testProgram with a clock moving one
+// millisecond and 2 allocations of 16 bytes every time it is read.
+func profile(t *testing.T) *Profiler {
+	t.Helper()
+	p := parser.New(lexer.New(testProgram))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+
+	prof := New("test.mk")
+	clock := time.Unix(0, 0)
+	prof.now = func() time.Time {
+		clock = clock.Add(time.Millisecond)
+		return clock
+	}
+	var objects int64
+	prof.allocs = func() (int64, int64) {
+		objects += 2
+		return objects, objects * 16
+	}
+
+	prof.Start()
+	ip := &evaluator.Interpreter{Hook: prof}
+	if result := ip.Eval(program, object.NewEnvironment()); result.Inspect() != "4" {
+		t.Fatalf("wrong result. expected=4, got=%s", result.Inspect())
+	}
+	prof.Stop()
+	return prof
+}
+
+func TestProfiler(t *testing.T) {
+	prof := profile(t)
+
+	calls := map[string]int64{}
+	var cpu, objects, space int64
+	for _, s := range prof.order {
+		names := []string{}
+		for _, f := range s.stack {
+			names = append(names, fmt.Sprintf("%s:%d", f.fn.name, f.line))
+		}
+		if s.values[callsValue] != 0 {
+			calls[strings.Join(names, " ")] += s.values[callsValue]
+		}
+		cpu += s.values[cpuValue]
+		objects += s.values[allocObjectsValue]
+		space += s.values[allocSpaceValue]
+	}
+
+	// the tail call to add in twice takes the place of twice on the stack.
+	expected := map[string]int64{
+		"twice:4 <program>:8":       1,
+		"add:1 twice:5 <program>:8": 1,
+		"add:1 <program>:8":         1,
+		"add:1 <program>:9":         1,
+	}
+	if !reflect.DeepEqual(calls, expected) {
+		t.Errorf("wrong calls.\nexpected=%v\ngot=%v", expected, calls)
+	}
+	if cpu != int64(prof.duration) || cpu == 0 {
+		t.Errorf("the time charged is not the duration. cpu=%d, duration=%d", cpu, prof.duration)
+	}
+	if objects == 0 || space != objects*16 {
+		t.Errorf("wrong allocations. objects=%d, space=%d", objects, space)
+	}
+}
+
+func TestWriteProfile(t *testing.T) {
+	prof := profile(t)
+	var buf bytes.Buffer
+	if err := prof.WriteProfile(&buf); err != nil {
+		t.Fatalf("WriteProfile failed: %s", err)
+	}
+	zr, err := gzip.NewReader(&buf)
+	if err != nil {
+		t.Fatalf("the profile is not gzipped: %s", err)
+	}
+	data, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the top level fields are decoded to count them and read the strings.
+	counts := map[uint64]int{}
+	table := []string{}
+	for len(data) > 0 {
+		key, n := readVarint(data)
+		data = data[n:]
+		field, wire := key>>3, key&7
+		counts[field]++
+		switch wire {
+		case wireVarint:
+			_, n = readVarint(data)
+			data = data[n:]
+		case wireBytes:
+			length, n := readVarint(data)
+			value := data[n : n+int(length)]
+			data = data[n+int(length):]
+			if field == profileStringTable {
+				table = append(table, string(value))
+			}
+		default:
+			t.Fatalf("unexpected wire type %d", wire)
+		}
+	}
+
+	if counts[profileSampleType] != sampleTypes || counts[profileSample] != len(prof.order) || counts[profileFunction] != 3 {
+		t.Errorf("wrong field counts: %v", counts)
+	}
+	if len(table) == 0 || table[0] != "" {
+		t.Fatalf("the string table does not start with an empty string: %q", table)
+	}
+	for _, s := range []string{"program", "add", "twice", "test.mk", "cpu", "nanoseconds", "alloc_space"} {
+		found := false
+		for _, entry := range table {
+			found = found || entry == s
+		}
+		if !found {
+			t.Errorf("%q is not in the string table %q", s, table)
+		}
+	}
+}
+
+func readVarint(data []byte) (uint64, int) {
+	var x uint64
+	for i, b := range data {
+		x |= uint64(b&0x7f) << (7 * i)
+		if b < 0x80 {
+			return x, i + 1
+		}
+	}
+	return x, len(data)
+}
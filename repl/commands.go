@@ -0,0 +1,217 @@
+package repl
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	ast "github.com/Artypuppet/monkey/ast"
+	lexer "github.com/Artypuppet/monkey/lexer"
+	object "github.com/Artypuppet/monkey/object"
+	parser "github.com/Artypuppet/monkey/parser"
+	token "github.com/Artypuppet/monkey/token"
+)
+
+// the character starting the inputs that are commands to the REPL rather
+// than Monkey code, e.g. ":env".
+const COMMAND_PREFIX = ":"
+
+// struct defining a REPL command. The argument is the rest of the line
+// after the command name, without surrounding spaces.
+type command struct {
+	usage string
+	help  string
+	run   func(s *session, arg string)
+}
+
+// map from the name of a command to its definition, filled in init since
+// :help reads it.
+var commands map[string]command
+
+func init() {
+	commands = map[string]command{
+		"env":    {":env", "list the bindings of the session", (*session).envCommand},
+		"type":   {":type expr", "show the type of the value of expr", (*session).typeCommand},
+		"ast":    {":ast expr", "print the syntax tree of expr", (*session).astCommand},
+		"tokens": {":tokens expr", "print the tokens of expr", (*session).tokensCommand},
+		"load":   {":load file", "evaluate a file in the session", (*session).loadCommand},
+		"save":   {":save file", "write the inputs of the session to a file", (*session).saveCommand},
+		"reset":  {":reset", "forget all the bindings and inputs of the session", (*session).resetCommand},
+		"help":   {":help", "list the commands", (*session).helpCommand},
+	}
+}
+
+// method that runs the command on the line, which starts with COMMAND_PREFIX.
+func (s *session) command(line string) {
+	name, arg, _ := strings.Cut(strings.TrimPrefix(strings.TrimSpace(line), COMMAND_PREFIX), " ")
+	c, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(s.out, "unknown command %s%s, type :help for the list\n", COMMAND_PREFIX, name)
+		return
+	}
+	c.run(s, strings.TrimSpace(arg))
+}
+
+// method that reports whether a command got the argument it needs, printing
+// its usage if it did not.
+func (s *session) needsArgument(name, arg string) bool {
+	if arg == "" {
+		fmt.Fprintf(s.out, "usage: %s\n", commands[name].usage)
+		return false
+	}
+	return true
+}
+
+// method that parses arg, printing the parser errors if there are any in
+// which case the returned program is nil.
+func (s *session) parse(arg string) *ast.Program {
+	p := parser.New(lexer.New(arg))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		printParserErrors(s.out, p.Errors())
+		return nil
+	}
+	return program
+}
+
+// ---------------------------------Commands----------------------------------
+
+// method implementing :env. The bindings of the innermost scope come first
+// and a name shadowed by an inner scope is left out.
+func (s *session) envCommand(arg string) {
+	seen := map[string]bool{}
+	for env := s.env; env != nil; env = env.Outer() {
+		for _, name := range env.Names() {
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+			val, _ := env.Get(name)
+			if val == nil {
+				// bound to a statement, e.g. the body of an empty function.
+				fmt.Fprintf(s.out, "%s: NULL = null\n", name)
+				continue
+			}
+			fmt.Fprintf(s.out, "%s: %s = %s\n", name, val.Type(), val.Inspect())
+		}
+	}
+	if len(seen) == 0 {
+		fmt.Fprintln(s.out, "no bindings")
+	}
+}
+
+// method implementing :type expr. The expression is evaluated in the
+// session, so its bindings are kept.
+func (s *session) typeCommand(arg string) {
+	if !s.needsArgument("type", arg) {
+		return
+	}
+	program := s.parse(arg)
+	if program == nil {
+		return
+	}
+	evaluated := s.ip.Eval(program, s.env)
+	if evaluated == nil {
+		fmt.Fprintln(s.out, "no value")
+		return
+	}
+	fmt.Fprintln(s.out, evaluated.Type())
+	if errObj, ok := evaluated.(*object.Error); ok {
+		fmt.Fprintln(s.out, errObj.Inspect())
+	}
+}
+
+// method implementing :ast expr, which prints the tree as the indented JSON
+// of `monkey parse -json`.
+func (s *session) astCommand(arg string) {
+	if !s.needsArgument("ast", arg) {
+		return
+	}
+	program := s.parse(arg)
+	if program == nil {
+		return
+	}
+	data, err := ast.ToJSON(program)
+	if err != nil {
+		fmt.Fprintf(s.out, "error: %s\n", err)
+		return
+	}
+	var out bytes.Buffer
+	json.Indent(&out, data, "", "  ")
+	out.WriteByte('\n')
+	s.out.Write(out.Bytes())
+}
+
+// method implementing :tokens expr, printing a token per line with its
+// position, type and literal.
+func (s *session) tokensCommand(arg string) {
+	if !s.needsArgument("tokens", arg) {
+		return
+	}
+	l := lexer.New(arg)
+	for tok := l.NextToken(); ; tok = l.NextToken() {
+		fmt.Fprintf(s.out, "%d:%d\t%s\t%q\n", tok.Line, tok.Column, tok.Type, tok.Literal)
+		if tok.Type == token.EOF {
+			return
+		}
+	}
+}
+
+// method implementing :load file. The file is evaluated in the session and
+// becomes part of its source, as if it had been typed.
+func (s *session) loadCommand(arg string) {
+	if !s.needsArgument("load", arg) {
+		return
+	}
+	src, err := os.ReadFile(arg)
+	if err != nil {
+		fmt.Fprintf(s.out, "error: %s\n", err)
+		return
+	}
+	program := s.parse(string(src))
+	if program == nil {
+		return
+	}
+	s.source = append(s.source, strings.TrimRight(string(src), "\n"))
+	if errObj, ok := s.ip.Eval(program, s.env).(*object.Error); ok {
+		fmt.Fprintln(s.out, errObj.Inspect())
+	}
+}
+
+// method implementing :save file, writing the inputs of the session that
+// parsed, one after the other, so that loading the file gets the bindings back.
+func (s *session) saveCommand(arg string) {
+	if !s.needsArgument("save", arg) {
+		return
+	}
+	src := ""
+	if len(s.source) != 0 {
+		src = strings.Join(s.source, "\n") + "\n"
+	}
+	if err := os.WriteFile(arg, []byte(src), 0o644); err != nil {
+		fmt.Fprintf(s.out, "error: %s\n", err)
+		return
+	}
+	fmt.Fprintf(s.out, "saved %d inputs to %s\n", len(s.source), arg)
+}
+
+// method implementing :reset.
+func (s *session) resetCommand(arg string) {
+	s.env = object.NewEnvironment()
+	s.source = nil
+}
+
+// method implementing :help.
+func (s *session) helpCommand(arg string) {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(s.out, "  %-14s %s\n", commands[name].usage, commands[name].help)
+	}
+}
@@ -0,0 +1,79 @@
+package repl
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	evaluator "github.com/Artypuppet/monkey/evaluator"
+	object "github.com/Artypuppet/monkey/object"
+	token "github.com/Artypuppet/monkey/token"
+)
+
+// type def for a function completing the word that ends at pos in line.
+// It returns where the word starts and the names the word can be completed
+// to, sorted.
+type completer func(line []rune, pos int) (start int, candidates []string)
+
+// method that completes the identifier before the cursor with the keywords,
+// the builtins and the names bound in the session, in any of its scopes.
+func (s *session) complete(line []rune, pos int) (int, []string) {
+	start := pos
+	for start > 0 && isIdentRune(line[start-1]) {
+		start--
+	}
+	// there is no member access yet, so nothing can follow a lone dot. The
+	// three dots of a spread are followed by an expression.
+	if start > 0 && line[start-1] == '.' && !strings.HasSuffix(string(line[:start]), "...") {
+		return start, nil
+	}
+	return start, completions(string(line[start:pos]), s.env)
+}
+
+// function that returns the keywords, builtins and names bound in env or
+// its outer environments that start with prefix, sorted and without repeats.
+func completions(prefix string, env *object.Environment) []string {
+	seen := map[string]bool{}
+	candidates := []string{}
+	add := func(name string) {
+		if strings.HasPrefix(name, prefix) && !seen[name] {
+			seen[name] = true
+			candidates = append(candidates, name)
+		}
+	}
+	for keyword := range token.Idents {
+		add(keyword)
+	}
+	for _, name := range evaluator.BuiltinNames() {
+		add(name)
+	}
+	for ; env != nil; env = env.Outer() {
+		for _, name := range env.Names() {
+			add(name)
+		}
+	}
+	sort.Strings(candidates)
+	return candidates
+}
+
+// function that returns the longest prefix shared by all the candidates.
+func commonPrefix(candidates []string) string {
+	if len(candidates) == 0 {
+		return ""
+	}
+	prefix := candidates[0]
+	for _, c := range candidates[1:] {
+		for !strings.HasPrefix(c, prefix) {
+			_, size := utf8.DecodeLastRuneInString(prefix)
+			prefix = prefix[:len(prefix)-size]
+		}
+	}
+	return prefix
+}
+
+// function that reports whether r can be part of an identifier, as the
+// lexer reads them.
+func isIdentRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r)
+}
@@ -0,0 +1,282 @@
+package repl
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+)
+
+// error returned when the user presses Ctrl-C while typing.
+var errInterrupted = errors.New("interrupted")
+
+// struct defining an emacs style line editor reading keys from a terminal
+// in raw mode. It edits one line at a time and browses the history with the
+// arrow keys. Tab completes the word before the cursor if there is a
+// completer, and the line is shown through highlight if it is set.
+type editor struct {
+	in        *bufio.Reader
+	out       io.Writer
+	history   *history
+	complete  completer
+	highlight func(line string) string
+}
+
+// the control keys understood by the editor.
+const (
+	keyCtrlA     = 1
+	keyCtrlB     = 2
+	keyCtrlC     = 3
+	keyCtrlD     = 4
+	keyCtrlE     = 5
+	keyCtrlF     = 6
+	keyCtrlH     = 8
+	keyTab       = 9
+	keyCtrlJ     = 10
+	keyCtrlK     = 11
+	keyCtrlL     = 12
+	keyEnter     = 13
+	keyCtrlN     = 14
+	keyCtrlP     = 16
+	keyCtrlU     = 21
+	keyCtrlW     = 23
+	keyEscape    = 27
+	keyBackspace = 127
+)
+
+// struct holding the line being edited.
+type lineState struct {
+	prompt string
+	buf    []rune
+	pos    int // the cursor, an index in buf
+	// the history entry shown, len(entries) for the line being typed, whose
+	// text is kept in typed while browsing.
+	index int
+	typed []rune
+}
+
+// method that reads a line after printing prompt. It returns io.EOF if
+// Ctrl-D is pressed on an empty line and errInterrupted on Ctrl-C.
+func (e *editor) readLine(prompt string) (string, error) {
+	s := &lineState{prompt: prompt, index: len(e.history.entries)}
+	e.refresh(s)
+	for {
+		r, _, err := e.in.ReadRune()
+		if err != nil {
+			return "", err
+		}
+		switch r {
+		case keyEnter, keyCtrlJ:
+			fmt.Fprint(e.out, "\r\n")
+			return string(s.buf), nil
+		case keyCtrlC:
+			fmt.Fprint(e.out, "^C\r\n")
+			return "", errInterrupted
+		case keyCtrlD:
+			if len(s.buf) == 0 {
+				fmt.Fprint(e.out, "\r\n")
+				return "", io.EOF
+			}
+			s.deleteForward()
+		case keyBackspace, keyCtrlH:
+			s.deleteBackward()
+		case keyCtrlA:
+			s.pos = 0
+		case keyCtrlE:
+			s.pos = len(s.buf)
+		case keyCtrlB:
+			s.left()
+		case keyCtrlF:
+			s.right()
+		case keyCtrlK:
+			s.buf = s.buf[:s.pos]
+		case keyCtrlU:
+			s.buf = append([]rune{}, s.buf[s.pos:]...)
+			s.pos = 0
+		case keyCtrlW:
+			end := s.pos
+			s.wordLeft()
+			s.buf = append(s.buf[:s.pos], s.buf[end:]...)
+		case keyCtrlP:
+			e.browse(s, -1)
+		case keyCtrlN:
+			e.browse(s, 1)
+		case keyCtrlL:
+			fmt.Fprint(e.out, "\x1b[H\x1b[2J")
+		case keyEscape:
+			e.escape(s)
+		case keyTab:
+			e.tab(s)
+		default:
+			if unicode.IsPrint(r) {
+				s.insert(r)
+			}
+		}
+		e.refresh(s)
+	}
+}
+
+// method that handles the keys sending escape sequences: the arrows, home,
+// end and delete, and the Alt-b and Alt-f word moves.
+func (e *editor) escape(s *lineState) {
+	r, _, err := e.in.ReadRune()
+	if err != nil {
+		return
+	}
+	switch r {
+	case 'b':
+		s.wordLeft()
+		return
+	case 'f':
+		s.wordRight()
+		return
+	case '[', 'O':
+	default:
+		return
+	}
+
+	// a control sequence is parameters ended by a final byte, e.g. "3~".
+	params := []rune{}
+	for {
+		r, _, err = e.in.ReadRune()
+		if err != nil {
+			return
+		}
+		if r >= 0x40 && r <= 0x7e {
+			break
+		}
+		params = append(params, r)
+	}
+	switch string(params) + string(r) {
+	case "A":
+		e.browse(s, -1)
+	case "B":
+		e.browse(s, 1)
+	case "C":
+		s.right()
+	case "D":
+		s.left()
+	case "H", "1~", "7~":
+		s.pos = 0
+	case "F", "4~", "8~":
+		s.pos = len(s.buf)
+	case "3~":
+		s.deleteForward()
+	}
+}
+
+// method that completes the word before the cursor to the longest prefix
+// its candidates share, and lists them if it cannot go further. With
+// nothing to complete, as at the start of a line, the tab is inserted to
+// indent.
+func (e *editor) tab(s *lineState) {
+	if e.complete == nil || s.pos == 0 || !isIdentRune(s.buf[s.pos-1]) {
+		s.insert(keyTab)
+		return
+	}
+	start, candidates := e.complete(s.buf, s.pos)
+	if len(candidates) == 0 {
+		fmt.Fprint(e.out, "\a")
+		return
+	}
+	word := string(s.buf[start:s.pos])
+	prefix := commonPrefix(candidates)
+	if len(candidates) == 1 || len(prefix) > len(word) {
+		for _, r := range prefix[len(word):] {
+			s.insert(r)
+		}
+		return
+	}
+	fmt.Fprintf(e.out, "\r\n%s\r\n", strings.Join(candidates, "  "))
+}
+
+// method that shows the previous (step -1) or next (step 1) history entry.
+func (e *editor) browse(s *lineState, step int) {
+	entries := e.history.entries
+	index := s.index + step
+	if index < 0 || index > len(entries) {
+		return
+	}
+	if s.index == len(entries) {
+		s.typed = s.buf
+	}
+	s.index = index
+	if index == len(entries) {
+		s.buf = s.typed
+	} else {
+		s.buf = []rune(entries[index])
+	}
+	s.pos = len(s.buf)
+}
+
+// method that redraws the line and puts the cursor back in place.
+func (e *editor) refresh(s *lineState) {
+	line := string(s.buf)
+	if e.highlight != nil {
+		line = e.highlight(line)
+	}
+	fmt.Fprintf(e.out, "\r%s%s\x1b[K", s.prompt, line)
+	if back := len(s.buf) - s.pos; back > 0 {
+		fmt.Fprintf(e.out, "\x1b[%dD", back)
+	}
+}
+
+// -------------------------------Line editing--------------------------------
+
+func (s *lineState) insert(r rune) {
+	s.buf = append(s.buf, 0)
+	copy(s.buf[s.pos+1:], s.buf[s.pos:])
+	s.buf[s.pos] = r
+	s.pos++
+}
+
+func (s *lineState) deleteBackward() {
+	if s.pos > 0 {
+		s.buf = append(s.buf[:s.pos-1], s.buf[s.pos:]...)
+		s.pos--
+	}
+}
+
+func (s *lineState) deleteForward() {
+	if s.pos < len(s.buf) {
+		s.buf = append(s.buf[:s.pos], s.buf[s.pos+1:]...)
+	}
+}
+
+func (s *lineState) left() {
+	if s.pos > 0 {
+		s.pos--
+	}
+}
+
+func (s *lineState) right() {
+	if s.pos < len(s.buf) {
+		s.pos++
+	}
+}
+
+// methods that move to the start of the word before the cursor or the end
+// of the word after it. Words are letters, digits and underscores.
+func (s *lineState) wordLeft() {
+	for s.pos > 0 && !isWordRune(s.buf[s.pos-1]) {
+		s.pos--
+	}
+	for s.pos > 0 && isWordRune(s.buf[s.pos-1]) {
+		s.pos--
+	}
+}
+
+func (s *lineState) wordRight() {
+	for s.pos < len(s.buf) && !isWordRune(s.buf[s.pos]) {
+		s.pos++
+	}
+	for s.pos < len(s.buf) && isWordRune(s.buf[s.pos]) {
+		s.pos++
+	}
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
@@ -0,0 +1,99 @@
+package repl
+
+import (
+	"sort"
+	"strings"
+
+	evaluator "github.com/Artypuppet/monkey/evaluator"
+	lexer "github.com/Artypuppet/monkey/lexer"
+	token "github.com/Artypuppet/monkey/token"
+)
+
+// the ANSI escape sequences coloring the input and the values printed.
+const (
+	COLOR_RESET   = "\x1b[0m"
+	COLOR_KEYWORD = "\x1b[35m" // magenta
+	COLOR_STRING  = "\x1b[32m" // green
+	COLOR_NUMBER  = "\x1b[36m" // cyan
+	COLOR_LITERAL = "\x1b[33m" // yellow, for true, false and null
+	COLOR_BUILTIN = "\x1b[34m" // blue
+	COLOR_COMMENT = "\x1b[90m" // grey, also for the type annotations
+	COLOR_ERROR   = "\x1b[31m" // red
+)
+
+// struct defining a part of the input to color, from start to the start of
+// the next span.
+type span struct {
+	start int
+	color string
+}
+
+// function that returns input with its tokens colored by type. The text
+// between the tokens is kept as it is, so the result shows as wide as input.
+func highlight(input string) string {
+	// the offset in input of the start of each line.
+	lineStarts := []int{0}
+	for i := 0; i < len(input); i++ {
+		if input[i] == '\n' {
+			lineStarts = append(lineStarts, i+1)
+		}
+	}
+	offset := func(tok *token.Token) int {
+		return lineStarts[tok.Line-1] + tok.Column - 1
+	}
+
+	builtins := map[string]bool{}
+	for _, name := range evaluator.BuiltinNames() {
+		builtins[name] = true
+	}
+
+	spans := []span{}
+	l := lexer.New(input)
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		spans = append(spans, span{offset(tok), tokenColor(tok, builtins)})
+	}
+	for _, comment := range l.Comments() {
+		spans = append(spans, span{offset(comment), COLOR_COMMENT})
+	}
+	sort.Slice(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+
+	var out strings.Builder
+	prev := 0
+	for i, s := range spans {
+		end := len(input)
+		if i+1 < len(spans) {
+			end = spans[i+1].start
+		}
+		out.WriteString(input[prev:s.start])
+		if s.color == "" {
+			out.WriteString(input[s.start:end])
+		} else {
+			out.WriteString(s.color + input[s.start:end] + COLOR_RESET)
+		}
+		prev = end
+	}
+	out.WriteString(input[prev:])
+	return out.String()
+}
+
+// function that returns the color of a token, empty for the ones left as
+// they are like operators and other identifiers.
+func tokenColor(tok *token.Token, builtins map[string]bool) string {
+	switch tok.Type {
+	case token.FUNCTION, token.LET, token.CONST, token.IF, token.ELSE, token.RETURN:
+		return COLOR_KEYWORD
+	case token.TRUE, token.FALSE:
+		return COLOR_LITERAL
+	case token.STRING:
+		return COLOR_STRING
+	case token.INT:
+		return COLOR_NUMBER
+	case token.ILLEGAL:
+		return COLOR_ERROR
+	case token.IDENT:
+		if builtins[tok.Literal] {
+			return COLOR_BUILTIN
+		}
+	}
+	return ""
+}
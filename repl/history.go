@@ -0,0 +1,123 @@
+package repl
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// the name of the history file in the home directory and how many entries
+// of it are kept.
+const (
+	HISTORY_FILE = ".monkey_history"
+	HISTORY_SIZE = 1000
+)
+
+// struct holding the inputs of past sessions and of this one, oldest first.
+// Every input is appended to the file as soon as it is added, one per line
+// with its newlines escaped, so sessions running at the same time keep theirs.
+type history struct {
+	entries []string
+	path    string // empty if the history is not saved
+}
+
+// function that returns the path of the history file, empty if there is no
+// home directory.
+func historyPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, HISTORY_FILE)
+}
+
+// function that loads the history saved at path. A missing file is an
+// empty history. A file that grew over HISTORY_SIZE entries is cut down to
+// the last ones so it doesn't grow forever.
+func loadHistory(path string) *history {
+	h := &history{path: path}
+	if path == "" {
+		return h
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return h
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		h.entries = append(h.entries, unescapeEntry(scanner.Text()))
+	}
+	if len(h.entries) > HISTORY_SIZE {
+		h.entries = h.entries[len(h.entries)-HISTORY_SIZE:]
+		h.save()
+	}
+	return h
+}
+
+// method that rewrites the history file with the entries. The file is
+// replaced at once so a failure leaves the old one.
+func (h *history) save() {
+	tmp, err := os.CreateTemp(filepath.Dir(h.path), HISTORY_FILE+".*")
+	if err != nil {
+		return
+	}
+	w := bufio.NewWriter(tmp)
+	for _, entry := range h.entries {
+		w.WriteString(escapeEntry(entry) + "\n")
+	}
+	if err := w.Flush(); err != nil || tmp.Close() != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return
+	}
+	if os.Rename(tmp.Name(), h.path) != nil {
+		os.Remove(tmp.Name())
+	}
+}
+
+// method that adds an input to the history, unless it is blank or the
+// same as the last one.
+func (h *history) add(entry string) {
+	if strings.TrimSpace(entry) == "" {
+		return
+	}
+	if n := len(h.entries); n > 0 && h.entries[n-1] == entry {
+		return
+	}
+	h.entries = append(h.entries, entry)
+	if len(h.entries) > HISTORY_SIZE {
+		h.entries = h.entries[1:]
+	}
+	if h.path == "" {
+		return
+	}
+	f, err := os.OpenFile(h.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	f.WriteString(escapeEntry(entry) + "\n")
+}
+
+// functions that keep a multi-line input on one line of the file.
+
+func escapeEntry(entry string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(entry)
+}
+
+func unescapeEntry(line string) string {
+	var b strings.Builder
+	for i := 0; i < len(line); i++ {
+		if line[i] == '\\' && i+1 < len(line) {
+			i++
+			if line[i] == 'n' {
+				b.WriteByte('\n')
+				continue
+			}
+		}
+		b.WriteByte(line[i])
+	}
+	return b.String()
+}
@@ -0,0 +1,222 @@
+package repl
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	evaluator "github.com/Artypuppet/monkey/evaluator"
+	object "github.com/Artypuppet/monkey/object"
+)
+
+// the limits of the pretty printer. A collection fitting in PRETTY_WIDTH
+// columns stays on one line, only the first PRETTY_MAX_ELEMENTS elements or
+// pairs of a collection are shown and strings are cut after
+// PRETTY_MAX_STRING bytes.
+const (
+	PRETTY_WIDTH        = 80
+	PRETTY_INDENT       = "  "
+	PRETTY_MAX_ELEMENTS = 100
+	PRETTY_MAX_STRING   = 200
+)
+
+// struct holding the state of the printing of a value.
+type printer struct {
+	color bool
+	// the collections being printed, a collection found inside itself is
+	// printed as <cycle>.
+	visiting map[object.Object]bool
+}
+
+// function that formats a value for the REPL: collections are laid out over
+// several indented lines when they don't fit on one, long ones are cut, and
+// the value is followed by its type. With color the output is colored like
+// the highlighted input.
+func prettyValue(obj object.Object, color bool) string {
+	p := &printer{color: color, visiting: map[object.Object]bool{}}
+	if errObj, ok := obj.(*object.Error); ok {
+		return p.paint(COLOR_ERROR, errObj.Inspect())
+	}
+	if fn, ok := obj.(*object.Function); ok {
+		// the whole source of a function is more useful than its signature.
+		return highlightIf(color, fn.Inspect()) + p.annotation(obj)
+	}
+	return p.format(obj, "") + p.annotation(obj)
+}
+
+// method that returns the type annotation following a value, e.g.
+// "  // ARRAY, 3 elements".
+func (p *printer) annotation(obj object.Object) string {
+	text := string(obj.Type())
+	switch obj := obj.(type) {
+	case *object.Array:
+		text += ", " + plural(len(obj.Elements), "element")
+	case *object.Hash:
+		text += ", " + plural(len(obj.Pairs), "pair")
+	case *object.String:
+		text += ", " + plural(len(obj.Value), "byte")
+	case *object.Function:
+		if name := evaluator.FunctionName(obj); name != "<anonymous>" {
+			text += " " + name
+		}
+	}
+	return "  " + p.paint(COLOR_COMMENT, "// "+text)
+}
+
+// method that formats obj whose first line starts at the current position
+// and whose other lines start with indent.
+func (p *printer) format(obj object.Object, indent string) string {
+	switch obj := obj.(type) {
+	case *object.Integer:
+		return p.paint(COLOR_NUMBER, obj.Inspect())
+	case *object.Boolean, *object.Null:
+		return p.paint(COLOR_LITERAL, obj.Inspect())
+	case *object.String:
+		return p.paint(COLOR_STRING, quote(obj.Value))
+	case *object.Error:
+		return p.paint(COLOR_ERROR, obj.Inspect())
+	case *object.Function:
+		name := ""
+		if obj.Name != "" {
+			name = " " + obj.Name
+		}
+		return p.paint(COLOR_KEYWORD, "fn") + name + signature(obj) + " { ... }"
+	case *object.Array:
+		if p.visiting[obj] {
+			return p.paint(COLOR_COMMENT, "<cycle>")
+		}
+		p.visiting[obj] = true
+		defer delete(p.visiting, obj)
+		items := obj.Elements
+		if len(items) > PRETTY_MAX_ELEMENTS {
+			items = items[:PRETTY_MAX_ELEMENTS]
+		}
+		return p.collection("[", "]", len(items), len(obj.Elements), indent, func(i int, indent string) string {
+			return p.format(items[i], indent)
+		})
+	case *object.Hash:
+		if p.visiting[obj] {
+			return p.paint(COLOR_COMMENT, "<cycle>")
+		}
+		p.visiting[obj] = true
+		defer delete(p.visiting, obj)
+		pairs := obj.SortedPairs()
+		if len(pairs) > PRETTY_MAX_ELEMENTS {
+			pairs = pairs[:PRETTY_MAX_ELEMENTS]
+		}
+		return p.collection("{", "}", len(pairs), len(obj.Pairs), indent, func(i int, indent string) string {
+			return p.format(pairs[i].Key, indent) + ": " + p.format(pairs[i].Value, indent)
+		})
+	}
+	return obj.Inspect()
+}
+
+// method that formats the n items shown of a collection of total items
+// between open and close, on one line if it fits and else one item per line.
+func (p *printer) collection(open, close string, n, total int, indent string, item func(i int, indent string) string) string {
+	if total == 0 {
+		return open + close
+	}
+	more := ""
+	if total > n {
+		more = p.paint(COLOR_COMMENT, fmt.Sprintf("... %d more", total-n))
+	}
+
+	// the one line form is tried first, its items can't span lines.
+	items := []string{}
+	fits := true
+	width := len(indent) + 2
+	for i := 0; i < n && fits; i++ {
+		s := item(i, indent)
+		width += visibleWidth(s) + 2
+		fits = !strings.Contains(s, "\n") && width <= PRETTY_WIDTH
+		items = append(items, s)
+	}
+	if fits && more == "" {
+		return open + strings.Join(items, ", ") + close
+	}
+
+	inner := indent + PRETTY_INDENT
+	var out strings.Builder
+	out.WriteString(open + "\n")
+	for i := 0; i < n; i++ {
+		out.WriteString(inner + item(i, inner) + ",\n")
+	}
+	if more != "" {
+		out.WriteString(inner + more + "\n")
+	}
+	out.WriteString(indent + close)
+	return out.String()
+}
+
+// method that colors text if the printer does.
+func (p *printer) paint(color, text string) string {
+	if !p.color {
+		return text
+	}
+	return color + text + COLOR_RESET
+}
+
+// ---------------------------------Helpers-----------------------------------
+
+// function that returns a string as a literal, cut if it is too long. There
+// are no escapes in Monkey strings, so the value is shown as it is.
+func quote(s string) string {
+	if len(s) <= PRETTY_MAX_STRING {
+		return `"` + s + `"`
+	}
+	cut := PRETTY_MAX_STRING
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return `"` + s[:cut] + `..."`
+}
+
+// function that returns the parameter list of a function, e.g. "(a, b = 1)".
+func signature(fn *object.Function) string {
+	params := []string{}
+	for i, param := range fn.Parameters {
+		if i < len(fn.Defaults) && fn.Defaults[i] != nil {
+			params = append(params, param.String()+" = "+fn.Defaults[i].String())
+		} else {
+			params = append(params, param.String())
+		}
+	}
+	if fn.Rest != nil {
+		params = append(params, "..."+fn.Rest.String())
+	}
+	return "(" + strings.Join(params, ", ") + ")"
+}
+
+func plural(n int, noun string) string {
+	if n == 1 {
+		return fmt.Sprintf("1 %s", noun)
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
+// function that returns how many columns s takes, leaving out the escape
+// sequences coloring it.
+func visibleWidth(s string) int {
+	width := 0
+	inEscape := false
+	for _, r := range s {
+		switch {
+		case inEscape:
+			inEscape = r != 'm'
+		case r == '\x1b':
+			inEscape = true
+		default:
+			width++
+		}
+	}
+	return width
+}
+
+// function that highlights source if color is on.
+func highlightIf(color bool, source string) string {
+	if !color {
+		return source
+	}
+	return highlight(source)
+}
@@ -2,42 +2,178 @@ package repl
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"io"
+	"os"
+	"strings"
 
 	evaluator "github.com/Artypuppet/monkey/evaluator"
 	lexer "github.com/Artypuppet/monkey/lexer"
 	object "github.com/Artypuppet/monkey/object"
 	parser "github.com/Artypuppet/monkey/parser"
+	sandbox "github.com/Artypuppet/monkey/sandbox"
+	token "github.com/Artypuppet/monkey/token"
 )
 
 const PROMPT = ">> "
 
+// the prompt of the lines continuing an input that is not complete yet.
+const CONTINUATION_PROMPT = ".. "
+
+// struct holding the state of a REPL session.
+type session struct {
+	out io.Writer
+	env *object.Environment
+	// evaluates the inputs, with print and friends writing to out and
+	// input and readline reading the lines after the one being evaluated.
+	ip *evaluator.Interpreter
+	// reads a line after printing a prompt. It returns io.EOF at the end of
+	// the input and errInterrupted if the user gave up on the line.
+	readLine func(prompt string) (string, error)
+	history  *history
+	// the inputs that parsed, in order, written by :save.
+	source []string
+	// whether values are shown by prettyValue rather than Inspect, and
+	// whether they and the input are colored.
+	pretty bool
+	color  bool
+}
+
+// function that runs a REPL reading from in and writing to out until the
+// input ends. If in is a terminal the lines can be edited and the inputs
+// are kept in the history file in the home directory. If out is a terminal
+// the values are pretty printed and, unless the NO_COLOR environment
+// variable is set, they and the input are colored.
+// The inputs are typed by the user, so they are granted every capability
+// and the file builtins work in the current directory.
 func Start(in io.Reader, out io.Writer) {
-	scanner := bufio.NewScanner(in)
-	env := object.NewEnvironment()
+	ip := &evaluator.Interpreter{
+		Stdout: out,
+		FS:     sandbox.NewDirFS("."),
+		Policy: evaluator.NewPolicy(object.Capabilities...),
+	}
+	s := &session{out: out, env: object.NewEnvironment(), ip: ip}
+	if f, ok := out.(*os.File); ok && isTerminal(f.Fd()) {
+		s.pretty = true
+		s.color = os.Getenv("NO_COLOR") == ""
+	}
+	if f, ok := in.(*os.File); ok && isTerminal(f.Fd()) {
+		s.history = loadHistory(historyPath())
+		e := &editor{in: bufio.NewReader(f), out: out, history: s.history, complete: s.complete}
+		s.ip.Stdin = e.in
+		if s.color {
+			e.highlight = highlight
+		}
+		s.readLine = func(prompt string) (string, error) {
+			restore, err := makeRaw(f.Fd())
+			if err != nil {
+				return "", err
+			}
+			defer restore()
+			return e.readLine(prompt)
+		}
+	} else {
+		s.history = &history{}
+		r := bufio.NewReader(in)
+		s.ip.Stdin = r
+		s.readLine = func(prompt string) (string, error) {
+			fmt.Fprint(out, prompt)
+			line, err := r.ReadString('\n')
+			if err == io.EOF && line != "" {
+				err = nil
+			}
+			return strings.TrimRight(line, "\r\n"), err
+		}
+	}
+	s.run()
+}
+
+// method that reads, evaluates and prints inputs until the input ends.
+func (s *session) run() {
 	for {
-		fmt.Fprintf(out, PROMPT)
-		scanned := scanner.Scan()
-		if !scanned {
+		input, err := s.readInput()
+		if errors.Is(err, errInterrupted) {
+			continue
+		}
+		if err != nil {
 			return
 		}
-		line := scanner.Text()
-		l := lexer.New(line)
-		p := parser.New(l)
-		program := p.ParseProgram()
-
-		if len(p.Errors()) != 0 {
-			printParserErrors(out, p.Errors())
+		if strings.TrimSpace(input) == "" {
+			continue
+		}
+		s.history.add(input)
+		if isCommand(input) {
+			s.command(input)
 			continue
 		}
+		s.eval(input)
+	}
+}
+
+// method that reads an input, which goes on over as many lines as needed to
+// close its braces, brackets and parentheses. A command is always one line.
+func (s *session) readInput() (string, error) {
+	input, err := s.readLine(PROMPT)
+	if err != nil {
+		return "", err
+	}
+	for !isCommand(input) && !isComplete(input) {
+		line, err := s.readLine(CONTINUATION_PROMPT)
+		if err == io.EOF {
+			// what was typed is evaluated to report what is missing.
+			return input, nil
+		}
+		if err != nil {
+			return "", err
+		}
+		input += "\n" + line
+	}
+	return input, nil
+}
+
+// method that evaluates an input and prints its value or its errors.
+func (s *session) eval(input string) {
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		printParserErrors(s.out, p.Errors())
+		return
+	}
+
+	s.source = append(s.source, input)
+	evaluated := s.ip.Eval(program, s.env)
+	if evaluated == nil {
+		return
+	}
+	if s.pretty {
+		io.WriteString(s.out, prettyValue(evaluated, s.color))
+	} else {
+		io.WriteString(s.out, evaluated.Inspect())
+	}
+	io.WriteString(s.out, "\n")
+}
+
+// function that reports whether an input is a REPL command rather than code.
+func isCommand(input string) bool {
+	return strings.HasPrefix(strings.TrimSpace(input), COMMAND_PREFIX)
+}
 
-		evaluated := evaluator.Eval(program, env)
-		if evaluated != nil {
-			io.WriteString(out, evaluated.Inspect())
-			io.WriteString(out, "\n")
+// function that reports whether an input closes all the braces, brackets and
+// parentheses it opens. Extra closing ones make it complete, the parser
+// reports them.
+func isComplete(input string) bool {
+	depth := 0
+	l := lexer.New(input)
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		switch tok.Type {
+		case token.LBRACE, token.LBRACKET, token.LPAREN:
+			depth++
+		case token.RBRACE, token.RBRACKET, token.RPAREN:
+			depth--
 		}
 	}
+	return depth <= 0
 }
 
 func printParserErrors(out io.Writer, errors []string) {
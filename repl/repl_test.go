@@ -0,0 +1,335 @@
+package repl
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	evaluator "github.com/Artypuppet/monkey/evaluator"
+	lexer "github.com/Artypuppet/monkey/lexer"
+	object "github.com/Artypuppet/monkey/object"
+	parser "github.com/Artypuppet/monkey/parser"
+)
+
+func TestIsComplete(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"let x = 1;", true},
+		{"let f = fn(x) {", false},
+		{"let f = fn(x) {\n  x\n};", true},
+		{"[1, [2,", false},
+		{"f(1,", false},
+		{`"{"`, true},
+		{"}", true},
+		{"", true},
+	}
+
+	for _, tt := range tests {
+		if got := isComplete(tt.input); got != tt.expected {
+			t.Errorf("isComplete(%q) = %t, want %t", tt.input, got, tt.expected)
+		}
+	}
+}
+
+func TestStart(t *testing.T) {
+	input := "let f = fn(x) {\n  x * 2\n};\nf(4)\n\nlet = 1;\n[1,\n2]\n[3,"
+	var out bytes.Buffer
+	Start(strings.NewReader(input), &out)
+	expected := ">> .. .. >> 8\n>> >> \texpected next token to be IDENT, got = instead\n" +
+		"\tno prefix parse function for = found\n>> .. [1, 2]\n>> .. \tno prefix parse function for EOF found\n\texpected next token to be ], got EOF instead\n>> "
+	if out.String() != expected {
+		t.Errorf("wrong output.\nexpected=%q\ngot=%q", expected, out.String())
+	}
+}
+
+func TestHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), HISTORY_FILE)
+	h := loadHistory(path)
+	for _, entry := range []string{"1 + 1", "1 + 1", " ", "let f = fn() {\n  \"a\\nb\"\n};"} {
+		h.add(entry)
+	}
+	expected := []string{"1 + 1", "let f = fn() {\n  \"a\\nb\"\n};"}
+	if !reflect.DeepEqual(h.entries, expected) {
+		t.Errorf("wrong entries.\nexpected=%q\ngot=%q", expected, h.entries)
+	}
+	if loaded := loadHistory(path); !reflect.DeepEqual(loaded.entries, expected) {
+		t.Errorf("wrong entries loaded.\nexpected=%q\ngot=%q", expected, loaded.entries)
+	}
+}
+
+func TestHistoryTrimmed(t *testing.T) {
+	path := filepath.Join(t.TempDir(), HISTORY_FILE)
+	lines := []string{}
+	for i := 0; i < HISTORY_SIZE+10; i++ {
+		lines = append(lines, fmt.Sprint(i))
+	}
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	// loading a file over the limit rewrites it with the last entries.
+	h := loadHistory(path)
+	if len(h.entries) != HISTORY_SIZE || h.entries[0] != "10" {
+		t.Fatalf("wrong entries loaded: %d starting with %q", len(h.entries), h.entries[0])
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := strings.Join(lines[10:], "\n") + "\n"; string(data) != expected {
+		t.Errorf("history file not trimmed, it has %d lines", strings.Count(string(data), "\n"))
+	}
+}
+
+func TestEditor(t *testing.T) {
+	tests := []struct {
+		keys     string
+		expected string
+	}{
+		{"abc\r", "abc"},
+		{"ac\x02b\r", "abc"},                   // Ctrl-B
+		{"bc\x01a\x05d\r", "abcd"},             // Ctrl-A, Ctrl-E
+		{"abc\x1b[D\x1b[D\x7f\r", "bc"},        // left arrow, backspace
+		{"abc\x1b[H\x1b[3~\r", "bc"},           // home, delete
+		{"abcdef\x02\x02\x02\x0b\r", "abc"},    // Ctrl-K
+		{"abcdef\x02\x02\x15\r", "ef"},         // Ctrl-U
+		{"let foo_bar = 1\x17\x17\r", "let "},  // Ctrl-W twice
+		{"one two\x1bbX\x1bf!\r", "one Xtwo!"}, // Alt-b, Alt-f
+		{"\x1b[A\r", "second"},                 // up
+		{"\x1b[A\x1b[A\x10\r", "first"},        // up, up, Ctrl-P
+		{"typed\x1b[A\x1b[B\r", "typed"},       // up, down
+		{"héllo\x02\x7f\r", "hélo"},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		e := &editor{
+			in:      bufio.NewReader(strings.NewReader(tt.keys)),
+			out:     &out,
+			history: &history{entries: []string{"first", "second"}},
+		}
+		line, err := e.readLine(PROMPT)
+		if err != nil {
+			t.Errorf("%q: readLine failed: %s", tt.keys, err)
+			continue
+		}
+		if line != tt.expected {
+			t.Errorf("%q: wrong line. expected=%q, got=%q", tt.keys, tt.expected, line)
+		}
+	}
+}
+
+func TestEditorEndings(t *testing.T) {
+	tests := []struct {
+		keys     string
+		expected error
+	}{
+		{"\x04", io.EOF},
+		{"abc\x03", errInterrupted},
+		{"abc", io.EOF},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		e := &editor{in: bufio.NewReader(strings.NewReader(tt.keys)), out: &out, history: &history{}}
+		if _, err := e.readLine(PROMPT); !errors.Is(err, tt.expected) {
+			t.Errorf("%q: wrong error. expected=%v, got=%v", tt.keys, tt.expected, err)
+		}
+	}
+}
+
+func TestCommands(t *testing.T) {
+	dir := t.TempDir()
+	lib := filepath.Join(dir, "lib.mk")
+	saved := filepath.Join(dir, "saved.mk")
+	if err := os.WriteFile(lib, []byte("let double = fn(x) { x * 2 };\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{":env", "no bindings\n"},
+		{"let x = 1;\n:env", "x: INTEGER = 1\n"},
+		{"let r = fn() {}();\n:env", "r: NULL = null\n"},
+		{":type [1]", "ARRAY\n"},
+		{":type \"a\" - 1", "ERROR\nERROR: type mismatch: STRING - INTEGER\n"},
+		{":type", "usage: :type expr\n"},
+		{":tokens x+1", "1:1\tIDENT\t\"x\"\n1:2\t+\t\"+\"\n1:3\tINT\t\"1\"\n1:4\tEOF\t\"\"\n"},
+		{":ast 1;", `"kind": "IntegerLiteral"`},
+		{":ast let = 1;", "\texpected next token to be IDENT, got = instead\n"},
+		{":load " + lib + "\ndouble(4)", "8\n"},
+		{"let x = 1;\n:reset\nx", "ERROR: identifier not found: x\n"},
+		{":nope", "unknown command :nope, type :help for the list\n"},
+		{":help", "  :save file     write the inputs of the session to a file\n"},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		Start(strings.NewReader(tt.input), &out)
+		if !strings.Contains(out.String(), tt.expected) {
+			t.Errorf("%q: wrong output.\nexpected to contain=%q\ngot=%q", tt.input, tt.expected, out.String())
+		}
+	}
+
+	var out bytes.Buffer
+	Start(strings.NewReader("let y = 2;\nlet = 3;\n:load "+lib+"\n:save "+saved), &out)
+	src, err := os.ReadFile(saved)
+	if err != nil {
+		t.Fatalf("session not saved: %s", err)
+	}
+	expected := "let y = 2;\nlet double = fn(x) { x * 2 };\n"
+	if string(src) != expected {
+		t.Errorf("wrong source saved.\nexpected=%q\ngot=%q", expected, string(src))
+	}
+}
+
+func TestComplete(t *testing.T) {
+	outer := object.NewEnvironment()
+	outer.Set("lengthy", &object.Integer{Value: 1})
+	outer.Set("shadow", &object.Integer{Value: 1})
+	env := object.NewEnclosedEnvironment(outer)
+	env.Set("shadow", &object.Integer{Value: 2})
+	env.Set("letter", &object.Integer{Value: 3})
+	s := &session{env: env}
+
+	tests := []struct {
+		line          string
+		expectedStart int
+		expected      []string
+	}{
+		{"le", 0, []string{"len", "lengthy", "let", "letter"}},
+		{"1 + sha", 4, []string{"shadow"}},
+		{"f(json_p", 2, []string{"json_parse"}},
+		{"[...leng", 4, []string{"lengthy"}},
+		{"x.le", 2, nil},
+		{"zz", 0, []string{}},
+	}
+
+	for _, tt := range tests {
+		start, candidates := s.complete([]rune(tt.line), len([]rune(tt.line)))
+		if start != tt.expectedStart || !reflect.DeepEqual(candidates, tt.expected) {
+			t.Errorf("%q: expected %d %q, got %d %q", tt.line, tt.expectedStart, tt.expected, start, candidates)
+		}
+	}
+}
+
+func TestEditorTab(t *testing.T) {
+	s := &session{env: object.NewEnvironment()}
+	s.env.Set("counter", &object.Integer{Value: 1})
+	s.env.Set("count_all", &object.Integer{Value: 1})
+
+	tests := []struct {
+		keys     string
+		expected string
+		listed   bool
+	}{
+		{"json_s\t(x)\r", "json_stringify(x)", false},
+		{"cou\t\r", "count", false},
+		{"count\t\r", "count", true},
+		{"\tx\r", "\tx", false},
+		{"qq\t\r", "qq", false},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		e := &editor{
+			in:       bufio.NewReader(strings.NewReader(tt.keys)),
+			out:      &out,
+			history:  &history{},
+			complete: s.complete,
+		}
+		line, err := e.readLine(PROMPT)
+		if err != nil {
+			t.Errorf("%q: readLine failed: %s", tt.keys, err)
+			continue
+		}
+		if line != tt.expected {
+			t.Errorf("%q: wrong line. expected=%q, got=%q", tt.keys, tt.expected, line)
+		}
+		if listed := strings.Contains(out.String(), "count_all  counter"); listed != tt.listed {
+			t.Errorf("%q: candidates listed=%t, want %t", tt.keys, listed, tt.listed)
+		}
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let x = len("ab") + 1; // size`,
+			COLOR_KEYWORD + "let " + COLOR_RESET + "x = " + COLOR_BUILTIN + "len" + COLOR_RESET + "(" +
+				COLOR_STRING + `"ab"` + COLOR_RESET + ") + " + COLOR_NUMBER + "1" + COLOR_RESET + "; " +
+				COLOR_COMMENT + "// size" + COLOR_RESET},
+		{"  if (true) {\n", "  " + COLOR_KEYWORD + "if " + COLOR_RESET + "(" + COLOR_LITERAL + "true" + COLOR_RESET + ") {\n"},
+		{`"open`, COLOR_STRING + `"open` + COLOR_RESET},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := highlight(tt.input); got != tt.expected {
+			t.Errorf("highlight(%q) wrong.\nexpected=%q\ngot=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestPrettyValue(t *testing.T) {
+	evaluate := func(input string) object.Object {
+		program := parser.New(lexer.New(input)).ParseProgram()
+		return evaluator.Eval(program, object.NewEnvironment())
+	}
+	cyclic := &object.Array{}
+	cyclic.Elements = []object.Object{&object.Integer{Value: 1}, cyclic}
+
+	tests := []struct {
+		value    object.Object
+		expected string
+	}{
+		{evaluate("1 + 2"), "3  // INTEGER"},
+		{evaluate(`"abc"`), `"abc"  // STRING, 3 bytes`},
+		{evaluate(`[1, "a", true, [], {}]`), `[1, "a", true, [], {}]  // ARRAY, 5 elements`},
+		{evaluate(`{"b": [1], "a": fn(x, y = 2) { x }}`), `{"a": fn(x, y = 2) { ... }, "b": [1]}  // HASH, 2 pairs`},
+		{evaluate(`1 + "a"`), "ERROR: type mismatch: INTEGER + STRING"},
+		{evaluate(`[["aaaaaaaaaaaaaaaaaaaa", "bbbbbbbbbbbbbbbbbbbb", "cccccccccccccccccccc"], [1, 2]]`),
+			"[\n  [\"aaaaaaaaaaaaaaaaaaaa\", \"bbbbbbbbbbbbbbbbbbbb\", \"cccccccccccccccccccc\"],\n  [1, 2],\n]  // ARRAY, 2 elements"},
+		{cyclic, "[1, <cycle>]  // ARRAY, 2 elements"},
+	}
+
+	for i, tt := range tests {
+		if got := prettyValue(tt.value, false); got != tt.expected {
+			t.Errorf("test %d: wrong output.\nexpected=%q\ngot=%q", i, tt.expected, got)
+		}
+	}
+
+	long := evaluate(`let f = fn(n, acc) { if (n == 0) { acc } else { f(n - 1, push(acc, n)) } }; f(150, [])`)
+	lines := strings.Split(prettyValue(long, false), "\n")
+	if len(lines) != PRETTY_MAX_ELEMENTS+3 || lines[1] != "  150," || lines[PRETTY_MAX_ELEMENTS+1] != "  ... 50 more" {
+		t.Errorf("long array not cut: %d lines, %q", len(lines), lines)
+	}
+	if got := quote(strings.Repeat("é", PRETTY_MAX_STRING)); got != `"`+strings.Repeat("é", PRETTY_MAX_STRING/2)+`..."` {
+		t.Errorf("long string not cut: %q", got)
+	}
+	if got := prettyValue(evaluate("[1]"), true); got != "["+COLOR_NUMBER+"1"+COLOR_RESET+"]  "+COLOR_COMMENT+"// ARRAY, 1 element"+COLOR_RESET {
+		t.Errorf("wrong colored output: %q", got)
+	}
+}
+
+func TestIOBuiltins(t *testing.T) {
+	var out bytes.Buffer
+	Start(strings.NewReader("let name = input(\"name? \");\nAnn\nprintln(\"hi\", name)\n"), &out)
+	expected := ">> name? >> hi Ann\nnull\n>> "
+	if out.String() != expected {
+		t.Errorf("wrong output.\nexpected=%q\ngot=%q", expected, out.String())
+	}
+}
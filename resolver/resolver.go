@@ -0,0 +1,409 @@
+package resolver
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	ast "github.com/Artypuppet/monkey/ast"
+	evaluator "github.com/Artypuppet/monkey/evaluator"
+	token "github.com/Artypuppet/monkey/token"
+)
+
+// ------------------------------Diagnostics---------------------------------
+
+// type def for how serious a diagnostic is.
+// Errors are guaranteed to fail at runtime if the code is reached while
+// warnings point at code that is legal but most likely a mistake.
+type Severity int
+
+const (
+	Error Severity = iota
+	Warning
+)
+
+func (s Severity) String() string {
+	if s == Error {
+		return "error"
+	}
+	return "warning"
+}
+
+// struct describing a single problem found by the resolver.
+// Line and Column are the position of the offending token.
+type Diagnostic struct {
+	Line     int
+	Column   int
+	Severity Severity
+	Message  string
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%d:%d: %s: %s", d.Line, d.Column, d.Severity, d.Message)
+}
+
+// ---------------------------------Scopes-----------------------------------
+
+// the kinds of bindings a scope can hold.
+const (
+	letBinding      = "let"
+	constBinding    = "const"
+	paramBinding    = "parameter"
+	functionBinding = "function"
+)
+
+// struct describing a name bound in a scope.
+type binding struct {
+	ident *ast.Identifier // the identifier that declared the name
+	kind  string
+	used  bool
+}
+
+// struct that mirrors object.Environment at analysis time.
+// The program, every function call and every block gets its own scope.
+type scope struct {
+	names      map[string]*binding
+	order      []*binding // bindings in declaration order to report them in order
+	outer      *scope
+	start, end *token.Token // the source the scope covers, nil for the program
+}
+
+func newScope(outer *scope) *scope {
+	return &scope{names: make(map[string]*binding), outer: outer}
+}
+
+// struct describing a scope for tools like editors.
+// Start and End are the first and last token of the source the scope covers,
+// both nil for the scope of the program. End is nil as well if the source
+// ends before the scope is closed.
+type Scope struct {
+	Start *token.Token
+	End   *token.Token
+	Names []*ast.Identifier // the identifiers declaring the names of the scope in order
+	Outer *Scope
+}
+
+// method that reports whether the position line:column is inside the scope.
+func (s *Scope) Contains(line, column int) bool {
+	if s.Start != nil && before(line, column, s.Start.Line, s.Start.Column) {
+		return false
+	}
+	return s.End == nil || !before(s.End.Line, s.End.Column, line, column)
+}
+
+// helper function that reports whether line:column a comes before b.
+func before(lineA, columnA, lineB, columnB int) bool {
+	return lineA < lineB || lineA == lineB && columnA < columnB
+}
+
+// method to find the binding for a name in this scope or any outer scope.
+func (s *scope) lookup(name string) (*binding, bool) {
+	for sc := s; sc != nil; sc = sc.outer {
+		if b, ok := sc.names[name]; ok {
+			return b, true
+		}
+	}
+	return nil, false
+}
+
+// struct holding the work of the program or of one function body.
+// Function bodies only run once they are called, by which time every name of
+// the enclosing scopes has been declared, so they are resolved in deferred
+// after the rest of the unit. Unused bindings are reported once everything
+// that could use them has been resolved.
+type unit struct {
+	deferred []func()
+	scopes   []*scope
+}
+
+// --------------------------------Resolver----------------------------------
+
+// struct that holds the state of a resolver pass.
+type resolver struct {
+	globals     func(name string) bool
+	builtins    map[string]bool
+	units       []*unit
+	scopes      []*scope // every scope in the order it was opened
+	diagnostics []Diagnostic
+	definitions map[*ast.Identifier]*ast.Identifier
+}
+
+// struct holding everything a resolver pass found out about a program.
+type Info struct {
+	Diagnostics []Diagnostic
+	// each use of a name that was resolved to a let, a const, a parameter or
+	// a function, mapped to the identifier that declared it. Uses of builtins
+	// and globals are not in it.
+	Definitions map[*ast.Identifier]*ast.Identifier
+	Scopes      []*Scope // the program scope comes first
+}
+
+// function that resolves every identifier in program against its lexical
+// scopes and the builtins table before the program is evaluated.
+// It reports undefined names as errors and shadowed names, unused let bindings
+// and unreachable code after return as warnings, in source order.
+// globals reports whether a name is already bound before the program runs,
+// e.g. by earlier input in the REPL. It may be nil.
+func Resolve(program *ast.Program, globals func(name string) bool) []Diagnostic {
+	return Analyze(program, globals).Diagnostics
+}
+
+// function that resolves program like Resolve and also returns where every
+// name is declared and the scopes of the program, for tools like editors.
+func Analyze(program *ast.Program, globals func(name string) bool) *Info {
+	r := &resolver{
+		globals:     globals,
+		builtins:    make(map[string]bool),
+		definitions: make(map[*ast.Identifier]*ast.Identifier),
+	}
+	for _, name := range evaluator.BuiltinNames() {
+		r.builtins[name] = true
+	}
+
+	r.units = append(r.units, &unit{})
+	s := r.openScope(nil, nil, nil)
+	r.resolveStatements(program.Statements, s)
+	r.closeUnit()
+
+	sort.SliceStable(r.diagnostics, func(i, j int) bool {
+		a, b := r.diagnostics[i], r.diagnostics[j]
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+	return &Info{Diagnostics: r.diagnostics, Definitions: r.definitions, Scopes: r.exportScopes()}
+}
+
+// helper method that turns the scopes of the pass into Scopes.
+func (r *resolver) exportScopes() []*Scope {
+	exported := make(map[*scope]*Scope)
+	scopes := []*Scope{}
+	for _, s := range r.scopes {
+		e := &Scope{Start: s.start, End: s.end, Outer: exported[s.outer]}
+		for _, b := range s.order {
+			e.Names = append(e.Names, b.ident)
+		}
+		exported[s] = e
+		scopes = append(scopes, e)
+	}
+	return scopes
+}
+
+// function that reports whether a set of diagnostics contains any error.
+func HasErrors(diagnostics []Diagnostic) bool {
+	for _, d := range diagnostics {
+		if d.Severity == Error {
+			return true
+		}
+	}
+	return false
+}
+
+// helper method to record a diagnostic at the position of tok.
+func (r *resolver) report(tok *token.Token, severity Severity, format string, a ...interface{}) {
+	r.diagnostics = append(r.diagnostics, Diagnostic{
+		Line:     tok.Line,
+		Column:   tok.Column,
+		Severity: severity,
+		Message:  fmt.Sprintf(format, a...),
+	})
+}
+
+// helper method that opens a scope in the current unit covering the source
+// from start to end.
+func (r *resolver) openScope(outer *scope, start, end *token.Token) *scope {
+	s := newScope(outer)
+	s.start, s.end = start, end
+	u := r.units[len(r.units)-1]
+	u.scopes = append(u.scopes, s)
+	r.scopes = append(r.scopes, s)
+	return s
+}
+
+// helper method that finishes the current unit. It resolves the deferred
+// function bodies and then reports the let bindings no one used.
+func (r *resolver) closeUnit() {
+	u := r.units[len(r.units)-1]
+	for i := 0; i < len(u.deferred); i++ {
+		u.deferred[i]()
+	}
+	for _, s := range u.scopes {
+		for _, b := range s.order {
+			if !b.used && (b.kind == letBinding || b.kind == constBinding) && !strings.HasPrefix(b.ident.Value, "_") {
+				r.report(b.ident.Token, Warning, "%s declared and not used", b.ident.Value)
+			}
+		}
+	}
+	r.units = r.units[:len(r.units)-1]
+}
+
+// method that declares ident in scope s with the given kind.
+func (r *resolver) declare(s *scope, ident *ast.Identifier, kind string) {
+	name := ident.Value
+	if prev, ok := s.names[name]; ok {
+		if prev.kind == constBinding {
+			r.report(ident.Token, Error, "cannot overwrite constant %s", name)
+		} else {
+			r.report(ident.Token, Warning, "%s redeclared in this scope", name)
+		}
+		// the earlier binding counts as used, the redeclaration is reported already.
+		prev.used = true
+	} else if _, ok := s.outer.lookup(name); ok {
+		r.report(ident.Token, Warning, "declaration of %s shadows an outer declaration", name)
+	} else if r.builtins[name] {
+		r.report(ident.Token, Warning, "declaration of %s shadows a builtin", name)
+	}
+
+	b := &binding{ident: ident, kind: kind}
+	s.names[name] = b
+	s.order = append(s.order, b)
+}
+
+// method that resolves a list of statements that run one after the other.
+// Any statement following a return can never run.
+func (r *resolver) resolveStatements(stmts []ast.Statement, s *scope) {
+	returned := false
+	for _, stmt := range stmts {
+		if returned {
+			r.reportUnreachable(stmt)
+			returned = false
+		}
+		r.resolveStatement(stmt, s)
+		if _, ok := stmt.(*ast.ReturnStatement); ok {
+			returned = true
+		}
+	}
+}
+
+// helper method that reports stmt as unreachable.
+func (r *resolver) reportUnreachable(stmt ast.Statement) {
+	switch stmt := stmt.(type) {
+	case *ast.LetStatement:
+		r.report(stmt.Token, Warning, "unreachable code")
+	case *ast.ReturnStatement:
+		r.report(stmt.Token, Warning, "unreachable code")
+	case *ast.ExpressionStatement:
+		r.report(stmt.Token, Warning, "unreachable code")
+	}
+}
+
+func (r *resolver) resolveStatement(stmt ast.Statement, s *scope) {
+	switch stmt := stmt.(type) {
+	case *ast.LetStatement:
+		r.resolveExpression(stmt.Value, s)
+		kind := letBinding
+		if stmt.IsConst() {
+			kind = constBinding
+		}
+		r.declare(s, stmt.Name, kind)
+	case *ast.ReturnStatement:
+		r.resolveExpression(stmt.ReturnValue, s)
+	case *ast.ExpressionStatement:
+		// a named function on its own declares its name in the current scope.
+		if fl, ok := stmt.Expression.(*ast.FunctionLiteral); ok && fl.Name != nil {
+			r.declare(s, fl.Name, functionBinding)
+			s.names[fl.Name.Value].used = true
+		}
+		r.resolveExpression(stmt.Expression, s)
+	case *ast.BlockStatement:
+		r.resolveStatements(stmt.Statements, r.openScope(s, stmt.Token, stmt.End))
+	}
+}
+
+func (r *resolver) resolveExpression(exp ast.Expression, s *scope) {
+	switch exp := exp.(type) {
+	case *ast.Identifier:
+		r.resolveIdentifier(exp, s)
+	case *ast.PrefixExpression:
+		r.resolveExpression(exp.Right, s)
+	case *ast.InfixExpression:
+		r.resolveExpression(exp.Left, s)
+		r.resolveExpression(exp.Right, s)
+	case *ast.IfExpression:
+		r.resolveExpression(exp.Condition, s)
+		if exp.Consequence != nil {
+			r.resolveStatement(exp.Consequence, s)
+		}
+		if exp.Alternative != nil {
+			r.resolveStatement(exp.Alternative, s)
+		}
+	case *ast.FunctionLiteral:
+		r.resolveFunctionLiteral(exp, s)
+	case *ast.CallExpression:
+		r.resolveExpression(exp.Function, s)
+		r.resolveExpressions(exp.Arguments, s)
+	case *ast.ArrayLiteral:
+		r.resolveExpressions(exp.Elements, s)
+	case *ast.IndexExpression:
+		r.resolveExpression(exp.Left, s)
+		r.resolveExpression(exp.Index, s)
+	case *ast.SpreadExpression:
+		r.resolveExpression(exp.Value, s)
+	case *ast.HashLiteral:
+		for _, pair := range exp.Pairs {
+			r.resolveExpression(pair.Key, s)
+			r.resolveExpression(pair.Value, s)
+		}
+	}
+}
+
+func (r *resolver) resolveExpressions(exps []ast.Expression, s *scope) {
+	for _, exp := range exps {
+		r.resolveExpression(exp, s)
+	}
+}
+
+// method that resolves a use of a name. Lexical scopes come first, then
+// the builtins and finally the globals, the same order as evalIdentifier.
+func (r *resolver) resolveIdentifier(ident *ast.Identifier, s *scope) {
+	if b, ok := s.lookup(ident.Value); ok {
+		b.used = true
+		r.definitions[ident] = b.ident
+		return
+	}
+	if r.builtins[ident.Value] {
+		return
+	}
+	if r.globals != nil && r.globals(ident.Value) {
+		return
+	}
+	r.report(ident.Token, Error, "undefined: %s", ident.Value)
+}
+
+// method that resolves a function literal. Defaults and the body only run
+// when the function is called so they are deferred to the end of the unit.
+// Like the evaluator a named function gets a scope holding just its name and
+// its parameters share the scope of its body.
+func (r *resolver) resolveFunctionLiteral(fl *ast.FunctionLiteral, s *scope) {
+	u := r.units[len(r.units)-1]
+	u.deferred = append(u.deferred, func() {
+		r.units = append(r.units, &unit{})
+		var end *token.Token
+		if fl.Body != nil {
+			end = fl.Body.End
+		}
+		outer := s
+		if fl.Name != nil {
+			// the name refers to the function itself, which is never a mistake.
+			outer = r.openScope(s, fl.Token, end)
+			b := &binding{ident: fl.Name, kind: functionBinding, used: true}
+			outer.names[fl.Name.Value] = b
+			outer.order = append(outer.order, b)
+		}
+		fnScope := r.openScope(outer, fl.Token, end)
+		for i, param := range fl.Parameters {
+			if i < len(fl.Defaults) && fl.Defaults[i] != nil {
+				r.resolveExpression(fl.Defaults[i], fnScope)
+			}
+			r.declare(fnScope, param, paramBinding)
+		}
+		if fl.Rest != nil {
+			r.declare(fnScope, fl.Rest, paramBinding)
+		}
+		if fl.Body != nil {
+			r.resolveStatements(fl.Body.Statements, fnScope)
+		}
+		r.closeUnit()
+	})
+}
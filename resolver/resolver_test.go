@@ -0,0 +1,133 @@
+package resolver
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	lexer "github.com/Artypuppet/monkey/lexer"
+	parser "github.com/Artypuppet/monkey/parser"
+)
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"let x = 5; x;", []string{}},
+		{"foobar;", []string{"1:1: error: undefined: foobar"}},
+		{"len([1]);", []string{}},
+		{"let x = y;\nx;", []string{"1:9: error: undefined: y"}},
+		{"x;\nlet x = 1;", []string{"1:1: error: undefined: x", "2:5: warning: x declared and not used"}},
+		{"let x = 1;", []string{"1:5: warning: x declared and not used"}},
+		{"let _x = 1;", []string{}},
+		{"let f = fn(a) { a + b };\nf(1);", []string{"1:21: error: undefined: b"}},
+		{"let f = fn() { g() };\nlet g = fn() { 1 };\nf();", []string{}},
+		{"fn fact(n) { if (n == 0) { 1 } else { n * fact(n - 1) } }", []string{}},
+		{"let f = fn fact(n) { fact(n) };\nfact(1);\nf;", []string{"2:1: error: undefined: fact"}},
+		{"if (true) { let y = 1; y };\ny;", []string{"2:1: error: undefined: y"}},
+		{"let x = 1;\nif (true) { let x = 2; x };\nx;", []string{"2:17: warning: declaration of x shadows an outer declaration"}},
+		{"let len = 1; len;", []string{"1:5: warning: declaration of len shadows a builtin"}},
+		{"let x = 1; let x = 2; x;", []string{"1:16: warning: x redeclared in this scope"}},
+		{"const x = 1; let x = 2; x;", []string{"1:18: error: cannot overwrite constant x"}},
+		{"let f = fn(x) { return x;\nx + 1; };\nf(1);", []string{"2:1: warning: unreachable code"}},
+		{"let f = fn(a, b = a, ...others) { [b, others] };\nf(1);", []string{}},
+		{"let f = fn(...rest) { rest };\nf(1);", []string{"1:15: warning: declaration of rest shadows a builtin"}},
+		{"let f = fn(a = c) { a };\nf();", []string{"1:16: error: undefined: c"}},
+		{"let f = fn() { let unused = 1; 2 };\nf();", []string{"1:20: warning: unused declared and not used"}},
+		{"let f = fn(xs) { g(...xs) };\nf([]);", []string{"1:18: error: undefined: g"}},
+		{"let f = fn() { if (true) { let y = 1; fn() { y } } };\nf();", []string{}},
+		{"let k = 1; {k: v};", []string{"1:16: error: undefined: v"}},
+	}
+
+	for _, tt := range tests {
+		p := parser.New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		if len(p.Errors()) != 0 {
+			t.Fatalf("parser errors for %q: %v", tt.input, p.Errors())
+		}
+		diagnostics := Resolve(program, nil)
+		if len(diagnostics) != len(tt.expected) {
+			t.Errorf("wrong number of diagnostics for %q. want=%v, got=%v",
+				tt.input, tt.expected, diagnostics)
+			continue
+		}
+		for i, d := range diagnostics {
+			if d.String() != tt.expected[i] {
+				t.Errorf("diagnostic %d wrong for %q. want=%q, got=%q",
+					i, tt.input, tt.expected[i], d.String())
+			}
+		}
+	}
+}
+
+func TestResolveGlobals(t *testing.T) {
+	p := parser.New(lexer.New("x + y"))
+	program := p.ParseProgram()
+
+	globals := func(name string) bool { return name == "x" }
+	diagnostics := Resolve(program, globals)
+	if len(diagnostics) != 1 || diagnostics[0].Message != "undefined: y" {
+		t.Fatalf("wrong diagnostics. got=%v", diagnostics)
+	}
+	if !HasErrors(diagnostics) {
+		t.Errorf("HasErrors is false for %v", diagnostics)
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	input := "let x = 1;\nlet f = fn(a) {\n  let y = a + x;\n  [y, len(y), f]\n};\nf(x);"
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+	info := Analyze(program, nil)
+	if len(info.Diagnostics) != 0 {
+		t.Fatalf("unexpected diagnostics: %v", info.Diagnostics)
+	}
+
+	// every use of a name points at the position of its declaration.
+	expected := map[string]string{
+		"3:11": "2:12", // a
+		"3:15": "1:5",  // x
+		"4:4":  "3:7",  // y
+		"4:11": "3:7",  // y
+		"4:15": "2:5",  // f
+		"6:1":  "2:5",  // f
+		"6:3":  "1:5",  // x
+	}
+	got := map[string]string{}
+	for use, decl := range info.Definitions {
+		got[fmt.Sprintf("%d:%d", use.Token.Line, use.Token.Column)] = fmt.Sprintf("%d:%d", decl.Token.Line, decl.Token.Column)
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("wrong definitions.\nexpected=%v\ngot=     %v", expected, got)
+	}
+
+	tests := []struct {
+		line, column int
+		expected     []string // the names in the innermost scope containing the position, outermost first
+	}{
+		{1, 1, []string{"x", "f"}},
+		{2, 14, []string{"a", "y"}},
+		{4, 3, []string{"a", "y"}},
+		{5, 1, []string{"a", "y"}},
+		{6, 1, []string{"x", "f"}},
+	}
+	for _, tt := range tests {
+		var inner *Scope
+		for _, s := range info.Scopes {
+			if s.Contains(tt.line, tt.column) {
+				inner = s
+			}
+		}
+		names := []string{}
+		for _, ident := range inner.Names {
+			names = append(names, ident.Value)
+		}
+		if !reflect.DeepEqual(names, tt.expected) {
+			t.Errorf("wrong scope at %d:%d. expected=%v, got=%v", tt.line, tt.column, tt.expected, names)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	evaluator "github.com/Artypuppet/monkey/evaluator"
+	object "github.com/Artypuppet/monkey/object"
+	optimizer "github.com/Artypuppet/monkey/optimizer"
+	profiler "github.com/Artypuppet/monkey/profiler"
+	resolver "github.com/Artypuppet/monkey/resolver"
+	sandbox "github.com/Artypuppet/monkey/sandbox"
+	testrunner "github.com/Artypuppet/monkey/testrunner"
+)
+
+// function implementing
+// `monkey run [-strict] [-O=false] [-cpuprofile out] [-allow caps] [-fs dir [-fs-write]] file`.
+// The program is resolved before it is evaluated so that mistakes like an
+// undefined name are reported up front instead of when the line runs.
+// It is then optimized unless -O=false is given. With -cpuprofile the time
+// and memory spent in every Monkey function are written to out as a pprof
+// profile, for `go tool pprof`. The program gets none of the capabilities
+// of object.Capabilities unless they are listed with -allow, comma
+// separated. The file builtins work on the files under the directory given
+// with -fs, read only unless -fs-write is given too.
+func runCommand(args []string) int {
+	flags := flag.NewFlagSet("run", flag.ContinueOnError)
+	strict := flags.Bool("strict", false, "make declaring a name twice in the same scope an error")
+	optimize := flags.Bool("O", true, "optimize the program before running it")
+	cpuprofile := flags.String("cpuprofile", "", "write a pprof profile of the program to `file`")
+	allow := flags.String("allow", "", "grant the program the comma separated `capabilities` e.g. fs-read,env")
+	fsRoot := flags.String("fs", "", "let the program read the files under `dir`")
+	fsWrite := flags.Bool("fs-write", false, "let the program change the files under the -fs directory too")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: monkey run [-strict] [-O=false] [-cpuprofile out] [-allow caps] [-fs dir [-fs-write]] file\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+	path := flags.Arg(0)
+	grants, err := parseCapabilities(*allow)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "monkey: %s\n", err)
+		return 2
+	}
+
+	program := parseFile(path)
+	if program == nil {
+		return 1
+	}
+
+	diagnostics := resolver.Resolve(program, testrunner.Globals(path))
+	for _, d := range diagnostics {
+		fmt.Fprintf(os.Stderr, "%s:%s\n", path, d)
+	}
+	if resolver.HasErrors(diagnostics) {
+		return 1
+	}
+
+	if *optimize {
+		program = optimizer.Optimize(program)
+	}
+
+	env := object.NewEnvironment()
+	if *strict {
+		env = object.NewStrictEnvironment()
+	}
+	ip := &evaluator.Interpreter{Policy: evaluator.NewPolicy(grants...)}
+	if *fsRoot != "" {
+		ip.FS = dirFS(*fsRoot, *fsWrite)
+	}
+	var prof *profiler.Profiler
+	if *cpuprofile != "" {
+		prof = profiler.New(path)
+		ip.Hook = prof
+		prof.Start()
+	}
+	result := ip.Eval(program, env)
+	if prof != nil {
+		prof.Stop()
+		if err := writeFile(*cpuprofile, prof.WriteProfile); err != nil {
+			fmt.Fprintf(os.Stderr, "monkey: %s\n", err)
+			return 1
+		}
+	}
+	if result, ok := result.(*object.Error); ok {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", path, result.Inspect())
+		return 1
+	}
+	return 0
+}
+
+// function that parses a comma separated list of capabilities, returning
+// an error for the ones that don't exist.
+func parseCapabilities(list string) ([]object.Capability, error) {
+	var grants []object.Capability
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if !slices.Contains(object.Capabilities, object.Capability(name)) {
+			return nil, fmt.Errorf("unknown capability %q", name)
+		}
+		grants = append(grants, object.Capability(name))
+	}
+	return grants, nil
+}
+
+// function that returns the files under root for the file builtins, read
+// only unless write is set. The capabilities of the program are checked on
+// top of it, so writing needs both fs-write and write.
+func dirFS(root string, write bool) sandbox.FS {
+	policy := sandbox.ReadOnly
+	if write {
+		policy = sandbox.ReadWrite
+	}
+	return sandbox.Restrict(sandbox.NewDirFS(root), policy)
+}
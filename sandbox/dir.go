@@ -0,0 +1,125 @@
+package sandbox
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// struct defining the file system of the files under a directory of the
+// host, its root. Names can't get out of the root, neither with .. nor
+// through symbolic links pointing outside of it.
+type dirFS struct {
+	root string // absolute, with its symbolic links followed
+}
+
+// constructor for the file system of the files under the directory dir.
+func NewDirFS(dir string) FS {
+	root, err := filepath.Abs(dir)
+	if err == nil {
+		if real, err := filepath.EvalSymlinks(root); err == nil {
+			root = real
+		}
+	}
+	return &dirFS{root: root}
+}
+
+// method that returns the host path of the file called name for op. It is
+// an error if name is not valid or if it leads out of the root. Files that
+// don't exist yet are checked through the nearest directory that does.
+func (d *dirFS) path(op, name string) (string, error) {
+	if !fs.ValidPath(name) {
+		return "", &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	path := filepath.Join(d.root, filepath.FromSlash(name))
+	for p := path; ; p = filepath.Dir(p) {
+		real, err := filepath.EvalSymlinks(p)
+		if err == nil {
+			if !d.contains(real) {
+				return "", &fs.PathError{Op: op, Path: name, Err: fs.ErrPermission}
+			}
+			return path, nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) || p == d.root {
+			return path, nil
+		}
+		// a link pointing nowhere could still be followed out of the root
+		// when writing to it.
+		if _, err := os.Lstat(p); err == nil {
+			return "", &fs.PathError{Op: op, Path: name, Err: fs.ErrPermission}
+		}
+	}
+}
+
+// method that reports whether the host path is the root or under it.
+func (d *dirFS) contains(path string) bool {
+	return path == d.root || strings.HasPrefix(path, d.root+string(filepath.Separator))
+}
+
+// function that replaces the host path in err by name, so that errors don't
+// tell where the root is.
+func hidePath(err error, name string) error {
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		pathErr.Path = name
+	}
+	return err
+}
+
+func (d *dirFS) Open(name string) (fs.File, error) {
+	path, err := d.path("open", name)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, hidePath(err, name)
+	}
+	return f, nil
+}
+
+func (d *dirFS) Stat(name string) (fs.FileInfo, error) {
+	path, err := d.path("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(path)
+	return info, hidePath(err, name)
+}
+
+func (d *dirFS) WriteFile(name string, data []byte) error {
+	path, err := d.path("write", name)
+	if err != nil {
+		return err
+	}
+	return hidePath(os.WriteFile(path, data, 0o666), name)
+}
+
+func (d *dirFS) AppendFile(name string, data []byte) error {
+	path, err := d.path("append", name)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o666)
+	if err != nil {
+		return hidePath(err, name)
+	}
+	_, err = f.Write(data)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return hidePath(err, name)
+}
+
+func (d *dirFS) Remove(name string) error {
+	if name == "." {
+		return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrPermission}
+	}
+	path, err := d.path("remove", name)
+	if err != nil {
+		return err
+	}
+	return hidePath(os.Remove(path), name)
+}
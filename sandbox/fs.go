@@ -0,0 +1,139 @@
+package sandbox
+
+import (
+	"io/fs"
+)
+
+// interface of a file system the file builtins operate on. It is an fs.FS,
+// so fs.ReadFile, fs.ReadDir and fs.Stat work on it, with the operations
+// changing files added. Names are slash separated and relative to the root
+// of the file system, see fs.ValidPath. Errors are *fs.PathError like the
+// ones of the os package.
+type FS interface {
+	fs.FS
+	// creates the file called name holding data, or replaces what it holds.
+	WriteFile(name string, data []byte) error
+	// adds data at the end of the file called name, creating it if needed.
+	AppendFile(name string, data []byte) error
+	// removes the file or empty directory called name.
+	Remove(name string) error
+}
+
+// ---------------------------------Policy------------------------------------
+
+// type of the operations done on a file system.
+type Op int
+
+const (
+	OpRead   Op = iota // reading a file
+	OpList             // listing a directory
+	OpStat             // finding out whether a file exists
+	OpWrite            // creating or replacing a file
+	OpAppend           // adding to a file
+	OpRemove           // removing a file
+)
+
+// the names of the operations, as they appear in errors.
+var opNames = [...]string{
+	OpRead:   "read",
+	OpList:   "readdir",
+	OpStat:   "stat",
+	OpWrite:  "write",
+	OpAppend: "append",
+	OpRemove: "remove",
+}
+
+func (op Op) String() string {
+	return opNames[op]
+}
+
+// function deciding whether op may be done on the file called name.
+type Policy func(op Op, name string) bool
+
+// function returning a policy allowing ops on any file and nothing else.
+func Allow(ops ...Op) Policy {
+	var allowed [len(opNames)]bool
+	for _, op := range ops {
+		allowed[op] = true
+	}
+	return func(op Op, name string) bool {
+		return allowed[op]
+	}
+}
+
+// policies allowing to look at files only, and to do anything.
+var (
+	ReadOnly  = Allow(OpRead, OpList, OpStat)
+	ReadWrite = Allow(OpRead, OpList, OpStat, OpWrite, OpAppend, OpRemove)
+)
+
+// function returning fsys with the operations policy denies failing with
+// fs.ErrPermission.
+func Restrict(fsys FS, policy Policy) FS {
+	return &restricted{fsys: fsys, policy: policy}
+}
+
+// struct defining a file system whose operations are checked by a policy.
+// It implements fs.ReadFileFS, fs.ReadDirFS and fs.StatFS so that reading,
+// listing and stating are told apart, Open on its own counts as reading.
+type restricted struct {
+	fsys   FS
+	policy Policy
+}
+
+// method that returns an error if the policy denies op on the file called name.
+func (r *restricted) check(op Op, name string) error {
+	if !r.policy(op, name) {
+		return &fs.PathError{Op: op.String(), Path: name, Err: fs.ErrPermission}
+	}
+	return nil
+}
+
+func (r *restricted) Open(name string) (fs.File, error) {
+	if err := r.check(OpRead, name); err != nil {
+		return nil, err
+	}
+	return r.fsys.Open(name)
+}
+
+func (r *restricted) ReadFile(name string) ([]byte, error) {
+	if err := r.check(OpRead, name); err != nil {
+		return nil, err
+	}
+	return fs.ReadFile(r.fsys, name)
+}
+
+func (r *restricted) ReadDir(name string) ([]fs.DirEntry, error) {
+	if err := r.check(OpList, name); err != nil {
+		return nil, err
+	}
+	return fs.ReadDir(r.fsys, name)
+}
+
+func (r *restricted) Stat(name string) (fs.FileInfo, error) {
+	if err := r.check(OpStat, name); err != nil {
+		return nil, err
+	}
+	return fs.Stat(r.fsys, name)
+}
+
+func (r *restricted) WriteFile(name string, data []byte) error {
+	if err := r.check(OpWrite, name); err != nil {
+		return err
+	}
+	return r.fsys.WriteFile(name, data)
+}
+
+func (r *restricted) AppendFile(name string, data []byte) error {
+	if err := r.check(OpAppend, name); err != nil {
+		return err
+	}
+	return r.fsys.AppendFile(name, data)
+}
+
+func (r *restricted) Remove(name string) error {
+	if err := r.check(OpRemove, name); err != nil {
+		return err
+	}
+	return r.fsys.Remove(name)
+}
@@ -0,0 +1,180 @@
+package sandbox
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// helper function that checks the operations of fsys on a tree holding the
+// file a.txt and the directory sub with b.txt in it.
+func testFS(t *testing.T, fsys FS) {
+	t.Helper()
+	data, err := fs.ReadFile(fsys, "a.txt")
+	if err != nil || string(data) != "a" {
+		t.Fatalf("ReadFile(a.txt) = %q, %v", data, err)
+	}
+
+	if err := fsys.WriteFile("sub/c.txt", []byte("c")); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if err := fsys.AppendFile("sub/c.txt", []byte("d")); err != nil {
+		t.Fatalf("AppendFile: %v", err)
+	}
+	if err := fsys.AppendFile("new.txt", []byte("n")); err != nil {
+		t.Fatalf("AppendFile of a new file: %v", err)
+	}
+	data, err = fs.ReadFile(fsys, "sub/c.txt")
+	if err != nil || string(data) != "cd" {
+		t.Errorf("ReadFile(sub/c.txt) = %q, %v", data, err)
+	}
+
+	entries, err := fs.ReadDir(fsys, "sub")
+	if err != nil {
+		t.Fatalf("ReadDir: %v", err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	if !reflect.DeepEqual(names, []string{"b.txt", "c.txt"}) {
+		t.Errorf("ReadDir(sub) = %v", names)
+	}
+
+	if err := fsys.Remove("sub"); err == nil {
+		t.Errorf("removing a directory with files didn't fail")
+	}
+	if err := fsys.Remove("sub/c.txt"); err != nil {
+		t.Errorf("Remove: %v", err)
+	}
+	if _, err := fs.Stat(fsys, "sub/c.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("removed file still there: %v", err)
+	}
+	if err := fsys.WriteFile("a.txt/x", nil); err == nil {
+		t.Errorf("writing under a file didn't fail")
+	}
+
+	for _, name := range []string{"../a.txt", "/a.txt", "sub/../../a.txt"} {
+		if _, err := fs.ReadFile(fsys, name); !errors.Is(err, fs.ErrInvalid) {
+			t.Errorf("ReadFile(%q): expected an invalid path error, got %v", name, err)
+		}
+		if err := fsys.WriteFile(name, nil); !errors.Is(err, fs.ErrInvalid) {
+			t.Errorf("WriteFile(%q): expected an invalid path error, got %v", name, err)
+		}
+	}
+}
+
+func TestMemFS(t *testing.T) {
+	testFS(t, NewMemFS(map[string]string{"a.txt": "a", "sub/b.txt": "b"}))
+}
+
+func TestDirFS(t *testing.T) {
+	dir := t.TempDir()
+	root := filepath.Join(dir, "root")
+	writeFiles(t, map[string]string{
+		filepath.Join(root, "a.txt"):        "a",
+		filepath.Join(root, "sub", "b.txt"): "b",
+		filepath.Join(dir, "secret.txt"):    "secret",
+	})
+	testFS(t, NewDirFS(root))
+}
+
+func TestDirFSSymlinks(t *testing.T) {
+	dir := t.TempDir()
+	root := filepath.Join(dir, "root")
+	writeFiles(t, map[string]string{
+		filepath.Join(root, "a.txt"):     "a",
+		filepath.Join(dir, "secret.txt"): "secret",
+	})
+	links := map[string]string{
+		"inside":  filepath.Join(root, "a.txt"),
+		"outside": filepath.Join(dir, "secret.txt"),
+		"up":      dir,
+		"dangles": filepath.Join(dir, "created.txt"),
+	}
+	for name, target := range links {
+		if err := os.Symlink(target, filepath.Join(root, name)); err != nil {
+			t.Skipf("can't make symbolic links: %v", err)
+		}
+	}
+	fsys := NewDirFS(root)
+
+	if data, err := fs.ReadFile(fsys, "inside"); err != nil || string(data) != "a" {
+		t.Errorf("ReadFile(inside) = %q, %v", data, err)
+	}
+	for _, name := range []string{"outside", "up/secret.txt"} {
+		if _, err := fs.ReadFile(fsys, name); !errors.Is(err, fs.ErrPermission) {
+			t.Errorf("ReadFile(%q): expected a permission error, got %v", name, err)
+		}
+	}
+	for _, name := range []string{"outside", "up/new.txt", "dangles"} {
+		if err := fsys.WriteFile(name, []byte("x")); !errors.Is(err, fs.ErrPermission) {
+			t.Errorf("WriteFile(%q): expected a permission error, got %v", name, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "created.txt")); err == nil {
+		t.Errorf("a file was created outside of the root")
+	}
+}
+
+func TestDirFSHidesRoot(t *testing.T) {
+	root := t.TempDir()
+	_, err := fs.ReadFile(NewDirFS(root), "missing.txt")
+	if err == nil || err.Error() != "open missing.txt: no such file or directory" {
+		t.Errorf("wrong error. got=%v", err)
+	}
+}
+
+func TestRestrict(t *testing.T) {
+	fsys := Restrict(NewMemFS(map[string]string{"a.txt": "a"}), ReadOnly)
+	if data, err := fs.ReadFile(fsys, "a.txt"); err != nil || string(data) != "a" {
+		t.Errorf("ReadFile = %q, %v", data, err)
+	}
+	if _, err := fs.Stat(fsys, "a.txt"); err != nil {
+		t.Errorf("Stat: %v", err)
+	}
+	if _, err := fs.ReadDir(fsys, "."); err != nil {
+		t.Errorf("ReadDir: %v", err)
+	}
+	err := fsys.WriteFile("a.txt", nil)
+	if !errors.Is(err, fs.ErrPermission) || err.Error() != "write a.txt: permission denied" {
+		t.Errorf("WriteFile: expected a permission error, got %v", err)
+	}
+	if err := fsys.AppendFile("a.txt", nil); !errors.Is(err, fs.ErrPermission) {
+		t.Errorf("AppendFile: expected a permission error, got %v", err)
+	}
+	if err := fsys.Remove("a.txt"); !errors.Is(err, fs.ErrPermission) {
+		t.Errorf("Remove: expected a permission error, got %v", err)
+	}
+
+	// a policy can look at the names too.
+	fsys = Restrict(NewMemFS(map[string]string{"a.txt": "a", "b.txt": "b"}), func(op Op, name string) bool {
+		return op == OpStat || name == "a.txt"
+	})
+	if _, err := fs.ReadFile(fsys, "b.txt"); !errors.Is(err, fs.ErrPermission) {
+		t.Errorf("ReadFile(b.txt): expected a permission error, got %v", err)
+	}
+	if _, err := fs.Stat(fsys, "b.txt"); err != nil {
+		t.Errorf("Stat(b.txt): %v", err)
+	}
+	if _, err := fs.ReadDir(fsys, "."); !errors.Is(err, fs.ErrPermission) {
+		t.Errorf("ReadDir: expected a permission error, got %v", err)
+	}
+}
+
+// helper function that creates the files, a map from their paths to what
+// they hold, and their directories.
+func writeFiles(t *testing.T, files map[string]string) {
+	t.Helper()
+	for path, data := range files {
+		if err := os.MkdirAll(filepath.Dir(path), 0o777); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0o666); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package sandbox
+
+import (
+	"errors"
+	"io/fs"
+	"path"
+	"strings"
+	"sync"
+	"testing/fstest"
+	"time"
+)
+
+var (
+	errIsDir    = errors.New("is a directory")
+	errNotDir   = errors.New("not a directory")
+	errNotEmpty = errors.New("directory not empty")
+)
+
+// struct defining a file system held in memory, e.g. for tests. The
+// directories are the ones the files are in, so writing a file makes its
+// directories and removing the last file of a directory removes it.
+// It is safe for concurrent use.
+type MemFS struct {
+	mu    sync.RWMutex
+	files fstest.MapFS
+}
+
+// constructor for a file system holding files, a map from their names to
+// what they hold.
+func NewMemFS(files map[string]string) *MemFS {
+	m := &MemFS{files: make(fstest.MapFS)}
+	for name, data := range files {
+		m.files[name] = &fstest.MapFile{Data: []byte(data), Mode: 0o666, ModTime: time.Now()}
+	}
+	return m
+}
+
+func (m *MemFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.files.Open(name)
+}
+
+// method that returns the error of op on the file called name if it can't
+// be written, because it is a directory or one of its parents is a file.
+// It must be called with mu held.
+func (m *MemFS) checkWritable(op, name string) error {
+	if !fs.ValidPath(name) || name == "." {
+		return &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	if m.isDir(name) {
+		return &fs.PathError{Op: op, Path: name, Err: errIsDir}
+	}
+	for dir := path.Dir(name); dir != "."; dir = path.Dir(dir) {
+		if _, ok := m.files[dir]; ok {
+			return &fs.PathError{Op: op, Path: name, Err: errNotDir}
+		}
+	}
+	return nil
+}
+
+// method that reports whether there are files in the directory called name.
+// It must be called with mu held.
+func (m *MemFS) isDir(name string) bool {
+	for file := range m.files {
+		if strings.HasPrefix(file, name+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *MemFS) WriteFile(name string, data []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.checkWritable("write", name); err != nil {
+		return err
+	}
+	// open files keep reading the data they had.
+	m.files[name] = &fstest.MapFile{Data: append([]byte(nil), data...), Mode: 0o666, ModTime: time.Now()}
+	return nil
+}
+
+func (m *MemFS) AppendFile(name string, data []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.checkWritable("append", name); err != nil {
+		return err
+	}
+	var old []byte
+	if file, ok := m.files[name]; ok {
+		old = file.Data
+	}
+	joined := make([]byte, 0, len(old)+len(data))
+	joined = append(append(joined, old...), data...)
+	m.files[name] = &fstest.MapFile{Data: joined, Mode: 0o666, ModTime: time.Now()}
+	return nil
+}
+
+func (m *MemFS) Remove(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !fs.ValidPath(name) || name == "." {
+		return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrInvalid}
+	}
+	if _, ok := m.files[name]; ok {
+		delete(m.files, name)
+		return nil
+	}
+	if m.isDir(name) {
+		return &fs.PathError{Op: "remove", Path: name, Err: errNotEmpty}
+	}
+	return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrNotExist}
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+
+	coverage "github.com/Artypuppet/monkey/coverage"
+	evaluator "github.com/Artypuppet/monkey/evaluator"
+	lexer "github.com/Artypuppet/monkey/lexer"
+	parser "github.com/Artypuppet/monkey/parser"
+	testrunner "github.com/Artypuppet/monkey/testrunner"
+)
+
+// function implementing
+// `monkey test [-run regexp] [-v] [-cover] [-coverprofile out] [-coverhtml out] [-allow caps] [-fs dir [-fs-write]] [path ...]`.
+// It runs the test_ functions of the *_test.mk files given or found in the
+// given directories, the current one by default, and fails if any of them
+// does. With -cover the statements and if arms that ran are summed up,
+// -coverprofile writes them as LCOV and -coverhtml as an annotated HTML page.
+// The tests get the capabilities and the files given with -allow, -fs and
+// -fs-write, like with `monkey run`.
+func testCommand(args []string) int {
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	run := flags.String("run", "", "only run the tests whose name matches `regexp`")
+	verbose := flags.Bool("v", false, "list every test as it passes too")
+	cover := flags.Bool("cover", false, "print a coverage summary")
+	coverprofile := flags.String("coverprofile", "", "write an LCOV coverage profile to `file`")
+	coverhtml := flags.String("coverhtml", "", "write an HTML coverage report to `file`")
+	allow := flags.String("allow", "", "grant the tests the comma separated `capabilities` e.g. fs-read,env")
+	fsRoot := flags.String("fs", "", "let the tests read the files under `dir`")
+	fsWrite := flags.Bool("fs-write", false, "let the tests change the files under the -fs directory too")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: monkey test [-run regexp] [-v] [-cover] [-coverprofile out] [-coverhtml out] [-allow caps] [-fs dir [-fs-write]] [path ...]\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	paths := flags.Args()
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+
+	grants, err := parseCapabilities(*allow)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "monkey: %s\n", err)
+		return 2
+	}
+	runner := &testrunner.Runner{Policy: evaluator.NewPolicy(grants...)}
+	if *fsRoot != "" {
+		runner.FS = dirFS(*fsRoot, *fsWrite)
+	}
+	if *run != "" {
+		filter, err := regexp.Compile(*run)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "monkey: invalid -run: %s\n", err)
+			return 2
+		}
+		runner.Filter = filter
+	}
+
+	files, err := findTestFiles(paths)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "monkey: %s\n", err)
+		return 1
+	}
+	if len(files) == 0 {
+		fmt.Fprintf(os.Stderr, "monkey: no test files\n")
+		return 1
+	}
+
+	var cov *coverage.Coverage
+	if *cover || *coverprofile != "" || *coverhtml != "" {
+		cov = coverage.New()
+		runner.Hook = cov
+	}
+	code := 0
+	for _, path := range files {
+		if !runTestFile(runner, path, cov, *verbose) {
+			code = 1
+		}
+	}
+
+	if cov == nil {
+		return code
+	}
+	if *cover {
+		cov.WriteText(os.Stdout)
+	}
+	for _, report := range []struct {
+		path  string
+		write func(w io.Writer) error
+	}{
+		{*coverprofile, cov.WriteLCOV},
+		{*coverhtml, cov.WriteHTML},
+	} {
+		if report.path == "" {
+			continue
+		}
+		if err := writeFile(report.path, report.write); err != nil {
+			fmt.Fprintf(os.Stderr, "monkey: %s\n", err)
+			code = 1
+		}
+	}
+	return code
+}
+
+// function that returns the files in paths, with directories replaced by
+// the *_test.mk files in them and their subdirectories.
+func findTestFiles(paths []string) ([]string, error) {
+	files := []string{}
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+		err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() && testrunner.IsTestFile(p) {
+				files = append(files, p)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// function that runs the tests of a file, prints how they went and reports
+// whether they all passed. The file is added to cov first if it is not nil.
+func runTestFile(runner *testrunner.Runner, path string, cov *coverage.Coverage, verbose bool) bool {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Printf("FAIL %s: %s\n", path, err)
+		return false
+	}
+	p := parser.New(lexer.New(string(src)))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		fmt.Printf("FAIL %s: %s\n", path, p.Errors()[0])
+		return false
+	}
+	if cov != nil {
+		cov.Add(path, string(src), program)
+	}
+
+	failed := 0
+	results := runner.RunProgram(path, string(src), program)
+	for _, result := range results {
+		name := result.Name
+		if name == "" {
+			name = path
+		}
+		if result.Failure != nil {
+			failed++
+			fmt.Printf("--- FAIL: %s\n    %s\n", name, result.Failure)
+		} else if verbose {
+			fmt.Printf("--- PASS: %s\n", name)
+		}
+	}
+
+	switch {
+	case failed > 0:
+		fmt.Printf("FAIL %s (%d of %d failed)\n", path, failed, len(results))
+	case len(results) == 1 && results[0].Name == "":
+		fmt.Printf("ok   %s (no tests)\n", path)
+	case len(results) == 1:
+		fmt.Printf("ok   %s (1 test)\n", path)
+	default:
+		fmt.Printf("ok   %s (%d tests)\n", path, len(results))
+	}
+	return failed == 0
+}
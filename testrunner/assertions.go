@@ -0,0 +1,100 @@
+package testrunner
+
+import (
+	"fmt"
+	"strings"
+
+	evaluator "github.com/Artypuppet/monkey/evaluator"
+	object "github.com/Artypuppet/monkey/object"
+)
+
+// method that makes the assertion builtins of a test run by ip. A failed
+// assertion returns an error, which ends the test, after recording where
+// it failed.
+func (r *Runner) assertions(ip *evaluator.Interpreter, file *testFile) map[string]*object.Builtin {
+	// fail records a failure at the assertion being called.
+	fail := func(format string, a ...interface{}) object.Object {
+		message := fmt.Sprintf(format, a...)
+		failure := &Failure{Path: file.path, Message: message}
+		if call := ip.CallSite(); call != nil {
+			failure.Source = callSource(file.lines, call)
+			failure.Line, failure.Column = startOf(call)
+		}
+		r.failure = failure
+		return &object.Error{Message: message}
+	}
+
+	return map[string]*object.Builtin{
+		// assert(condition, message?) checks that condition is truthy.
+		"assert": {Fn: func(args ...object.Object) object.Object {
+			if len(args) < 1 || len(args) > 2 {
+				return fail("wrong number of arguments. got=%d, want=1 to 2", len(args))
+			}
+			if args[0] == evaluator.FALSE || args[0] == evaluator.NULL {
+				return fail("%s", assertionMessage("assertion failed", args[1:]))
+			}
+			return evaluator.NULL
+		}},
+		// assert_eq(got, want, message?) checks that two values are equal.
+		"assert_eq": {Fn: func(args ...object.Object) object.Object {
+			if len(args) < 2 || len(args) > 3 {
+				return fail("wrong number of arguments. got=%d, want=2 to 3", len(args))
+			}
+			if !evaluator.ObjectsEqual(args[0], args[1]) {
+				return fail("%s", assertionMessage(fmt.Sprintf("got %s, want %s", describe(args[0]), describe(args[1])), args[2:]))
+			}
+			return evaluator.NULL
+		}},
+		// assert_error(fn, part?) checks that calling fn fails, with an
+		// error message containing part if given.
+		"assert_error": {Fn: func(args ...object.Object) object.Object {
+			if len(args) < 1 || len(args) > 2 {
+				return fail("wrong number of arguments. got=%d, want=1 to 2", len(args))
+			}
+			switch args[0].(type) {
+			case *object.Function, *object.Builtin:
+			default:
+				return fail("first argument to `assert_error` must be FUNCTION, got %s", args[0].Type())
+			}
+			var part string
+			if len(args) == 2 {
+				s, ok := args[1].(*object.String)
+				if !ok {
+					return fail("second argument to `assert_error` must be STRING, got %s", args[1].Type())
+				}
+				part = s.Value
+			}
+			result := ip.Call(args[0])
+			err, ok := result.(*object.Error)
+			// a failed assertion inside the call is what was expected.
+			r.failure = nil
+			if !ok {
+				return fail("expected an error, got %s", describe(result))
+			}
+			if !strings.Contains(err.Message, part) {
+				return fail("error %q does not contain %q", err.Message, part)
+			}
+			return evaluator.NULL
+		}},
+	}
+}
+
+// helper function that adds the optional message of an assertion.
+func assertionMessage(message string, rest []object.Object) string {
+	if len(rest) == 0 {
+		return message
+	}
+	if s, ok := rest[0].(*object.String); ok {
+		return s.Value + ": " + message
+	}
+	return rest[0].Inspect() + ": " + message
+}
+
+// helper function that prints a value with its type when that can tell
+// apart values that print the same, like 1 and "1".
+func describe(obj object.Object) string {
+	if obj.Type() == object.STRING_OBJ {
+		return fmt.Sprintf("%q", obj.Inspect())
+	}
+	return obj.Inspect()
+}
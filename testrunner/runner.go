@@ -0,0 +1,227 @@
+package testrunner
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	ast "github.com/Artypuppet/monkey/ast"
+	evaluator "github.com/Artypuppet/monkey/evaluator"
+	lexer "github.com/Artypuppet/monkey/lexer"
+	object "github.com/Artypuppet/monkey/object"
+	parser "github.com/Artypuppet/monkey/parser"
+	sandbox "github.com/Artypuppet/monkey/sandbox"
+	token "github.com/Artypuppet/monkey/token"
+)
+
+// the prefix of the functions that are tests.
+const TEST_PREFIX = "test_"
+
+// the suffix of the names of the files holding tests.
+const FILE_SUFFIX = "_test.mk"
+
+// the names of the assertion builtins the tests get on top of the builtins
+// of the evaluator.
+var Assertions = []string{"assert", "assert_eq", "assert_error"}
+
+// function that reports whether the file at path holds tests.
+func IsTestFile(path string) bool {
+	return strings.HasSuffix(path, FILE_SUFFIX)
+}
+
+// function that returns the names bound before the file at path runs, as
+// the globals of resolver.Resolve: the assertions for a test file, none
+// for others.
+func Globals(path string) func(name string) bool {
+	if !IsTestFile(path) {
+		return nil
+	}
+	return func(name string) bool {
+		return slices.Contains(Assertions, name)
+	}
+}
+
+// struct defining a runner of the tests of Monkey files. The tests of a file
+// are the functions whose name starts with test_ declared at its top level.
+// Every test runs in an environment of its own in which the file was
+// evaluated again, so tests cannot see what other tests did.
+type Runner struct {
+	Filter *regexp.Regexp // only the tests whose name matches run, all if nil
+	Hook   evaluator.Hook // the hook of the evaluations, e.g. for coverage
+	// the files and the capabilities the tests get, see evaluator.Interpreter.
+	FS     sandbox.FS
+	Policy *evaluator.Policy
+
+	// the failure reported by the last assertion that failed.
+	failure *Failure
+}
+
+// struct holding the outcome of a test.
+type Result struct {
+	Name    string
+	Failure *Failure // nil if the test passed
+}
+
+// struct describing why a test failed. Source is the text of the failed
+// assertion, empty if the test failed with another error.
+type Failure struct {
+	Path         string
+	Line, Column int
+	Source       string
+	Message      string
+}
+
+func (f *Failure) String() string {
+	if f.Source == "" {
+		return fmt.Sprintf("%s:%d:%d: %s", f.Path, f.Line, f.Column, f.Message)
+	}
+	return fmt.Sprintf("%s:%d:%d: %s: %s", f.Path, f.Line, f.Column, f.Source, f.Message)
+}
+
+// method that runs the tests of the file at path, whose text is source.
+// It fails only if the file does not parse.
+func (r *Runner) RunFile(path, source string) ([]Result, error) {
+	p := parser.New(lexer.New(source))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return nil, fmt.Errorf("%s: %s", path, p.Errors()[0])
+	}
+	return r.RunProgram(path, source, program), nil
+}
+
+// method that runs the tests of program, parsed from source read from the
+// file at path. A program without tests is evaluated once and reported as a
+// single result without a name.
+func (r *Runner) RunProgram(path, source string, program *ast.Program) []Result {
+	file := &testFile{path: path, lines: strings.Split(source, "\n"), program: program}
+
+	tests := findTests(program)
+	if len(tests) == 0 {
+		return []Result{{Failure: r.run(file, nil)}}
+	}
+	results := []Result{}
+	for _, test := range tests {
+		if r.Filter != nil && !r.Filter.MatchString(test.Value) {
+			continue
+		}
+		results = append(results, Result{Name: test.Value, Failure: r.run(file, test)})
+	}
+	return results
+}
+
+// struct holding a parsed test file.
+type testFile struct {
+	path    string
+	lines   []string
+	program *ast.Program
+}
+
+// function that returns the names of the tests of a program, in the order
+// they are declared. Both `let test_x = fn() {...}` and `fn test_x() {...}`
+// declare a test.
+func findTests(program *ast.Program) []*ast.Identifier {
+	tests := []*ast.Identifier{}
+	for _, stmt := range program.Statements {
+		var name *ast.Identifier
+		switch stmt := stmt.(type) {
+		case *ast.LetStatement:
+			if _, ok := stmt.Value.(*ast.FunctionLiteral); ok {
+				name = stmt.Name
+			}
+		case *ast.ExpressionStatement:
+			if fn, ok := stmt.Expression.(*ast.FunctionLiteral); ok && fn.Name != nil {
+				name = fn.Name
+			}
+		}
+		if name != nil && strings.HasPrefix(name.Value, TEST_PREFIX) {
+			tests = append(tests, name)
+		}
+	}
+	return tests
+}
+
+// method that evaluates the file in a new environment and calls test in it,
+// if it is not nil. It returns why the test failed, nil if it passed.
+func (r *Runner) run(file *testFile, test *ast.Identifier) *Failure {
+	r.failure = nil
+	ip := &evaluator.Interpreter{Hook: r.Hook, FS: r.FS, Policy: r.Policy}
+	ip.Builtins = r.assertions(ip, file)
+	env := object.NewEnvironment()
+
+	result := ip.Eval(file.program, env)
+	if test != nil && !isError(result) {
+		// the file may have returned before declaring the test.
+		fn, ok := env.Get(test.Value)
+		if _, isFunction := fn.(*object.Function); !ok || !isFunction {
+			return &Failure{
+				Path: file.path, Line: test.Token.Line, Column: test.Token.Column,
+				Message: fmt.Sprintf("%s was not defined when the file finished", test.Value),
+			}
+		}
+		result = ip.Call(fn)
+	}
+	if !isError(result) {
+		return nil
+	}
+	if r.failure != nil {
+		return r.failure
+	}
+	// any other error is reported at the test, or the top of the file.
+	failure := &Failure{Path: file.path, Line: 1, Column: 1, Message: result.(*object.Error).Message}
+	if test != nil {
+		failure.Line, failure.Column = test.Token.Line, test.Token.Column
+	}
+	return failure
+}
+
+func isError(obj object.Object) bool {
+	return obj != nil && obj.Type() == object.ERROR_OBJ
+}
+
+// helper function that returns the text of a call in the source, or how
+// it prints if its tokens are missing.
+func callSource(lines []string, call *ast.CallExpression) string {
+	ident, ok := call.Function.(*ast.Identifier)
+	if !ok || call.End == nil {
+		return call.String()
+	}
+	text := sourceBetween(lines, ident.Token, call.End)
+	if text == "" {
+		return call.String()
+	}
+	return text
+}
+
+// helper function that returns the position of a call, which is where the
+// name of the function called starts.
+func startOf(call *ast.CallExpression) (int, int) {
+	if ident, ok := call.Function.(*ast.Identifier); ok {
+		return ident.Token.Line, ident.Token.Column
+	}
+	return call.Token.Line, call.Token.Column
+}
+
+// helper function that returns the source from the start of the token
+// start to the end of the token end, joining lines with spaces.
+func sourceBetween(lines []string, start, end *token.Token) string {
+	if start.Line < 1 || end.Line > len(lines) || start.Line > end.Line {
+		return ""
+	}
+	parts := []string{}
+	for line := start.Line; line <= end.Line; line++ {
+		text := lines[line-1]
+		from, to := 0, len(text)
+		if line == start.Line {
+			from = start.Column - 1
+		}
+		if line == end.Line {
+			to = end.Column - 1 + len(end.Literal)
+		}
+		if from < 0 || to > len(text) || from > to {
+			return ""
+		}
+		parts = append(parts, strings.TrimSpace(text[from:to]))
+	}
+	return strings.Join(parts, " ")
+}
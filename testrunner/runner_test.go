@@ -0,0 +1,151 @@
+package testrunner
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	evaluator "github.com/Artypuppet/monkey/evaluator"
+	object "github.com/Artypuppet/monkey/object"
+	sandbox "github.com/Artypuppet/monkey/sandbox"
+)
+
+const testFileSource = `let add = fn(a, b) { a + b };
+let test_add = fn() {
+  assert_eq(add(1, 2), 3);
+  assert(add(1, 1) == 2, "one and one");
+  assert_eq([1, {"a": [true]}], [1, {"a": [true]}]);
+};
+fn test_fails() {
+  assert_eq(add(2, 2),
+    5, "math");
+}
+let test_errors = fn() {
+  assert_error(fn() { 1 + "a" }, "type mismatch");
+  assert_error(fn() { assert(false) });
+};
+let test_not_error = fn() { assert_error(fn() { 1 }) };
+let test_other = fn() { missing };
+let helper = fn() { 1 };`
+
+// helper function that returns the column of the first substr on line n of source.
+func columnOf(source string, n int, substr string) int {
+	return strings.Index(strings.Split(source, "\n")[n-1], substr) + 1
+}
+
+func TestRunFile(t *testing.T) {
+	r := &Runner{}
+	results, err := r.RunFile("math_test.mk", testFileSource)
+	if err != nil {
+		t.Fatalf("RunFile failed: %s", err)
+	}
+
+	expected := []Result{
+		{Name: "test_add"},
+		{Name: "test_fails", Failure: &Failure{
+			Path: "math_test.mk", Line: 8, Column: 3,
+			Source:  `assert_eq(add(2, 2), 5, "math")`,
+			Message: "math: got 4, want 5",
+		}},
+		{Name: "test_errors"},
+		{Name: "test_not_error", Failure: &Failure{
+			Path: "math_test.mk", Line: 15, Column: columnOf(testFileSource, 15, "assert_error"),
+			Source:  "assert_error(fn() { 1 })",
+			Message: "expected an error, got 1",
+		}},
+		{Name: "test_other", Failure: &Failure{
+			Path: "math_test.mk", Line: 16, Column: 5,
+			Message: "identifier not found: missing",
+		}},
+	}
+	if !reflect.DeepEqual(results, expected) {
+		t.Errorf("wrong results.\nexpected=%s\ngot=%s", show(expected), show(results))
+	}
+}
+
+func show(results []Result) string {
+	lines := []string{}
+	for _, r := range results {
+		line := r.Name + " ok"
+		if r.Failure != nil {
+			line = r.Name + " " + r.Failure.String()
+		}
+		lines = append(lines, line)
+	}
+	return "\n  " + strings.Join(lines, "\n  ")
+}
+
+func TestRunFileFilterAndTopLevel(t *testing.T) {
+	r := &Runner{Filter: regexp.MustCompile("error")}
+	results, err := r.RunFile("math_test.mk", testFileSource)
+	if err != nil {
+		t.Fatalf("RunFile failed: %s", err)
+	}
+	names := []string{}
+	for _, result := range results {
+		names = append(names, result.Name)
+	}
+	if !reflect.DeepEqual(names, []string{"test_errors", "test_not_error"}) {
+		t.Errorf("wrong tests run: %v", names)
+	}
+
+	// a file without tests is run once, its assertions checked as it goes.
+	results, err = (&Runner{}).RunFile("top_test.mk", "let x = 1;\nassert(x == 2, \"x\");")
+	if err != nil {
+		t.Fatalf("RunFile failed: %s", err)
+	}
+	expected := []Result{{Failure: &Failure{Path: "top_test.mk", Line: 2, Column: 1, Source: `assert(x == 2, "x")`, Message: "x: assertion failed"}}}
+	if !reflect.DeepEqual(results, expected) {
+		t.Errorf("wrong results.\nexpected=%s\ngot=%s", show(expected), show(results))
+	}
+
+	// a test the file returned before declaring fails instead of being called.
+	results, err = (&Runner{}).RunFile("early_test.mk", "return 1;\nfn test_a() { assert(true) }")
+	if err != nil {
+		t.Fatalf("RunFile failed: %s", err)
+	}
+	expected = []Result{{Name: "test_a", Failure: &Failure{Path: "early_test.mk", Line: 2, Column: 4, Message: "test_a was not defined when the file finished"}}}
+	if !reflect.DeepEqual(results, expected) {
+		t.Errorf("wrong results.\nexpected=%s\ngot=%s", show(expected), show(results))
+	}
+
+	if _, err := (&Runner{}).RunFile("bad_test.mk", "let = 1;"); err == nil {
+		t.Errorf("RunFile did not fail on a parse error")
+	}
+}
+
+func TestRunFilePolicy(t *testing.T) {
+	source := "fn test_read() { assert_eq(read_file(\"a.txt\"), \"a\") }"
+	fsys := sandbox.NewMemFS(map[string]string{"a.txt": "a"})
+
+	r := &Runner{FS: fsys, Policy: evaluator.NewPolicy(object.FS_READ_CAP)}
+	results, err := r.RunFile("read_test.mk", source)
+	if err != nil || len(results) != 1 || results[0].Failure != nil {
+		t.Fatalf("test with fs-read failed: %v %+v", err, results)
+	}
+
+	results, _ = (&Runner{FS: fsys}).RunFile("read_test.mk", source)
+	if len(results) != 1 || results[0].Failure == nil || results[0].Failure.Message != "permission denied: `read_file` needs fs-read" {
+		t.Errorf("test without fs-read did not fail: %+v", results)
+	}
+}
+
+func TestGlobals(t *testing.T) {
+	globals := Globals("math_test.mk")
+	builtins := (&Runner{}).assertions(&evaluator.Interpreter{}, &testFile{})
+	if len(builtins) != len(Assertions) {
+		t.Errorf("Assertions has %d names, the runner makes %d assertions", len(Assertions), len(builtins))
+	}
+	for name := range builtins {
+		if !globals(name) {
+			t.Errorf("%s is not a global of a test file", name)
+		}
+	}
+	if globals("len") {
+		t.Errorf("len is a global of a test file")
+	}
+	if Globals("math.mk") != nil {
+		t.Errorf("a file that is not a test file has globals")
+	}
+}
@@ -10,9 +10,14 @@ type TokenType string
 // of it e.g. in the experession let x = 5
 // 'let' is a keyword with Literal value of 'let'
 // while 'x' is an IDENTIFIER with a Literal value of 'x' and so on.
+// Line and Column give the position of the first character of the token
+// in the input, both starting at 1. They are 0 for tokens that were not
+// produced by the lexer.
 type Token struct {
 	Type    TokenType
 	Literal string
+	Line    int
+	Column  int // counted in bytes
 }
 
 // Following are the possible TokenTypes in the language
@@ -24,6 +29,8 @@ const (
 	IDENT  = "IDENT"  // add, foobar, x, y, ...
 	INT    = "INT"    // 1343456
 	STRING = "STRING" // anything enclosed within ""
+	// Comments are only recorded by the lexer, the parser never sees them.
+	COMMENT = "COMMENT" // from // to the end of the line
 	// Operators
 	ASSIGN   = "="
 	PLUS     = "+"
@@ -45,9 +52,11 @@ const (
 	LBRACKET  = "["
 	RBRACKET  = "]"
 	COLON     = ":"
+	ELLIPSIS  = "..." // ...rest parameters and ...spread arguments
 	// Keywords
 	FUNCTION = "FUNCTION"
 	LET      = "LET"
+	CONST    = "CONST"
 	TRUE     = "TRUE"
 	FALSE    = "FALSE"
 	IF       = "IF"
@@ -80,6 +89,7 @@ var TokenTypes = map[TokenType]struct{}{
 var Idents = map[string]TokenType{
 	"fn":     FUNCTION,
 	"let":    LET,
+	"const":  CONST,
 	"true":   TRUE,
 	"false":  FALSE,
 	"if":     IF,
@@ -0,0 +1,420 @@
+package types
+
+import (
+	"fmt"
+	"sort"
+
+	ast "github.com/Artypuppet/monkey/ast"
+	token "github.com/Artypuppet/monkey/token"
+)
+
+// ---------------------------------Scopes-----------------------------------
+
+// struct holding the static types of the names bound in a scope.
+// Like the resolver it mirrors object.Environment: the program, every
+// function and every block gets its own scope.
+type scope struct {
+	names map[string]Type
+	outer *scope
+}
+
+func newScope(outer *scope) *scope {
+	return &scope{names: make(map[string]Type), outer: outer}
+}
+
+// method to find the type of a name in this scope or any outer scope.
+func (s *scope) lookup(name string) (Type, bool) {
+	for sc := s; sc != nil; sc = sc.outer {
+		if t, ok := sc.names[name]; ok {
+			return t, true
+		}
+	}
+	return nil, false
+}
+
+// --------------------------------Checker-----------------------------------
+
+// struct that holds the state of a type checking pass.
+type checker struct {
+	errors    []Error
+	functions []*function // the enclosing function literals, innermost last
+}
+
+// struct holding what the checker knows about the function being checked.
+type function struct {
+	declared Type // the annotated return type, nil if there is none
+	returned Type // join of the types of its return statements so far
+}
+
+// function that checks the types of program before it is evaluated.
+// Checking is gradual: names and parameters without annotations take the type
+// of their value where it can be inferred and Any otherwise, and Any never
+// causes an error. Undefined names are left to the resolver and are Any here.
+// The errors are returned in source order.
+func Check(program *ast.Program) []Error {
+	c := &checker{}
+	c.checkStatements(program.Statements, newScope(nil))
+	sort.SliceStable(c.errors, func(i, j int) bool {
+		a, b := c.errors[i], c.errors[j]
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+	return c.errors
+}
+
+// helper method to record a type error at the position of tok.
+func (c *checker) report(tok *token.Token, format string, a ...interface{}) {
+	c.errors = append(c.errors, Error{
+		Line:    tok.Line,
+		Column:  tok.Column,
+		Message: fmt.Sprintf(format, a...),
+	})
+}
+
+// helper method that reports an error unless a value of type from can be
+// used where to is expected. context finishes the message e.g. "in return".
+func (c *checker) assign(tok *token.Token, to, from Type, context string) {
+	if !Consistent(to, from) {
+		c.report(tok, "cannot use value of type %s as %s %s", from, to, context)
+	}
+}
+
+// method that checks a list of statements and returns the type of the value
+// they produce, which is the value of the last statement.
+func (c *checker) checkStatements(stmts []ast.Statement, s *scope) Type {
+	var result Type = Null
+	for _, stmt := range stmts {
+		result = c.checkStatement(stmt, s)
+	}
+	return result
+}
+
+func (c *checker) checkStatement(stmt ast.Statement, s *scope) Type {
+	switch stmt := stmt.(type) {
+	case *ast.LetStatement:
+		t := c.checkExpression(stmt.Value, s)
+		if stmt.Name.Type != nil {
+			declared := FromAnnotation(stmt.Name.Type)
+			c.assign(stmt.Name.Token, declared, t, "in declaration of "+stmt.Name.Value)
+			t = declared
+		}
+		s.names[stmt.Name.Value] = t
+		return Null
+	case *ast.ReturnStatement:
+		t := c.checkExpression(stmt.ReturnValue, s)
+		if len(c.functions) > 0 {
+			f := c.functions[len(c.functions)-1]
+			if f.declared != nil {
+				c.assign(stmt.Token, f.declared, t, "in return")
+			}
+			f.returned = join(f.returned, t)
+		}
+		return t
+	case *ast.ExpressionStatement:
+		t := c.checkExpression(stmt.Expression, s)
+		// a named function on its own declares its name in the current scope.
+		if fl, ok := stmt.Expression.(*ast.FunctionLiteral); ok && fl.Name != nil {
+			s.names[fl.Name.Value] = t
+		}
+		return t
+	case *ast.BlockStatement:
+		return c.checkStatements(stmt.Statements, newScope(s))
+	}
+	return Any
+}
+
+func (c *checker) checkExpression(exp ast.Expression, s *scope) Type {
+	switch exp := exp.(type) {
+	case *ast.IntegerLiteral:
+		return Int
+	case *ast.StringLiteral:
+		return String
+	case *ast.Boolean:
+		return Bool
+	case *ast.Identifier:
+		if t, ok := s.lookup(exp.Value); ok {
+			return t
+		}
+		if t, ok := builtins[exp.Value]; ok {
+			return t
+		}
+		return Any
+	case *ast.PrefixExpression:
+		return c.checkPrefixExpression(exp, s)
+	case *ast.InfixExpression:
+		return c.checkInfixExpression(exp, s)
+	case *ast.IfExpression:
+		c.checkExpression(exp.Condition, s)
+		var result Type = Null
+		if exp.Consequence != nil {
+			result = c.checkStatement(exp.Consequence, s)
+		}
+		if exp.Alternative != nil {
+			return join(result, c.checkStatement(exp.Alternative, s))
+		}
+		// without an else branch the value is null when the condition is false.
+		return join(result, Null)
+	case *ast.FunctionLiteral:
+		return c.checkFunctionLiteral(exp, s)
+	case *ast.CallExpression:
+		return c.checkCallExpression(exp, s)
+	case *ast.ArrayLiteral:
+		var elem Type
+		for _, el := range exp.Elements {
+			if spread, ok := el.(*ast.SpreadExpression); ok {
+				elem = join(elem, c.checkSpread(spread, s))
+				continue
+			}
+			elem = join(elem, c.checkExpression(el, s))
+		}
+		if elem == nil {
+			elem = Any
+		}
+		return &Array{Elem: elem}
+	case *ast.HashLiteral:
+		// hashes are not typed, their keys and values are checked on their own.
+		for _, pair := range exp.Pairs {
+			c.checkExpression(pair.Key, s)
+			c.checkExpression(pair.Value, s)
+		}
+		return Any
+	case *ast.IndexExpression:
+		return c.checkIndexExpression(exp, s)
+	case *ast.SpreadExpression:
+		// the evaluator rejects spreads anywhere else at runtime.
+		c.checkExpression(exp.Value, s)
+		return Any
+	}
+	return Any
+}
+
+// method that checks a spread in a call or an array literal and returns the
+// type of the elements it expands into.
+func (c *checker) checkSpread(spread *ast.SpreadExpression, s *scope) Type {
+	switch t := c.checkExpression(spread.Value, s).(type) {
+	case *Array:
+		return t.Elem
+	default:
+		if t != Any {
+			c.report(spread.Token, "cannot spread value of type %s, want array", t)
+		}
+		return Any
+	}
+}
+
+// method that checks a prefix expression with the same rules as
+// evalPrefixExpression: ! takes any value and - only takes integers.
+func (c *checker) checkPrefixExpression(exp *ast.PrefixExpression, s *scope) Type {
+	right := c.checkExpression(exp.Right, s)
+	switch exp.Operator {
+	case "!":
+		return Bool
+	case "-":
+		if right != Any && right != Int {
+			c.report(exp.Token, "operator - not defined on %s", right)
+		}
+		return Int
+	}
+	return Any
+}
+
+// method that checks an infix expression with the same rules as
+// evalInfixExpression: == and != compare any two values, + works on two
+// integers or two strings and every other operator only works on integers.
+func (c *checker) checkInfixExpression(exp *ast.InfixExpression, s *scope) Type {
+	left := c.checkExpression(exp.Left, s)
+	right := c.checkExpression(exp.Right, s)
+	op := exp.Operator
+
+	if op == "==" || op == "!=" {
+		return Bool
+	}
+
+	var result Type = Int
+	if op == "<" || op == ">" {
+		result = Bool
+	}
+
+	if left != Any && right != Any && !equal(left, right) {
+		c.report(exp.Token, "mismatched types %s and %s in %s", left, right, op)
+		return result
+	}
+
+	operand := left
+	if operand == Any {
+		operand = right
+	}
+	switch {
+	case operand == Any:
+		if op == "+" {
+			// either integer or string addition, we can't tell.
+			return Any
+		}
+	case operand == Int:
+	case operand == String && op == "+":
+		return String
+	default:
+		c.report(exp.Token, "operator %s not defined on %s", op, operand)
+	}
+	return result
+}
+
+// method that checks an index expression. Arrays can only be indexed with
+// integers. Hashes are not typed so anything else of type Any may be a hash.
+func (c *checker) checkIndexExpression(exp *ast.IndexExpression, s *scope) Type {
+	left := c.checkExpression(exp.Left, s)
+	index := c.checkExpression(exp.Index, s)
+
+	switch left := left.(type) {
+	case *Array:
+		if index != Any && index != Int {
+			c.report(exp.Token, "array index must be int, got %s", index)
+		}
+		return left.Elem
+	default:
+		if left != Any {
+			c.report(exp.Token, "cannot index value of type %s", left)
+		}
+		return Any
+	}
+}
+
+// method that checks a function literal and returns its type.
+// Parameters without annotation are Any. When the return type is not given it
+// is inferred from the value of the body, which makes it Any as soon as any
+// return statement disagrees with it.
+func (c *checker) checkFunctionLiteral(fl *ast.FunctionLiteral, s *scope) Type {
+	fn := &Function{Return: FromAnnotation(fl.ReturnType)}
+	for i, param := range fl.Parameters {
+		fn.Params = append(fn.Params, FromAnnotation(param.Type))
+		if i >= len(fl.Defaults) || fl.Defaults[i] == nil {
+			fn.Required = i + 1
+		}
+	}
+	if fl.Rest != nil {
+		fn.Rest = Any
+		if rest, ok := FromAnnotation(fl.Rest.Type).(*Array); ok {
+			fn.Rest = rest.Elem
+		}
+	}
+
+	outer := s
+	if fl.Name != nil {
+		// a named function sees itself. Its return type is only known here
+		// if it is annotated.
+		outer = newScope(s)
+		outer.names[fl.Name.Value] = fn
+	}
+	fnScope := newScope(outer)
+	for i, param := range fl.Parameters {
+		if i < len(fl.Defaults) && fl.Defaults[i] != nil {
+			t := c.checkExpression(fl.Defaults[i], fnScope)
+			c.assign(param.Token, fn.Params[i], t, "as default of "+param.Value)
+		}
+		fnScope.names[param.Value] = fn.Params[i]
+	}
+	if fl.Rest != nil {
+		fnScope.names[fl.Rest.Value] = &Array{Elem: fn.Rest}
+	}
+
+	f := &function{}
+	if fl.ReturnType != nil {
+		f.declared = fn.Return
+	}
+	c.functions = append(c.functions, f)
+	var result Type = Null
+	if fl.Body != nil {
+		result = c.checkStatements(fl.Body.Statements, fnScope)
+	}
+	c.functions = c.functions[:len(c.functions)-1]
+
+	if fl.Body != nil && endsInReturn(fl.Body) {
+		// the value of the last statement is what the return already recorded.
+		result = nil
+	}
+	if fl.ReturnType != nil {
+		if result != nil {
+			c.assign(fl.Body.Token, fn.Return, result, "as result of function")
+		}
+	} else {
+		fn.Return = join(result, f.returned)
+		if fn.Return == nil {
+			fn.Return = Null
+		}
+	}
+	return fn
+}
+
+// helper function that reports whether the last statement of a block is a return.
+func endsInReturn(block *ast.BlockStatement) bool {
+	if len(block.Statements) == 0 {
+		return false
+	}
+	_, ok := block.Statements[len(block.Statements)-1].(*ast.ReturnStatement)
+	return ok
+}
+
+// method that checks a call. When the callee is a known function the number
+// of arguments and the type of each one are checked against its parameters,
+// unless an argument is spread since its length is not known.
+func (c *checker) checkCallExpression(call *ast.CallExpression, s *scope) Type {
+	callee := c.checkExpression(call.Function, s)
+	name := "function"
+	if ident, ok := call.Function.(*ast.Identifier); ok {
+		name = ident.Value
+	}
+
+	fn, ok := callee.(*Function)
+	if !ok {
+		if callee != Any {
+			c.report(call.Token, "cannot call non-function %s of type %s", name, callee)
+		}
+		for _, arg := range call.Arguments {
+			c.checkArgument(arg, s)
+		}
+		return Any
+	}
+
+	spread := false
+	for i, arg := range call.Arguments {
+		t := c.checkArgument(arg, s)
+		if _, ok := arg.(*ast.SpreadExpression); ok {
+			spread = true
+		}
+		if spread {
+			continue
+		}
+		var param Type
+		switch {
+		case i < len(fn.Params):
+			param = fn.Params[i]
+		case fn.Rest != nil:
+			param = fn.Rest
+		default:
+			continue
+		}
+		c.assign(call.Token, param, t, fmt.Sprintf("in argument %d to %s", i+1, name))
+	}
+
+	got := len(call.Arguments)
+	if !spread && (got < fn.Required || (fn.Rest == nil && got > len(fn.Params))) {
+		want := fmt.Sprintf("%d", fn.Required)
+		if fn.Rest != nil {
+			want = fmt.Sprintf("at least %d", fn.Required)
+		} else if fn.Required != len(fn.Params) {
+			want = fmt.Sprintf("%d to %d", fn.Required, len(fn.Params))
+		}
+		c.report(call.Token, "wrong number of arguments to %s. got=%d, want=%s", name, got, want)
+	}
+	return fn.Return
+}
+
+// helper method to check one argument of a call.
+func (c *checker) checkArgument(arg ast.Expression, s *scope) Type {
+	if spread, ok := arg.(*ast.SpreadExpression); ok {
+		return c.checkSpread(spread, s)
+	}
+	return c.checkExpression(arg, s)
+}
@@ -0,0 +1,208 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+
+	ast "github.com/Artypuppet/monkey/ast"
+)
+
+// ---------------------------------Types-----------------------------------
+
+// interface that all static types implement.
+// The checker is gradual: any value whose type is not known, because it has
+// no annotation and can't be inferred, has the type Any, which is consistent
+// with every other type. Only types that are both known can clash.
+type Type interface {
+	String() string
+}
+
+// struct defining the named types int, string, bool, null and any.
+type Basic struct {
+	Name string
+}
+
+func (b *Basic) String() string {
+	return b.Name
+}
+
+// the named types. They are singletons so they can be compared by pointer.
+var (
+	Int    = &Basic{Name: "int"}
+	String = &Basic{Name: "string"}
+	Bool   = &Basic{Name: "bool"}
+	Null   = &Basic{Name: "null"}
+	Any    = &Basic{Name: "any"}
+)
+
+// struct defining the type of arrays whose elements are of type Elem.
+type Array struct {
+	Elem Type
+}
+
+func (a *Array) String() string {
+	return "[" + a.Elem.String() + "]"
+}
+
+// struct defining the type of a function.
+// Only the first Required parameters have to be passed and, if Rest is not nil,
+// any number of extra arguments of type Rest may follow the parameters.
+type Function struct {
+	Params   []Type
+	Required int
+	Rest     Type
+	Return   Type
+}
+
+func (f *Function) String() string {
+	params := []string{}
+	for i, p := range f.Params {
+		if i >= f.Required {
+			params = append(params, p.String()+"?")
+		} else {
+			params = append(params, p.String())
+		}
+	}
+	if f.Rest != nil {
+		params = append(params, "..."+f.Rest.String())
+	}
+	return "fn(" + strings.Join(params, ", ") + "): " + f.Return.String()
+}
+
+// function that converts an annotation from the ast into a Type.
+// A missing annotation is Any.
+func FromAnnotation(ta *ast.TypeAnnotation) Type {
+	if ta == nil {
+		return Any
+	}
+	switch ta.Name {
+	case "int":
+		return Int
+	case "string":
+		return String
+	case "bool":
+		return Bool
+	case "null":
+		return Null
+	case "array":
+		return &Array{Elem: FromAnnotation(ta.Elem)}
+	case "fn":
+		fn := &Function{Required: len(ta.Params), Return: FromAnnotation(ta.Return)}
+		for _, p := range ta.Params {
+			fn.Params = append(fn.Params, FromAnnotation(p))
+		}
+		return fn
+	default:
+		return Any
+	}
+}
+
+// function that reports whether a value of type from can be used where a
+// value of type to is expected. Any is consistent with everything and the
+// relation is applied element wise to arrays and functions.
+func Consistent(to, from Type) bool {
+	if to == Any || from == Any {
+		return true
+	}
+	switch to := to.(type) {
+	case *Basic:
+		return to == from
+	case *Array:
+		from, ok := from.(*Array)
+		return ok && Consistent(to.Elem, from.Elem)
+	case *Function:
+		from, ok := from.(*Function)
+		if !ok || len(to.Params) != len(from.Params) || to.Required != from.Required {
+			return false
+		}
+		if (to.Rest == nil) != (from.Rest == nil) {
+			return false
+		}
+		if to.Rest != nil && !Consistent(to.Rest, from.Rest) {
+			return false
+		}
+		for i := range to.Params {
+			if !Consistent(to.Params[i], from.Params[i]) {
+				return false
+			}
+		}
+		return Consistent(to.Return, from.Return)
+	}
+	return false
+}
+
+// function that returns the type of a value that is either of type a or of
+// type b, e.g. the value of an if expression. If they differ it is Any.
+func join(a, b Type) Type {
+	if a == nil {
+		return b
+	}
+	if b == nil {
+		return a
+	}
+	if equal(a, b) {
+		return a
+	}
+	return Any
+}
+
+// function that reports whether two types are identical.
+func equal(a, b Type) bool {
+	if a == Any || b == Any {
+		return a == b
+	}
+	return Consistent(a, b) && Consistent(b, a) && a.String() == b.String()
+}
+
+// --------------------------------Builtins---------------------------------
+
+// map holding the signatures of the builtin functions.
+// Builtins missing from it are of type Any.
+var builtins = map[string]Type{
+	"len":   &Function{Params: []Type{Any}, Required: 1, Return: Int},
+	"first": &Function{Params: []Type{&Array{Elem: Any}}, Required: 1, Return: Any},
+	"last":  &Function{Params: []Type{&Array{Elem: Any}}, Required: 1, Return: Any},
+	"rest":  &Function{Params: []Type{&Array{Elem: Any}}, Required: 1, Return: &Array{Elem: Any}},
+	"push":  &Function{Params: []Type{&Array{Elem: Any}, Any}, Required: 2, Return: &Array{Elem: Any}},
+
+	"json_parse":     &Function{Params: []Type{String}, Required: 1, Return: Any},
+	"json_stringify": &Function{Params: []Type{Any, Bool}, Required: 1, Return: String},
+
+	"puts":     &Function{Rest: Any, Return: Null},
+	"print":    &Function{Rest: Any, Return: Null},
+	"println":  &Function{Rest: Any, Return: Null},
+	"format":   &Function{Params: []Type{String}, Required: 1, Rest: Any, Return: String},
+	"input":    &Function{Params: []Type{String}, Return: Any},
+	"readline": &Function{Return: Any},
+
+	"read_file":   &Function{Params: []Type{String}, Required: 1, Return: String},
+	"write_file":  &Function{Params: []Type{String, String}, Required: 2, Return: Null},
+	"append_file": &Function{Params: []Type{String, String}, Required: 2, Return: Null},
+	"list_dir":    &Function{Params: []Type{String}, Return: &Array{Elem: String}},
+	"exists":      &Function{Params: []Type{String}, Required: 1, Return: Bool},
+	"remove":      &Function{Params: []Type{String}, Required: 1, Return: Null},
+}
+
+// function that returns the signature of a builtin function e.g.
+// "len(any): int", or false if its signature is not known.
+func BuiltinSignature(name string) (string, bool) {
+	t, ok := builtins[name]
+	if !ok {
+		return "", false
+	}
+	return name + strings.TrimPrefix(t.String(), "fn"), true
+}
+
+// ------------------------------Type Errors--------------------------------
+
+// struct describing a type error found by the checker.
+// Line and Column are the position of the offending token.
+type Error struct {
+	Line    int
+	Column  int
+	Message string
+}
+
+func (e Error) String() string {
+	return fmt.Sprintf("%d:%d: %s", e.Line, e.Column, e.Message)
+}
@@ -0,0 +1,137 @@
+package types
+
+import (
+	"testing"
+
+	lexer "github.com/Artypuppet/monkey/lexer"
+	parser "github.com/Artypuppet/monkey/parser"
+)
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"let x = 5; let y = x + 10; y * 2;", nil},
+		{"let x: int = 5;", nil},
+		{`let x: int = "five";`, []string{"1:5: cannot use value of type string as int in declaration of x"}},
+		{`5 + "five";`, []string{"1:3: mismatched types int and string in +"}},
+		{`"a" - "b";`, []string{"1:5: operator - not defined on string"}},
+		{"true + false;", []string{"1:6: operator + not defined on bool"}},
+		{`-"a";`, []string{"1:1: operator - not defined on string"}},
+		{`5 == "five"; !5;`, nil},
+		{`let s: string = "a" + "b";`, nil},
+		{`let b: bool = 1 < 2;`, nil},
+		// values without annotations are any and never cause an error.
+		{`let f = fn(x) { x + 1 }; f("a");`, nil},
+		{`let f = fn(x) { x }; let y: int = f("a");`, nil},
+		// x + "a" only works if x is a string.
+		{`let f = fn(x) { x + "a" }; let y: int = f(1);`, []string{"1:32: cannot use value of type string as int in declaration of y"}},
+		{
+			`let add = fn(a: int, b: int): int { a + b }; add(1, "2");`,
+			[]string{`1:49: cannot use value of type string as int in argument 2 to add`},
+		},
+		{
+			`let add = fn(a: int, b: int): int { a + b }; let s: string = add(1, 2);`,
+			[]string{"1:50: cannot use value of type int as string in declaration of s"},
+		},
+		{
+			`let add = fn(a, b) { a + b }; add(1);`,
+			[]string{"1:34: wrong number of arguments to add. got=1, want=2"},
+		},
+		{
+			`let f = fn(a, b = 2) { a + b }; f(); f(1); f(1, 2); f(1, 2, 3);`,
+			[]string{
+				"1:34: wrong number of arguments to f. got=0, want=1 to 2",
+				"1:54: wrong number of arguments to f. got=3, want=1 to 2",
+			},
+		},
+		{`let f = fn(a, ...r) { r }; f(); f(1, 2, 3);`, []string{"1:29: wrong number of arguments to f. got=0, want=at least 1"}},
+		{`let f = fn(...r: [int]) { r }; f(1, "2");`, []string{"1:33: cannot use value of type string as int in argument 2 to f"}},
+		// a spread argument can be of any length.
+		{`let f = fn(a, b) { a + b }; f(...[1, 2]);`, nil},
+		{`let f = fn(a, b) { a + b }; f(...1);`, []string{"1:31: cannot spread value of type int, want array"}},
+		{`let f = fn(a: int = "x") { a };`, []string{"1:12: cannot use value of type string as int as default of a"}},
+		{
+			`let f = fn(x: int): string { x };`,
+			[]string{"1:28: cannot use value of type int as string as result of function"},
+		},
+		{
+			`let f = fn(x: int): bool { if (x > 0) { return true; } return x; };`,
+			[]string{"1:56: cannot use value of type int as bool in return"},
+		},
+		// the return type is inferred from the body when it isn't annotated.
+		{`let f = fn() { 1 }; let s: string = f();`, []string{"1:25: cannot use value of type int as string in declaration of s"}},
+		{`let f = fn(x) { if (x) { return 1; } 2 }; let s: string = f(true);`, []string{"1:47: cannot use value of type int as string in declaration of s"}},
+		{`let f = fn(x) { if (x) { return "a"; } 2 }; let s: string = f(true);`, nil},
+		{`5();`, []string{"1:2: cannot call non-function function of type int"}},
+		{`let x = 5; x(1);`, []string{"1:13: cannot call non-function x of type int"}},
+		{`let a: [int] = [1, 2, 3]; let x: int = a[0];`, nil},
+		{`let a: [int] = [1, "2"];`, nil},
+		{`let a: [int] = ["1", "2"];`, []string{"1:5: cannot use value of type [string] as [int] in declaration of a"}},
+		{`let a = [1, 2]; a["0"];`, []string{`1:18: array index must be int, got string`}},
+		{`let a = 5; a[0];`, []string{"1:13: cannot index value of type int"}},
+		{`let h = {"a": 1 + "b"}; h["a"] + 1;`, []string{"1:17: mismatched types int and string in +"}},
+		{`let a: [int] = [...[1, 2], 3];`, nil},
+		{`let f: fn(int): int = fn(x: int): int { x };`, nil},
+		{`let f: fn(int): int = fn(x: string): int { 1 };`, []string{"1:5: cannot use value of type fn(string): int as fn(int): int in declaration of f"}},
+		// named functions see their own type.
+		{`fn fact(n: int): int { if (n < 2) { return 1; } n * fact(n - 1, 2) }`, []string{"1:57: wrong number of arguments to fact. got=2, want=1"}},
+		{`fn id(x: int): int { x } let s: string = id(1);`, []string{"1:30: cannot use value of type int as string in declaration of s"}},
+		// builtins have signatures.
+		{`let n: string = len("abc");`, []string{"1:5: cannot use value of type int as string in declaration of n"}},
+		{`first(1);`, []string{"1:6: cannot use value of type int as [any] in argument 1 to first"}},
+		{`push([1]);`, []string{"1:5: wrong number of arguments to push. got=1, want=2"}},
+		// names are scoped like the evaluator.
+		{`let x = 1; if (true) { let x = "a"; x - 1; } x - 1;`, []string{"1:39: mismatched types string and int in -"}},
+	}
+
+	for _, tt := range tests {
+		program := parser.New(lexer.New(tt.input)).ParseProgram()
+		errors := Check(program)
+		if len(errors) != len(tt.expected) {
+			t.Errorf("input %q: wrong number of errors. want=%d, got=%d: %v", tt.input, len(tt.expected), len(errors), errors)
+			continue
+		}
+		for i, e := range errors {
+			if e.String() != tt.expected[i] {
+				t.Errorf("input %q: error %d wrong. want=%q, got=%q", tt.input, i, tt.expected[i], e.String())
+			}
+		}
+	}
+}
+
+func TestConsistent(t *testing.T) {
+	intFn := &Function{Params: []Type{Int}, Required: 1, Return: Int}
+	tests := []struct {
+		to, from Type
+		expected bool
+	}{
+		{Int, Int, true},
+		{Int, String, false},
+		{Any, String, true},
+		{Int, Any, true},
+		{&Array{Elem: Int}, &Array{Elem: Any}, true},
+		{&Array{Elem: Int}, &Array{Elem: Bool}, false},
+		{&Array{Elem: Int}, Int, false},
+		{intFn, &Function{Params: []Type{Any}, Required: 1, Return: Int}, true},
+		{intFn, &Function{Params: []Type{Int, Int}, Required: 2, Return: Int}, false},
+		{intFn, &Function{Params: []Type{Int}, Required: 1, Return: String}, false},
+	}
+
+	for _, tt := range tests {
+		if got := Consistent(tt.to, tt.from); got != tt.expected {
+			t.Errorf("Consistent(%s, %s) wrong. want=%t, got=%t", tt.to, tt.from, tt.expected, got)
+		}
+	}
+}
+
+func TestBuiltinSignature(t *testing.T) {
+	sig, ok := BuiltinSignature("push")
+	if !ok || sig != "push([any], any): [any]" {
+		t.Errorf("wrong signature for push. got=%q, %t", sig, ok)
+	}
+	if _, ok := BuiltinSignature("nope"); ok {
+		t.Errorf("expected no signature for nope")
+	}
+}